@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+func runConvert(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	in := fs.String("in", "", "path to the model to convert (required)")
+	from := fs.String("from", "", "format of the input: goro, onnx or config, inferred from the extension if not set")
+	outPath := fs.String("out", "", "path to write the converted model to (required)")
+	to := fs.String("to", "", "format of the output: goro, onnx or config, inferred from the extension if not set")
+	fs.Parse(args)
+	if *in == "" || *outPath == "" {
+		fs.Usage()
+		return fmt.Errorf("in and out must be set")
+	}
+
+	m, err := loadModel(*in, *from)
+	if err != nil {
+		return err
+	}
+	err = writeModel(m, *outPath, *to)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "converted %s to %s\n", *in, *outPath)
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/aunum/goro/pkg/v1/data"
+	"github.com/aunum/goro/pkg/v1/model"
+	"github.com/aunum/goro/pkg/v1/onnx"
+
+	t "gorgonia.org/tensor"
+)
+
+// Model file formats.
+const (
+	// goroFormat is a model saved with its learnables by model.Save.
+	goroFormat = "goro"
+
+	// onnxFormat is an ONNX model.
+	onnxFormat = "onnx"
+
+	// configFormat is a declarative model definition, without learnables.
+	configFormat = "config"
+)
+
+// formatOf returns the format of the model file at the path, which is the given format if set or
+// otherwise inferred from the extension.
+func formatOf(path, format string) (string, error) {
+	if format != "" {
+		switch format {
+		case goroFormat, onnxFormat, configFormat:
+			return format, nil
+		}
+		return "", fmt.Errorf("unknown format %q, must be one of %s, %s or %s", format, goroFormat, onnxFormat, configFormat)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return goroFormat, nil
+	case ".onnx":
+		return onnxFormat, nil
+	case ".yaml", ".yml":
+		return configFormat, nil
+	}
+	return "", fmt.Errorf("cannot infer the format of %q from its extension, set the format", path)
+}
+
+// loadModel loads a compiled model from the file at the path, models defined by a config have newly
+// initialized learnables.
+func loadModel(path, format string) (*model.Sequential, error) {
+	format, err := formatOf(path, format)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case onnxFormat:
+		return onnx.ImportFile(path, model.WithoutTracker())
+	case configFormat:
+		def, err := model.ReadDefinitionFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return def.Build(model.WithoutTracker())
+	default:
+		return model.LoadFile(path, model.WithoutTracker())
+	}
+}
+
+// writeModel writes a compiled model to the file at the path.
+func writeModel(m *model.Sequential, path, format string) error {
+	format, err := formatOf(path, format)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case onnxFormat:
+		return onnx.ExportFile(m, path)
+	case configFormat:
+		def, err := m.Definition()
+		if err != nil {
+			return err
+		}
+		return def.WriteFile(path)
+	default:
+		return m.SaveFile(path)
+	}
+}
+
+// readTensor reads a tensor from a .npy file, an array of a .npz file given as path:name, or the
+// columns of a .csv file with a header.
+func readTensor(path string, columns []string) (*t.Dense, error) {
+	if i := strings.LastIndex(path, ".npz:"); i >= 0 {
+		arrays, err := data.ReadNPZFile(path[:i+4])
+		if err != nil {
+			return nil, err
+		}
+		name := path[i+5:]
+		d, ok := arrays[name]
+		if !ok {
+			return nil, fmt.Errorf("no array %q in %s", name, path[:i+4])
+		}
+		return d, nil
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".npy":
+		return data.ReadNPYFile(path)
+	case ".csv":
+		csv, err := data.ReadCSVFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return csv.Tensor(data.Columns{Names: columns})
+	}
+	return nil, fmt.Errorf("cannot read %q, data must be a .npy, .csv or .npz:<array> file", path)
+}
+
+// asInput converts the tensor to the data type of the model and reshapes it to a batch of the input
+// shape of the model, leaving the size of the batch as is.
+func asInput(d *t.Dense, m *model.Sequential, shape t.Shape) (*t.Dense, error) {
+	d, err := data.Convert(d, m.DType())
+	if err != nil {
+		return nil, err
+	}
+	rows := shape.Clone()
+	if len(rows) > 1 && rows[0] == 1 {
+		rows = rows[1:]
+	}
+	if d.Shape().TotalSize()%rows.TotalSize() != 0 {
+		return nil, fmt.Errorf("data of shape %v cannot be reshaped to rows of shape %v", d.Shape(), rows)
+	}
+	batch := d.Shape().TotalSize() / rows.TotalSize()
+	d = d.Clone().(*t.Dense)
+	err = d.Reshape(append(t.Shape{batch}, rows...)...)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// splitColumns splits a comma separated list of columns.
+func splitColumns(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
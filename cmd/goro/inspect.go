@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+)
+
+func runInspect(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	path := fs.String("model", "", "path to the model (required)")
+	format := fs.String("format", "", "format of the model: goro, onnx or config, inferred from the extension if not set")
+	fs.Parse(args)
+	if *path == "" {
+		fs.Usage()
+		return fmt.Errorf("model must be set")
+	}
+
+	m, err := loadModel(*path, *format)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LEARNABLE\tSHAPE\tMIN\tMAX\tMEAN\tSTD\tZEROS")
+	for _, learnable := range m.Learnables() {
+		s, err := statsOf(learnable.Value())
+		if err != nil {
+			return fmt.Errorf("learnable %q: %w", learnable.Name(), err)
+		}
+		fmt.Fprintf(w, "%s\t%v\t%.4g\t%.4g\t%.4g\t%.4g\t%.1f%%\n", learnable.Name(), learnable.Shape(), s.min, s.max, s.mean, s.std, 100*s.zeros)
+	}
+	return w.Flush()
+}
+
+// stats of the values of a tensor.
+type stats struct {
+	min, max, mean, std float64
+
+	// zeros is the fraction of values that are zero.
+	zeros float64
+}
+
+func statsOf(v interface{ Data() interface{} }) (stats, error) {
+	var values []float64
+	switch data := v.Data().(type) {
+	case []float32:
+		for _, f := range data {
+			values = append(values, float64(f))
+		}
+	case []float64:
+		values = data
+	case float32:
+		values = []float64{float64(data)}
+	case float64:
+		values = []float64{data}
+	default:
+		return stats{}, fmt.Errorf("unsupported data type %T", data)
+	}
+	if len(values) == 0 {
+		return stats{}, nil
+	}
+	s := stats{min: math.Inf(1), max: math.Inf(-1)}
+	for _, f := range values {
+		s.min = math.Min(s.min, f)
+		s.max = math.Max(s.max, f)
+		s.mean += f
+		if f == 0 {
+			s.zeros++
+		}
+	}
+	n := float64(len(values))
+	s.mean /= n
+	s.zeros /= n
+	for _, f := range values {
+		s.std += (f - s.mean) * (f - s.mean)
+	}
+	s.std = math.Sqrt(s.std / n)
+	return s, nil
+}
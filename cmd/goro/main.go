@@ -0,0 +1,112 @@
+// Command goro trains, inspects, converts and serves goro models.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aunum/goro/pkg/v1/model"
+	"github.com/aunum/goro/pkg/v1/serve"
+	"github.com/aunum/log"
+)
+
+const usage = `goro trains, inspects, converts and serves goro models.
+
+Usage:
+  goro <command> [flags]
+
+Commands:
+  train    train a model from a YAML or JSON definition on CSV or NumPy data
+  summary  print the layers, output shapes and parameter counts of a model
+  predict  predict a file of inputs with a model
+  convert  convert a model between the goro, onnx and config formats
+  inspect  print statistics of the learnables of a model
+  serve    serve a saved model over HTTP
+
+Models are goro models saved as .json, ONNX models as .onnx, or definitions as .yaml, a format
+flag overrides the format inferred from the extension.
+
+Use "goro <command> -h" for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "train":
+		err = runTrain(os.Args[2:], os.Stdout)
+	case "summary":
+		err = runSummary(os.Args[2:], os.Stdout)
+	case "predict":
+		err = runPredict(os.Args[2:], os.Stdout)
+	case "convert":
+		err = runConvert(os.Args[2:], os.Stdout)
+	case "inspect":
+		err = runInspect(os.Args[2:], os.Stdout)
+	case "serve":
+		err = runServe(os.Args[2:])
+	case "-h", "--help", "help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	path := fs.String("model", "", "path to the saved model (required)")
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	maxBatch := fs.Int("max-batch", 0, "largest batch to coalesce requests into, defaults to the max batch size of the model")
+	maxLatency := fs.Duration("max-latency", 5*time.Millisecond, "longest a request waits for a batch to fill")
+	maxConcurrency := fs.Int("max-concurrency", 0, "most batches predicted at once, defaults to GOMAXPROCS")
+	maxBodyBytes := fs.Int64("max-body-bytes", 32<<20, "largest request body accepted")
+	fs.Parse(args)
+	if *path == "" {
+		fs.Usage()
+		return fmt.Errorf("model must be set")
+	}
+
+	m, err := model.LoadFile(*path, model.WithoutTracker())
+	if err != nil {
+		return err
+	}
+	opts := []serve.Opt{serve.WithMaxLatency(*maxLatency), serve.WithMaxBodyBytes(*maxBodyBytes)}
+	if *maxBatch > 0 {
+		opts = append(opts, serve.WithMaxBatchSize(*maxBatch))
+	}
+	if *maxConcurrency > 0 {
+		opts = append(opts, serve.WithMaxConcurrency(*maxConcurrency))
+	}
+	server, err := serve.NewServer(m, opts...)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		log.Info("shutting down")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		err := server.Close(ctx)
+		if err != nil {
+			log.Errorf("shutting down: %v", err)
+		}
+	}()
+	return server.ListenAndServe(*addr)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const definition = `
+name: iris
+x:
+- name: x
+  shape: [1, 2]
+y:
+  name: y
+  shape: [1, 2]
+layers:
+- type: fc
+  config: {output: 4, activation: relu}
+- type: fc
+  config: {output: 2, activation: softmax}
+loss: cross_entropy
+optimizer: {type: adam, learnRate: 0.01}
+batchSize: 3
+epochs: 2
+`
+
+const csv = `a,b,label
+0.1,0.2,x
+0.9,0.8,y
+0.2,0.1,x
+0.8,0.9,y
+0.3,0.3,x
+`
+
+func TestCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goro")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	config := filepath.Join(dir, "iris.yaml")
+	require.NoError(t, ioutil.WriteFile(config, []byte(definition), 0644))
+	data := filepath.Join(dir, "iris.csv")
+	require.NoError(t, ioutil.WriteFile(data, []byte(csv), 0644))
+	saved := filepath.Join(dir, "iris.json")
+
+	var out bytes.Buffer
+	err = runTrain([]string{"-config", config, "-data", data, "-y-columns", "label", "-categorical", "label", "-seed", "1", "-out", saved}, &out)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "epoch 2/2 loss")
+
+	out.Reset()
+	require.NoError(t, runSummary([]string{"-model", saved}, &out))
+	require.Contains(t, out.String(), "total params: 22")
+
+	out.Reset()
+	require.NoError(t, runPredict([]string{"-model", saved, "-input", data, "-columns", "a,b"}, &out))
+	rows := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, rows, 5)
+	require.Len(t, strings.Split(rows[0], ","), 2)
+
+	exported := filepath.Join(dir, "iris.onnx")
+	require.NoError(t, runConvert([]string{"-in", saved, "-out", exported}, &out))
+	var onnxOut bytes.Buffer
+	require.NoError(t, runPredict([]string{"-model", exported, "-input", data, "-columns", "a,b"}, &onnxOut))
+	require.Equal(t, strings.Join(rows, "\n"), strings.TrimSpace(onnxOut.String()))
+
+	defined := filepath.Join(dir, "defined.json")
+	require.NoError(t, runConvert([]string{"-in", exported, "-out", defined, "-to", "config"}, &out))
+	out.Reset()
+	require.NoError(t, runSummary([]string{"-model", defined, "-format", "config"}, &out))
+	require.Contains(t, out.String(), "total params: 22")
+
+	out.Reset()
+	require.NoError(t, runInspect([]string{"-model", saved}, &out))
+	require.Len(t, strings.Split(strings.TrimSpace(out.String()), "\n"), 5)
+
+	require.Error(t, runConvert([]string{"-in", saved, "-out", filepath.Join(dir, "model.bin")}, &out))
+}
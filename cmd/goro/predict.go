@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/aunum/goro/pkg/v1/data"
+	"github.com/aunum/goro/pkg/v1/model"
+
+	t "gorgonia.org/tensor"
+)
+
+func runPredict(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("predict", flag.ExitOnError)
+	path := fs.String("model", "", "path to the model (required)")
+	format := fs.String("format", "", "format of the model: goro, onnx or config, inferred from the extension if not set")
+	input := fs.String("input", "", "path to the input as a .npy file, .npz:<array> or .csv file with a header (required)")
+	columns := fs.String("columns", "", "comma separated columns of a CSV input, defaults to all columns")
+	outPath := fs.String("out", "", "path to write the predictions to as .csv or .npy, defaults to CSV on stdout")
+	fs.Parse(args)
+	if *path == "" || *input == "" {
+		fs.Usage()
+		return fmt.Errorf("model and input must be set")
+	}
+
+	m, err := loadModel(*path, *format)
+	if err != nil {
+		return err
+	}
+	x, err := readTensor(*input, splitColumns(*columns))
+	if err != nil {
+		return err
+	}
+	x, err = asInput(x, m, m.FwdInput().Shape())
+	if err != nil {
+		return err
+	}
+	predictions, err := predictAll(m, x)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case *outPath == "":
+		return data.WriteCSV(out, predictions, nil)
+	case strings.ToLower(filepath.Ext(*outPath)) == ".npy":
+		return data.WriteNPYFile(*outPath, predictions)
+	default:
+		return data.WriteCSVFile(*outPath, predictions, nil)
+	}
+}
+
+// predictAll predicts the batch of x in batches of at most the max batch size of the model.
+func predictAll(m *model.Sequential, x *t.Dense) (*t.Dense, error) {
+	ds, err := data.FromTensors(x)
+	if err != nil {
+		return nil, err
+	}
+	batches, err := data.Collect(data.Batch(ds, m.MaxBatchSize(), false))
+	if err != nil {
+		return nil, err
+	}
+	predictions := []data.Example{}
+	for _, batch := range batches {
+		prediction, err := m.PredictBatch(batch[0])
+		if err != nil {
+			return nil, err
+		}
+		d, ok := prediction.(*t.Dense)
+		if !ok {
+			return nil, fmt.Errorf("unexpected prediction type %T", prediction)
+		}
+		if d.Shape()[0] != batch[0].Shape()[0] {
+			// online predictions of a single example have the output shape of the model.
+			d = d.Clone().(*t.Dense)
+			err = d.Reshape(append(t.Shape{batch[0].Shape()[0]}, d.Shape()...)...)
+			if err != nil {
+				return nil, err
+			}
+		}
+		predictions = append(predictions, data.Example{d})
+	}
+	stacked, err := data.Stack(predictions)
+	if err != nil {
+		return nil, err
+	}
+	return stacked[0], nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+func runSummary(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("summary", flag.ExitOnError)
+	path := fs.String("model", "", "path to the model (required)")
+	format := fs.String("format", "", "format of the model: goro, onnx or config, inferred from the extension if not set")
+	fs.Parse(args)
+	if *path == "" {
+		fs.Usage()
+		return fmt.Errorf("model must be set")
+	}
+
+	m, err := loadModel(*path, *format)
+	if err != nil {
+		return err
+	}
+	summaries, err := m.Summary()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "model %q\n", m.Name())
+	fmt.Fprintf(out, "input %v %v, output %v\n\n", m.FwdInput().Shape(), m.DType(), m.OutputShape())
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LAYER\tTYPE\tOUTPUT SHAPE\tPARAMS")
+	total := 0
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%s\t%s\t%v\t%d\n", s.Name, s.Type, s.OutputShape, s.Params)
+		total += s.Params
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\ntotal params: %d\n", total)
+	return nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aunum/goro/pkg/v1/data"
+	"github.com/aunum/goro/pkg/v1/model"
+	"github.com/aunum/goro/pkg/v1/tensorboard"
+
+	"github.com/aunum/gold/pkg/v1/track"
+	t "gorgonia.org/tensor"
+)
+
+func runTrain(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("train", flag.ExitOnError)
+	config := fs.String("config", "", "path to the YAML or JSON model definition (required)")
+	dataPath := fs.String("data", "", "path to a .csv file with a header, or a .npz file with x and y arrays")
+	xPath := fs.String("x", "", "path to the x data as a .npy file or .npz:<array>, instead of -data")
+	yPath := fs.String("y", "", "path to the y data as a .npy file or .npz:<array>, instead of -data")
+	yColumns := fs.String("y-columns", "", "comma separated columns of a CSV which are y, the other columns are x")
+	categorical := fs.String("categorical", "", "comma separated columns of a CSV which are one-hot encoded")
+	epochs := fs.Int("epochs", 0, "epochs to train for, defaults to the epochs of the definition or 1")
+	seed := fs.Int64("seed", 0, "seed of the shuffle of each epoch, the examples are not shuffled if 0")
+	outPath := fs.String("out", "", "path to save the trained model to, defaults to <name>.json")
+	logDir := fs.String("logdir", "", "directory to write TensorBoard event files to")
+	fs.Parse(args)
+	if *config == "" {
+		fs.Usage()
+		return fmt.Errorf("config must be set")
+	}
+
+	def, err := model.ReadDefinitionFile(*config)
+	if err != nil {
+		return err
+	}
+	tracker, err := track.NewTracker()
+	if err != nil {
+		return err
+	}
+	m, err := def.Build(model.WithTracker(tracker), model.WithMetrics(model.TrainBatchLossMetric))
+	if err != nil {
+		return err
+	}
+	x, y, err := readTrainData(m, *dataPath, *xPath, *yPath, splitColumns(*yColumns), splitColumns(*categorical))
+	if err != nil {
+		return err
+	}
+	ds, err := data.FromTensors(x, y)
+	if err != nil {
+		return err
+	}
+
+	var writer *tensorboard.Writer
+	if *logDir != "" {
+		writer, err = tensorboard.NewWriter(*logDir)
+		if err != nil {
+			return err
+		}
+		defer writer.Close()
+		err = writer.AddModelGraph(m)
+		if err != nil {
+			return err
+		}
+	}
+	n := *epochs
+	if n == 0 {
+		n = def.Epochs
+	}
+	if n == 0 {
+		n = 1
+	}
+	lossName := fmt.Sprintf("%s_%s", m.Name(), model.TrainBatchLossMetric)
+	for epoch := 0; epoch < n; epoch++ {
+		start := time.Now()
+		var epochDS data.Dataset = ds
+		if *seed != 0 {
+			epochDS = data.Shuffle(ds, ds.Len(), *seed+int64(epoch))
+		}
+		loss, err := fitEpoch(m, tracker, lossName, data.Prefetch(data.Batch(epochDS, m.BatchSize(), false), 2))
+		if err != nil {
+			return err
+		}
+		m.NextEpoch()
+		fmt.Fprintf(out, "epoch %d/%d loss %.6f (%v)\n", epoch+1, n, loss, time.Since(start).Round(time.Millisecond))
+		if writer != nil {
+			step := int64(epoch)
+			err = writer.AddScalar("loss", loss, step)
+			if err != nil {
+				return err
+			}
+			err = writer.AddLearnables(m.Learnables(), step)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	path := *outPath
+	if path == "" {
+		path = m.Name() + ".json"
+	}
+	err = writeModel(m, path, "")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "saved model to %s\n", path)
+	return nil
+}
+
+// fitEpoch fits the model on each batch of the dataset, returning the mean loss of the batches.
+func fitEpoch(m *model.Sequential, tracker *track.Tracker, lossName string, batches data.Dataset) (float64, error) {
+	iter := batches.Iter()
+	defer iter.Close()
+	loss := 0.0
+	n := 0
+	for ; ; n++ {
+		batch, err := iter.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		err = m.FitBatch(batch[0], batch[1])
+		if err != nil {
+			return 0, err
+		}
+		v, err := tracker.GetValue(lossName)
+		if err != nil {
+			return 0, err
+		}
+		loss += v.Scalar()
+	}
+	return loss / float64(n), iter.Close()
+}
+
+// readTrainData reads the x and y data as batches of the inputs of the model.
+func readTrainData(m *model.Sequential, dataPath, xPath, yPath string, yColumns, categorical []string) (x, y *t.Dense, err error) {
+	xShape, yShape := m.FwdInput().Shape(), m.Y().Shape()
+	switch {
+	case dataPath != "" && (xPath != "" || yPath != ""):
+		return nil, nil, fmt.Errorf("set either data or x and y")
+	case strings.HasSuffix(strings.ToLower(dataPath), ".csv"):
+		if len(yColumns) == 0 {
+			return nil, nil, fmt.Errorf("y columns must be set to train on a CSV")
+		}
+		csv, err := data.ReadCSVFile(dataPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		isY := map[string]bool{}
+		for _, col := range yColumns {
+			isY[col] = true
+		}
+		xColumns := []string{}
+		for _, col := range csv.Header {
+			if !isY[col] {
+				xColumns = append(xColumns, col)
+			}
+		}
+		x, err = csv.Tensor(data.Columns{Names: xColumns, Categorical: categorical})
+		if err != nil {
+			return nil, nil, err
+		}
+		y, err = csv.Tensor(data.Columns{Names: yColumns, Categorical: categorical})
+		if err != nil {
+			return nil, nil, err
+		}
+	case strings.HasSuffix(strings.ToLower(dataPath), ".npz"):
+		x, err = readTensor(dataPath+":x", nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		y, err = readTensor(dataPath+":y", nil)
+		if err != nil {
+			return nil, nil, err
+		}
+	case dataPath != "":
+		return nil, nil, fmt.Errorf("cannot read %q, data must be a .csv or .npz file", dataPath)
+	case xPath != "" && yPath != "":
+		x, err = readTensor(xPath, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		y, err = readTensor(yPath, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+	default:
+		return nil, nil, fmt.Errorf("data or x and y must be set")
+	}
+	x, err = asInput(x, m, xShape)
+	if err != nil {
+		return nil, nil, err
+	}
+	y, err = asInput(y, m, yShape)
+	if err != nil {
+		return nil, nil, err
+	}
+	if x.Shape()[0] != y.Shape()[0] {
+		return nil, nil, fmt.Errorf("x has %d examples and y has %d", x.Shape()[0], y.Shape()[0])
+	}
+	return x, y, nil
+}
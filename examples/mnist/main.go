@@ -1,9 +1,12 @@
 package main
 
 import (
+	"io"
+
 	"github.com/aunum/gold/pkg/v1/common/num"
 	"github.com/aunum/gold/pkg/v1/common/require"
 	"github.com/aunum/gold/pkg/v1/dense"
+	"github.com/aunum/goro/pkg/v1/data"
 	"github.com/aunum/goro/pkg/v1/layer"
 	m "github.com/aunum/goro/pkg/v1/model"
 	"github.com/aunum/log"
@@ -31,7 +34,7 @@ func main() {
 	batchSize := 100
 	log.Infov("batchsize", batchSize)
 
-	batches := exampleSize / batchSize
+	batches := (exampleSize + batchSize - 1) / batchSize
 	log.Infov("num batches", batches)
 
 	xi := m.NewInput("x", []int{1, 1, 28, 28})
@@ -43,17 +46,18 @@ func main() {
 	model, err := m.NewSequential("mnist")
 	require.NoError(err)
 
-	model.AddLayers(
-		layer.Conv2D{Input: 1, Output: 32, Width: 3, Height: 3},
+	err = model.AddLayers(
+		layer.Conv2D{Output: 32, Width: 3, Height: 3},
 		layer.MaxPooling2D{},
-		layer.Conv2D{Input: 32, Output: 64, Width: 3, Height: 3},
+		layer.Conv2D{Output: 64, Width: 3, Height: 3},
 		layer.MaxPooling2D{},
-		layer.Conv2D{Input: 64, Output: 128, Width: 3, Height: 3},
+		layer.Conv2D{Output: 128, Width: 3, Height: 3},
 		layer.MaxPooling2D{},
 		layer.Flatten{},
-		layer.FC{Input: 128 * 3 * 3, Output: 100},
-		layer.FC{Input: 100, Output: 10, Activation: layer.Softmax},
+		layer.FC{Output: 100},
+		layer.FC{Output: 10, Activation: layer.Softmax},
 	)
+	require.NoError(err)
 
 	optimizer := g.NewRMSPropSolver(g.WithBatchSize(float64(batchSize)))
 	err = model.Compile(xi, yi,
@@ -65,30 +69,33 @@ func main() {
 
 	epochs := 20
 
+	// reshape the images, shuffle and batch the train set, preparing the next batch while the
+	// current one trains.
+	train, err := data.FromTensors(x.(*tensor.Dense), y.(*tensor.Dense))
+	require.NoError(err)
+	images := data.Map(train, func(ex data.Example) (data.Example, error) {
+		err := ex[0].Reshape(1, 1, 28, 28)
+		return ex, err
+	})
+	batched := data.Prefetch(data.Batch(data.Shuffle(images, exampleSize, 0), batchSize, false), 2)
+
 	log.Infov("epochs", epochs)
 	for epoch := 0; epoch < epochs; epoch++ {
-		for batch := 0; batch < batches; batch++ {
-			start := batch * batchSize
-			end := start + batchSize
-			if start >= exampleSize {
+		iter := batched.Iter()
+		for batch := 0; ; batch++ {
+			ex, err := iter.Next()
+			if err == io.EOF {
 				break
 			}
-			if end > exampleSize {
-				end = exampleSize
-			}
-
-			xi, err := x.Slice(dense.MakeRangedSlice(start, end))
-			require.NoError(err)
-			xi.Reshape(batchSize, 1, 28, 28)
-
-			yi, err := y.Slice(dense.MakeRangedSlice(start, end))
 			require.NoError(err)
-			yi.Reshape(batchSize, 10)
 
-			err = model.FitBatch(xi, yi)
+			// the last batch may be partial, the model compiles a graph for its size.
+			err = model.FitBatch(ex[0], ex[1])
 			require.NoError(err)
 			model.Tracker.LogStep(epoch, batch)
 		}
+		require.NoError(iter.Close())
+		model.NextEpoch()
 		accuracy, loss, err := evaluate(testX.(*tensor.Dense), testY.(*tensor.Dense), model, batchSize)
 		require.NoError(err)
 		log.Infof("completed train epoch %v with accuracy %v and loss %v", epoch, accuracy, loss)
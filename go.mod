@@ -8,6 +8,7 @@ require (
 	github.com/pkg/errors v0.9.1
 	github.com/skratchdot/open-golang v0.0.0-20200116055534-eef842397966
 	github.com/stretchr/testify v1.5.1
+	gopkg.in/yaml.v3 v3.0.1
 	gorgonia.org/gorgonia v0.9.9
 	gorgonia.org/tensor v0.9.4
 )
@@ -2,31 +2,114 @@
 package graph
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os/exec"
+	"strings"
 
-	"github.com/aunum/gold/pkg/v1/common/require"
 	"github.com/aunum/log"
 	"github.com/skratchdot/open-golang/open"
 
 	g "gorgonia.org/gorgonia"
 )
 
-// Visualize the graph using graphviz.
+// Group is a named group of nodes e.g. the nodes of a layer.
+type Group struct {
+	// Name of the group.
+	Name string
+
+	// Nodes in the group.
+	Nodes g.Nodes
+}
+
+// WriteDot writes the graph in graphviz DOT format. Nodes in a group are drawn together in a
+// cluster, edges point from the operands of a node to the node.
+func WriteDot(w io.Writer, graph *g.ExprGraph, groups ...Group) error {
+	if graph == nil {
+		return fmt.Errorf("graph is nil")
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph {")
+	fmt.Fprintln(bw, "\trankdir=BT;")
+	fmt.Fprintln(bw, "\tnode [shape=box, style=rounded, fontname=\"Helvetica\"];")
+
+	grouped := map[int64]bool{}
+	for i, group := range groups {
+		fmt.Fprintf(bw, "\tsubgraph cluster_%d {\n", i)
+		fmt.Fprintf(bw, "\t\tlabel=%s;\n", quote(group.Name))
+		for _, n := range group.Nodes {
+			if grouped[n.ID()] || !graph.Has(n.ID()) {
+				continue
+			}
+			grouped[n.ID()] = true
+			fmt.Fprintf(bw, "\t\t%s;\n", dotNode(n))
+		}
+		fmt.Fprintln(bw, "\t}")
+	}
+	nodes := graph.AllNodes()
+	for _, n := range nodes {
+		if !grouped[n.ID()] {
+			fmt.Fprintf(bw, "\t%s;\n", dotNode(n))
+		}
+	}
+	for _, n := range nodes {
+		children := graph.From(n.ID())
+		for children.Next() {
+			fmt.Fprintf(bw, "\tn%d -> n%d;\n", children.Node().ID(), n.ID())
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+func dotNode(n *g.Node) string {
+	label := n.Name()
+	if op := n.Op(); op != nil && !n.IsVar() {
+		label = fmt.Sprintf("%s\n%v", label, op)
+	}
+	if n.Type() != nil {
+		label = fmt.Sprintf("%s\n%v %v", label, n.Type(), n.Shape())
+	}
+	return fmt.Sprintf("n%d [label=%s]", n.ID(), quote(label))
+}
+
+func quote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	return `"` + s + `"`
+}
+
+// Visualize the graph using graphviz, opening the rendered SVG.
 //
-// Note: this requires graphviz `dot` to be installed on the host os.
-func Visualize(graph *g.ExprGraph) {
+// Note: this requires graphviz `dot` to be installed on the host os and a way to open files, use
+// WriteDot on headless servers.
+func Visualize(graph *g.ExprGraph, groups ...Group) error {
+	dot, err := exec.LookPath("dot")
+	if err != nil {
+		return fmt.Errorf("graphviz dot is required to visualize a graph: %w", err)
+	}
 	f, err := ioutil.TempFile("", "graph.*.dot")
-	require.NoError(err)
-	_, err = f.Write([]byte(graph.ToDot()))
-	require.NoError(err)
+	if err != nil {
+		return err
+	}
+	err = WriteDot(f, graph, groups...)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
 	tempPath := f.Name()
-	svgPath := fmt.Sprintf("%s.svg", f.Name())
+	svgPath := fmt.Sprintf("%s.svg", tempPath)
 	log.Debug("saved file: ", tempPath)
-	cmd := exec.Command("dot", "-Tsvg", tempPath, "-O")
-	err = cmd.Run()
-	require.NoError(err)
-	err = open.Run(svgPath)
-	require.NoError(err)
+	out, err := exec.Command(dot, "-Tsvg", tempPath, "-O").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("could not render graph: %w: %s", err, out)
+	}
+	return open.Run(svgPath)
 }
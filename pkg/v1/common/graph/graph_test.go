@@ -1,6 +1,10 @@
 package graph
 
 import (
+	"bytes"
+	"os/exec"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -13,8 +17,22 @@ func TestVisualize(t *testing.T) {
 	a := g.NewVector(graph, g.Float32, g.WithShape(4), g.WithName("test1"), g.WithInit(g.Zeroes()))
 	b := g.NewVector(graph, g.Float32, g.WithShape(4), g.WithName("test2"), g.WithInit(g.Zeroes()))
 
-	_, err := g.Mul(a, b)
+	c, err := g.Mul(a, b)
 	require.NoError(t, err)
 
-	Visualize(graph)
+	var buf bytes.Buffer
+	err = WriteDot(&buf, graph, Group{Name: "inputs", Nodes: g.Nodes{a, b}})
+	require.NoError(t, err)
+	dot := buf.String()
+	require.True(t, strings.HasPrefix(dot, "digraph {"))
+	require.Contains(t, dot, `label="inputs"`)
+	require.Contains(t, dot, "test1")
+	require.Equal(t, 2, strings.Count(dot, "-> n"+strconv.FormatInt(c.ID(), 10)+";"))
+
+	require.Error(t, WriteDot(&buf, nil))
+
+	// rendering requires graphviz, which must fail with an error rather than exit when missing.
+	if _, err := exec.LookPath("dot"); err != nil {
+		require.Error(t, Visualize(graph))
+	}
 }
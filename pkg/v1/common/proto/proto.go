@@ -0,0 +1,217 @@
+// Package proto encodes and decodes the subset of the protobuf wire format needed to write and read
+// messages of well known schemas, such as TensorBoard events and ONNX models, without generated code.
+// See https://developers.google.com/protocol-buffers/docs/encoding
+package proto
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Wire types.
+const (
+	WireVarint  = 0
+	WireFixed64 = 1
+	WireBytes   = 2
+	WireFixed32 = 5
+)
+
+// Message is an encoded protobuf message, fields are appended in the order they are added.
+type Message []byte
+
+func (m Message) key(field, wire int) Message {
+	return m.varint(uint64(field<<3 | wire))
+}
+
+func (m Message) varint(v uint64) Message {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(m, buf[:n]...)
+}
+
+// Varint adds a varint field e.g. uint64, enum or bool.
+func (m Message) Varint(field int, v uint64) Message {
+	return m.key(field, WireVarint).varint(v)
+}
+
+// Int64 adds an int64 field.
+func (m Message) Int64(field int, v int64) Message {
+	return m.Varint(field, uint64(v))
+}
+
+// Double adds a double field.
+func (m Message) Double(field int, v float64) Message {
+	m = m.key(field, WireFixed64)
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+	return append(m, buf[:]...)
+}
+
+// Float adds a float field.
+func (m Message) Float(field int, v float32) Message {
+	m = m.key(field, WireFixed32)
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], math.Float32bits(v))
+	return append(m, buf[:]...)
+}
+
+// Bytes adds a bytes field, which is also how embedded messages are added.
+func (m Message) Bytes(field int, b []byte) Message {
+	m = m.key(field, WireBytes).varint(uint64(len(b)))
+	return append(m, b...)
+}
+
+// String adds a string field.
+func (m Message) String(field int, s string) Message {
+	return m.Bytes(field, []byte(s))
+}
+
+// PackedDoubles adds a packed repeated double field.
+func (m Message) PackedDoubles(field int, vs []float64) Message {
+	packed := make([]byte, 8*len(vs))
+	for i, v := range vs {
+		binary.LittleEndian.PutUint64(packed[8*i:], math.Float64bits(v))
+	}
+	return m.Bytes(field, packed)
+}
+
+// PackedFloats adds a packed repeated float field.
+func (m Message) PackedFloats(field int, vs []float32) Message {
+	packed := make([]byte, 4*len(vs))
+	for i, v := range vs {
+		binary.LittleEndian.PutUint32(packed[4*i:], math.Float32bits(v))
+	}
+	return m.Bytes(field, packed)
+}
+
+// PackedInt64s adds a packed repeated int64 field.
+func (m Message) PackedInt64s(field int, vs []int64) Message {
+	packed := Message{}
+	for _, v := range vs {
+		packed = packed.varint(uint64(v))
+	}
+	return m.Bytes(field, packed)
+}
+
+// Field is a decoded field, the value is set for varint and fixed fields and the bytes for length
+// delimited fields.
+type Field struct {
+	// Number of the field.
+	Number int
+
+	// Wire type of the field.
+	Wire int
+
+	// Value of a varint or fixed field.
+	Value uint64
+
+	// Bytes of a length delimited field.
+	Bytes []byte
+}
+
+// Fields decodes the fields of a message.
+func Fields(b []byte) ([]Field, error) {
+	ret := []Field{}
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid field key")
+		}
+		b = b[n:]
+		f := Field{Number: int(key >> 3), Wire: int(key & 7)}
+		switch f.Wire {
+		case WireVarint:
+			f.Value, n = binary.Uvarint(b)
+			if n <= 0 {
+				return nil, fmt.Errorf("invalid varint for field %d", f.Number)
+			}
+			b = b[n:]
+		case WireFixed64:
+			if len(b) < 8 {
+				return nil, fmt.Errorf("truncated fixed64 for field %d", f.Number)
+			}
+			f.Value = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case WireFixed32:
+			if len(b) < 4 {
+				return nil, fmt.Errorf("truncated fixed32 for field %d", f.Number)
+			}
+			f.Value = uint64(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		case WireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return nil, fmt.Errorf("truncated bytes for field %d", f.Number)
+			}
+			f.Bytes = b[n : n+int(l)]
+			b = b[n+int(l):]
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d for field %d", f.Wire, f.Number)
+		}
+		ret = append(ret, f)
+	}
+	return ret, nil
+}
+
+// Int64 value of the field.
+func (f Field) Int64() int64 {
+	return int64(f.Value)
+}
+
+// Double value of the field.
+func (f Field) Double() float64 {
+	return math.Float64frombits(f.Value)
+}
+
+// Float value of the field.
+func (f Field) Float() float32 {
+	return math.Float32frombits(uint32(f.Value))
+}
+
+// String value of the field.
+func (f Field) String() string {
+	return string(f.Bytes)
+}
+
+// Doubles are the values of a repeated double field, which may or may not be packed.
+func (f Field) Doubles() []float64 {
+	if f.Wire == WireFixed64 {
+		return []float64{f.Double()}
+	}
+	ret := make([]float64, len(f.Bytes)/8)
+	for i := range ret {
+		ret[i] = math.Float64frombits(binary.LittleEndian.Uint64(f.Bytes[8*i:]))
+	}
+	return ret
+}
+
+// Floats are the values of a repeated float field, which may or may not be packed.
+func (f Field) Floats() []float32 {
+	if f.Wire == WireFixed32 {
+		return []float32{f.Float()}
+	}
+	ret := make([]float32, len(f.Bytes)/4)
+	for i := range ret {
+		ret[i] = math.Float32frombits(binary.LittleEndian.Uint32(f.Bytes[4*i:]))
+	}
+	return ret
+}
+
+// Int64s are the values of a repeated int64 field, which may or may not be packed.
+func (f Field) Int64s() ([]int64, error) {
+	if f.Wire == WireVarint {
+		return []int64{f.Int64()}, nil
+	}
+	ret := []int64{}
+	b := f.Bytes
+	for len(b) > 0 {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid packed varint for field %d", f.Number)
+		}
+		ret = append(ret, int64(v))
+		b = b[n:]
+	}
+	return ret, nil
+}
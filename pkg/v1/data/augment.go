@@ -0,0 +1,117 @@
+package data
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Augmentation transforms an image, drawing any randomness from the source.
+type Augmentation func(img *Image, r *rand.Rand) *Image
+
+// RandomCrop crops a random region of the height and width from the image, the image is returned as
+// is if it is not larger than the region. Images are resized before they are augmented, so the
+// region is the shape of the model input.
+func RandomCrop(height, width int) Augmentation {
+	return func(img *Image, r *rand.Rand) *Image {
+		if img.Height < height || img.Width < width {
+			return img
+		}
+		top := r.Intn(img.Height - height + 1)
+		left := r.Intn(img.Width - width + 1)
+		ret := NewImage(img.Channels, height, width)
+		for c := 0; c < img.Channels; c++ {
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					ret.Set(c, y, x, img.At(c, top+y, left+x))
+				}
+			}
+		}
+		return ret
+	}
+}
+
+// RandomFlip flips the image horizontally and or vertically, each with a probability of one half.
+func RandomFlip(horizontal, vertical bool) Augmentation {
+	return func(img *Image, r *rand.Rand) *Image {
+		flipX := horizontal && r.Intn(2) == 0
+		flipY := vertical && r.Intn(2) == 0
+		if !flipX && !flipY {
+			return img
+		}
+		ret := NewImage(img.Channels, img.Height, img.Width)
+		for c := 0; c < img.Channels; c++ {
+			for y := 0; y < img.Height; y++ {
+				sy := y
+				if flipY {
+					sy = img.Height - 1 - y
+				}
+				for x := 0; x < img.Width; x++ {
+					sx := x
+					if flipX {
+						sx = img.Width - 1 - x
+					}
+					ret.Set(c, y, x, img.At(c, sy, sx))
+				}
+			}
+		}
+		return ret
+	}
+}
+
+// RandomRotation rotates the image about its center by a random angle of up to the degrees either
+// way, areas rotated in from outside the image are black.
+func RandomRotation(degrees float64) Augmentation {
+	return func(img *Image, r *rand.Rand) *Image {
+		angle := (r.Float64()*2 - 1) * degrees * math.Pi / 180
+		sin, cos := math.Sincos(angle)
+		cy, cx := float64(img.Height-1)/2, float64(img.Width-1)/2
+		ret := NewImage(img.Channels, img.Height, img.Width)
+		for y := 0; y < img.Height; y++ {
+			for x := 0; x < img.Width; x++ {
+				// sample the source point that rotates onto the pixel.
+				dy, dx := float64(y)-cy, float64(x)-cx
+				sy := cos*dy - sin*dx + cy
+				sx := sin*dy + cos*dx + cx
+				for c := 0; c < img.Channels; c++ {
+					ret.Set(c, y, x, img.Bilinear(c, sy, sx))
+				}
+			}
+		}
+		return ret
+	}
+}
+
+// ColorJitter scales the brightness and contrast of the image by random factors between 1-x and
+// 1+x, values are clipped between 0 and 1.
+func ColorJitter(brightness, contrast float64) Augmentation {
+	return func(img *Image, r *rand.Rand) *Image {
+		b := float32(1 + (r.Float64()*2-1)*brightness)
+		c := float32(1 + (r.Float64()*2-1)*contrast)
+		var mean float32
+		for _, v := range img.Pix {
+			mean += v * b
+		}
+		if len(img.Pix) > 0 {
+			mean /= float32(len(img.Pix))
+		}
+		ret := NewImage(img.Channels, img.Height, img.Width)
+		for i, v := range img.Pix {
+			v = (v*b-mean)*c + mean
+			ret.Pix[i] = float32(math.Min(math.Max(float64(v), 0), 1))
+		}
+		return ret
+	}
+}
+
+// Normalize subtracts the mean and divides by the standard deviation of each channel.
+func Normalize(mean, std []float32) Augmentation {
+	return func(img *Image, r *rand.Rand) *Image {
+		ret := NewImage(img.Channels, img.Height, img.Width)
+		size := img.Height * img.Width
+		for i, v := range img.Pix {
+			c := i / size
+			ret.Pix[i] = (v - mean[c%len(mean)]) / std[c%len(std)]
+		}
+		return ret
+	}
+}
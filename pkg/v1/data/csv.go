@@ -0,0 +1,281 @@
+package data
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+
+	t "gorgonia.org/tensor"
+)
+
+// CSV is a table read from a CSV file.
+type CSV struct {
+	// Header is the names of the columns, which are the column indices e.g. "0" if the file has no
+	// header.
+	Header []string
+
+	// Records are the rows of the table.
+	Records [][]string
+}
+
+// CSVOpt is an option for reading a CSV.
+type CSVOpt func(*csvOpts)
+
+type csvOpts struct {
+	header bool
+	comma  rune
+}
+
+// WithoutHeader reads the first row of the CSV as a record rather than a header.
+func WithoutHeader() CSVOpt {
+	return func(o *csvOpts) {
+		o.header = false
+	}
+}
+
+// WithComma sets the field delimiter of the CSV.
+// Defaults to ','
+func WithComma(comma rune) CSVOpt {
+	return func(o *csvOpts) {
+		o.comma = comma
+	}
+}
+
+// ReadCSV reads a CSV, the first row is the header unless WithoutHeader is given.
+func ReadCSV(r io.Reader, opts ...CSVOpt) (*CSV, error) {
+	o := &csvOpts{header: true, comma: ','}
+	for _, opt := range opts {
+		opt(o)
+	}
+	reader := csv.NewReader(r)
+	reader.Comma = o.comma
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("csv is empty")
+	}
+	table := &CSV{}
+	if o.header {
+		table.Header, records = records[0], records[1:]
+	} else {
+		for i := range records[0] {
+			table.Header = append(table.Header, strconv.Itoa(i))
+		}
+	}
+	table.Records = records
+	return table, nil
+}
+
+// ReadCSVFile reads a CSV from the file at the path.
+func ReadCSVFile(path string, opts ...CSVOpt) (*CSV, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadCSV(f, opts...)
+}
+
+// Columns selects and encodes columns of a CSV as a tensor.
+type Columns struct {
+	// Names of the columns to select.
+	// Defaults to all columns.
+	Names []string
+
+	// Categorical are the names of the selected columns that hold categories, which are one-hot
+	// encoded.
+	Categorical []string
+
+	// Categories of the categorical columns in encoding order, set them to encode another file
+	// of the same data the same way.
+	// Defaults to the sorted unique values of the column.
+	Categories map[string][]string
+
+	// DType of the tensor.
+	// Defaults to Float32
+	DType t.Dtype
+
+	// Shape of a row e.g. the shape of an input without its batch dimension.
+	// Defaults to the number of encoded columns.
+	Shape t.Shape
+}
+
+// Column returns the index of the column with the name.
+func (c *CSV) Column(name string) (int, error) {
+	for i, h := range c.Header {
+		if h == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("no column %q in csv with columns %v", name, c.Header)
+}
+
+// Categories returns the sorted unique values of the column.
+func (c *CSV) Categories(name string) ([]string, error) {
+	col, err := c.Column(name)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	categories := []string{}
+	for _, record := range c.Records {
+		if seen[record[col]] {
+			continue
+		}
+		seen[record[col]] = true
+		categories = append(categories, record[col])
+	}
+	sort.Strings(categories)
+	return categories, nil
+}
+
+// Tensor returns the columns of the table as a tensor of shape (rows, row shape...) e.g. the
+// features of a CSV as x and the label as y.
+func (c *CSV) Tensor(cols Columns) (*t.Dense, error) {
+	names := cols.Names
+	if len(names) == 0 {
+		names = c.Header
+	}
+	dtype := cols.DType
+	if dtype.Type == nil {
+		dtype = t.Float32
+	}
+	categorical := map[string]bool{}
+	for _, name := range cols.Categorical {
+		categorical[name] = true
+	}
+
+	type column struct {
+		name       string
+		index      int
+		categories map[string]int
+		width      int
+	}
+	columns := []column{}
+	width := 0
+	for _, name := range names {
+		index, err := c.Column(name)
+		if err != nil {
+			return nil, err
+		}
+		col := column{name: name, index: index, width: 1}
+		if categorical[name] {
+			categories, ok := cols.Categories[name]
+			if !ok {
+				categories, err = c.Categories(name)
+				if err != nil {
+					return nil, err
+				}
+			}
+			col.categories = map[string]int{}
+			for i, category := range categories {
+				col.categories[category] = i
+			}
+			col.width = len(categories)
+		}
+		columns = append(columns, col)
+		width += col.width
+	}
+
+	backing := make([]float64, 0, len(c.Records)*width)
+	for row, record := range c.Records {
+		for _, col := range columns {
+			if col.index >= len(record) {
+				return nil, fmt.Errorf("row %d has no column %q", row, col.name)
+			}
+			value := record[col.index]
+			if col.categories == nil {
+				f, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					return nil, fmt.Errorf("row %d column %q: %w", row, col.name, err)
+				}
+				backing = append(backing, f)
+				continue
+			}
+			category, ok := col.categories[value]
+			if !ok {
+				return nil, fmt.Errorf("row %d column %q: unknown category %q", row, col.name, value)
+			}
+			oneHot := make([]float64, col.width)
+			oneHot[category] = 1
+			backing = append(backing, oneHot...)
+		}
+	}
+
+	shape := cols.Shape
+	if len(shape) == 0 {
+		shape = t.Shape{width}
+	}
+	if shape.TotalSize() != width {
+		return nil, fmt.Errorf("row shape %v does not fit the %d encoded columns", shape, width)
+	}
+	d := t.New(t.WithShape(append(t.Shape{len(c.Records)}, shape...)...), t.WithBacking(backing))
+	return Convert(d, dtype)
+}
+
+// WriteCSV writes the tensor as a CSV with a row for each index of its first dimension e.g. the
+// predictions of a batch. The header is optional.
+func WriteCSV(w io.Writer, d *t.Dense, header []string) error {
+	if d.Dims() == 0 {
+		return fmt.Errorf("tensor is a scalar, tensors must have a first dimension to write as rows")
+	}
+	rows := d.Shape()[0]
+	width := 1
+	if rows > 0 {
+		width = d.Shape().TotalSize() / rows
+	}
+	if header != nil && len(header) != width {
+		return fmt.Errorf("header has %d columns, tensor rows have %d", len(header), width)
+	}
+	writer := csv.NewWriter(w)
+	if header != nil {
+		err := writer.Write(header)
+		if err != nil {
+			return err
+		}
+	}
+	values := flat(d)
+	record := make([]string, width)
+	for row := 0; row < rows; row++ {
+		for col := range record {
+			record[col] = formatValue(values.Index(row*width + col))
+		}
+		err := writer.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteCSVFile writes the tensor as a CSV to the file at the path.
+func WriteCSVFile(path string, d *t.Dense, header []string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = WriteCSV(f, d, header)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
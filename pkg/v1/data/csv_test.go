@@ -0,0 +1,64 @@
+package data_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/aunum/goro/pkg/v1/data"
+
+	"github.com/stretchr/testify/require"
+	t "gorgonia.org/tensor"
+)
+
+const iris = `sepal_length,sepal_width,species
+5.1,3.5,setosa
+7.0,3.2,versicolor
+6.3,3.3,virginica
+4.9,3.0,setosa
+`
+
+func TestCSV(tt *testing.T) {
+	table, err := ReadCSV(strings.NewReader(iris))
+	require.NoError(tt, err)
+	require.Equal(tt, []string{"sepal_length", "sepal_width", "species"}, table.Header)
+	require.Len(tt, table.Records, 4)
+
+	x, err := table.Tensor(Columns{Names: []string{"sepal_length", "sepal_width"}, Shape: t.Shape{1, 2}})
+	require.NoError(tt, err)
+	require.Equal(tt, t.Shape{4, 1, 2}, x.Shape())
+	require.Equal(tt, t.Float32, x.Dtype())
+	require.Equal(tt, []float32{5.1, 3.5, 7.0, 3.2, 6.3, 3.3, 4.9, 3.0}, x.Float32s())
+
+	y, err := table.Tensor(Columns{Names: []string{"species"}, Categorical: []string{"species"}, DType: t.Float64})
+	require.NoError(tt, err)
+	require.Equal(tt, t.Shape{4, 3}, y.Shape())
+	require.Equal(tt, []float64{1, 0, 0, 0, 1, 0, 0, 0, 1, 1, 0, 0}, y.Float64s())
+
+	// categories can be fixed so other files encode the same way.
+	y, err = table.Tensor(Columns{
+		Names:       []string{"species"},
+		Categorical: []string{"species"},
+		Categories:  map[string][]string{"species": {"virginica", "versicolor", "setosa"}},
+	})
+	require.NoError(tt, err)
+	require.Equal(tt, []float32{0, 0, 1}, y.Float32s()[:3])
+
+	_, err = table.Tensor(Columns{})
+	require.Error(tt, err)
+	_, err = table.Tensor(Columns{Names: []string{"petal_length"}})
+	require.Error(tt, err)
+
+	table, err = ReadCSV(strings.NewReader("1;2\n3;4\n"), WithoutHeader(), WithComma(';'))
+	require.NoError(tt, err)
+	require.Equal(tt, []string{"0", "1"}, table.Header)
+	x, err = table.Tensor(Columns{Names: []string{"1"}})
+	require.NoError(tt, err)
+	require.Equal(tt, []float32{2, 4}, x.Float32s())
+
+	var buf bytes.Buffer
+	predictions := t.New(t.WithShape(2, 2), t.WithBacking([]float32{0.25, 0.75, 1, 0}))
+	err = WriteCSV(&buf, predictions, []string{"a", "b"})
+	require.NoError(tt, err)
+	require.Equal(tt, "a,b\n0.25,0.75\n1,0\n", buf.String())
+}
@@ -0,0 +1,175 @@
+// Package data provides datasets and data loading pipelines for models.
+package data
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/aunum/gold/pkg/v1/dense"
+
+	t "gorgonia.org/tensor"
+)
+
+// Example is a set of tensors that make up an example e.g. x and y. Each tensor has a leading
+// batch dimension, which is 1 for a single example.
+type Example []*t.Dense
+
+// Clone the example.
+func (e Example) Clone() Example {
+	ret := make(Example, len(e))
+	for i, d := range e {
+		ret[i] = d.Clone().(*t.Dense)
+	}
+	return ret
+}
+
+// Dataset is a source of examples.
+type Dataset interface {
+	// Iter returns a new iterator over the dataset.
+	Iter() Iterator
+}
+
+// Iterator iterates over the examples in a dataset.
+type Iterator interface {
+	// Next returns the next example, io.EOF is returned once the iterator is exhausted.
+	Next() (Example, error)
+
+	// Close the iterator, releasing any resources.
+	Close() error
+}
+
+// Collect all the examples from the dataset.
+func Collect(ds Dataset) ([]Example, error) {
+	iter := ds.Iter()
+	defer iter.Close()
+	examples := []Example{}
+	for {
+		ex, err := iter.Next()
+		if err == io.EOF {
+			return examples, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		examples = append(examples, ex)
+	}
+}
+
+// TensorDataset is an in-memory dataset of tensors sliced along their first dimension.
+type TensorDataset struct {
+	tensors []*t.Dense
+	size    int
+}
+
+// FromTensors returns a dataset of the tensors sliced along their first dimension, which must be
+// the same size for every tensor. Example i is made up of the i-th slice of each tensor with the
+// first dimension kept e.g. tensors of shape (100, 784) and (100, 10) give examples of shape
+// (1, 784) and (1, 10).
+func FromTensors(tensors ...*t.Dense) (*TensorDataset, error) {
+	if len(tensors) == 0 {
+		return nil, fmt.Errorf("no tensors provided")
+	}
+	size := -1
+	for i, d := range tensors {
+		if d.Dims() == 0 {
+			return nil, fmt.Errorf("tensor %d is a scalar, tensors must have a first dimension to slice", i)
+		}
+		if size == -1 {
+			size = d.Shape()[0]
+		}
+		if d.Shape()[0] != size {
+			return nil, fmt.Errorf("tensor %d has a first dimension of %d, expected %d", i, d.Shape()[0], size)
+		}
+	}
+	return &TensorDataset{tensors: tensors, size: size}, nil
+}
+
+// Len is the number of examples in the dataset.
+func (d *TensorDataset) Len() int {
+	return d.size
+}
+
+// Get the example at the index.
+func (d *TensorDataset) Get(index int) (Example, error) {
+	if index < 0 || index >= d.size {
+		return nil, fmt.Errorf("index %d out of range for dataset of size %d", index, d.size)
+	}
+	ex := make(Example, len(d.tensors))
+	for i, tensor := range d.tensors {
+		view, err := tensor.Slice(dense.MakeRangedSlice(index, index+1))
+		if err != nil {
+			return nil, err
+		}
+		if view.IsScalar() {
+			ex[i] = t.New(t.Of(tensor.Dtype()), t.WithShape(1))
+			ex[i].Set(0, view.Data())
+			continue
+		}
+		// views of contiguous memory are not copied when materialized.
+		ex[i] = view.Materialize().(*t.Dense).Clone().(*t.Dense)
+		err = ex[i].Reshape(append(t.Shape{1}, tensor.Shape()[1:]...)...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ex, nil
+}
+
+// Iter returns a new iterator over the dataset.
+func (d *TensorDataset) Iter() Iterator {
+	return &indexIterator{dataset: d}
+}
+
+// indexed is a dataset with random access to its examples.
+type indexed interface {
+	Len() int
+	Get(index int) (Example, error)
+}
+
+// indexIterator iterates over an indexed dataset in order.
+type indexIterator struct {
+	dataset indexed
+	index   int
+}
+
+func (i *indexIterator) Next() (Example, error) {
+	if i.index >= i.dataset.Len() {
+		return nil, io.EOF
+	}
+	ex, err := i.dataset.Get(i.index)
+	if err != nil {
+		return nil, err
+	}
+	i.index++
+	return ex, nil
+}
+
+func (i *indexIterator) Close() error {
+	return nil
+}
+
+// SliceDataset is an in-memory dataset of examples.
+type SliceDataset []Example
+
+// Iter returns a new iterator over the dataset.
+func (s SliceDataset) Iter() Iterator {
+	return &sliceIterator{examples: s}
+}
+
+type sliceIterator struct {
+	examples []Example
+	index    int
+}
+
+func (i *sliceIterator) Next() (Example, error) {
+	if i.index >= len(i.examples) {
+		return nil, io.EOF
+	}
+	ex := i.examples[i.index]
+	i.index++
+	return ex, nil
+}
+
+func (i *sliceIterator) Close() error {
+	return nil
+}
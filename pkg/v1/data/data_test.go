@@ -0,0 +1,123 @@
+package data_test
+
+import (
+	"io"
+	"testing"
+
+	. "github.com/aunum/goro/pkg/v1/data"
+
+	"github.com/stretchr/testify/require"
+	t "gorgonia.org/tensor"
+)
+
+func newDataset(tt *testing.T, size int) *TensorDataset {
+	x := t.New(t.WithShape(size, 2), t.WithBacking(t.Range(t.Float32, 0, size*2)))
+	y := t.New(t.WithShape(size), t.WithBacking(t.Range(t.Float32, 0, size)))
+	ds, err := FromTensors(x, y)
+	require.NoError(tt, err)
+	return ds
+}
+
+func TestTensorDataset(tt *testing.T) {
+	ds := newDataset(tt, 5)
+	require.Equal(tt, 5, ds.Len())
+
+	examples, err := Collect(ds)
+	require.NoError(tt, err)
+	require.Len(tt, examples, 5)
+	require.Equal(tt, t.Shape{1, 2}, examples[3][0].Shape())
+	require.Equal(tt, []float32{6, 7}, examples[3][0].Data())
+	require.Equal(tt, t.Shape{1}, examples[3][1].Shape())
+
+	// examples must not share memory with the dataset.
+	examples[0][0].Set(0, float32(100))
+	ex, err := ds.Get(0)
+	require.NoError(tt, err)
+	require.Equal(tt, []float32{0, 1}, ex[0].Data())
+
+	_, err = FromTensors(t.New(t.WithShape(2, 2), t.Of(t.Float32)), t.New(t.WithShape(3), t.Of(t.Float32)))
+	require.Error(tt, err)
+}
+
+func TestTransforms(tt *testing.T) {
+	ds := newDataset(tt, 10)
+
+	even := Filter(ds, func(ex Example) (bool, error) {
+		return int(ex[1].Float32s()[0])%2 == 0, nil
+	})
+	doubled := Map(even, func(ex Example) (Example, error) {
+		y, err := ex[1].MulScalar(float32(2), true)
+		if err != nil {
+			return nil, err
+		}
+		return Example{ex[0], y}, nil
+	})
+	batches, err := Collect(Batch(doubled, 2, false))
+	require.NoError(tt, err)
+	require.Len(tt, batches, 3)
+	require.Equal(tt, t.Shape{2, 2}, batches[0][0].Shape())
+	require.Equal(tt, []float32{0, 1, 4, 5}, batches[0][0].Data())
+	require.Equal(tt, []float32{0, 4}, batches[0][1].Data())
+	require.Equal(tt, t.Shape{1, 2}, batches[2][0].Shape())
+
+	batches, err = Collect(Batch(doubled, 2, true))
+	require.NoError(tt, err)
+	require.Len(tt, batches, 2)
+
+	repeated, err := Collect(Repeat(ds, 3))
+	require.NoError(tt, err)
+	require.Len(tt, repeated, 30)
+
+	empty := Filter(ds, func(ex Example) (bool, error) { return false, nil })
+	repeated, err = Collect(Repeat(empty, 0))
+	require.NoError(tt, err)
+	require.Empty(tt, repeated)
+
+	shuffled := Shuffle(ds, 10, 1)
+	first, err := Collect(shuffled)
+	require.NoError(tt, err)
+	second, err := Collect(shuffled)
+	require.NoError(tt, err)
+	require.Len(tt, first, 10)
+	seen := map[float32]bool{}
+	for _, ex := range first {
+		seen[ex[1].Float32s()[0]] = true
+	}
+	require.Len(tt, seen, 10)
+	require.NotEqual(tt, ys(first), ys(second))
+
+	again, err := Collect(Shuffle(ds, 10, 1))
+	require.NoError(tt, err)
+	require.Equal(tt, ys(first), ys(again))
+}
+
+func TestPrefetch(tt *testing.T) {
+	ds := Prefetch(Batch(newDataset(tt, 10), 3, false), 2)
+	batches, err := Collect(ds)
+	require.NoError(tt, err)
+	require.Len(tt, batches, 4)
+	require.Equal(tt, []float32{9}, batches[3][1].Float32s())
+
+	// closing early stops the background goroutine.
+	iter := Prefetch(Repeat(newDataset(tt, 10), 0), 2).Iter()
+	_, err = iter.Next()
+	require.NoError(tt, err)
+	require.NoError(tt, iter.Close())
+
+	iter = ds.Iter()
+	for i := 0; i < 4; i++ {
+		_, err = iter.Next()
+		require.NoError(tt, err)
+	}
+	_, err = iter.Next()
+	require.Equal(tt, io.EOF, err)
+	require.NoError(tt, iter.Close())
+}
+
+func ys(examples []Example) []float32 {
+	ret := []float32{}
+	for _, ex := range examples {
+		ret = append(ret, ex[1].Float32s()...)
+	}
+	return ret
+}
@@ -0,0 +1,38 @@
+package data
+
+import (
+	"fmt"
+	"reflect"
+
+	t "gorgonia.org/tensor"
+)
+
+// Convert the tensor to the data type e.g. float64 arrays saved by NumPy to the float32 of a model.
+// The tensor is returned as is if it already has the data type.
+func Convert(d *t.Dense, dtype t.Dtype) (*t.Dense, error) {
+	if d.Dtype() == dtype {
+		return d, nil
+	}
+	if !isNumeric(d.Dtype().Kind()) || !isNumeric(dtype.Kind()) {
+		return nil, fmt.Errorf("cannot convert %v to %v, only numeric types can be converted", d.Dtype(), dtype)
+	}
+	from := flat(d)
+	to := reflect.MakeSlice(reflect.SliceOf(dtype.Type), from.Len(), from.Len())
+	for i := 0; i < from.Len(); i++ {
+		to.Index(i).Set(from.Index(i).Convert(dtype.Type))
+	}
+	if d.IsScalar() && d.Dims() == 0 {
+		return t.New(t.FromScalar(to.Index(0).Interface())), nil
+	}
+	return t.New(t.WithShape(d.Shape().Clone()...), t.WithBacking(to.Interface())), nil
+}
+
+func isNumeric(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,275 @@
+package data
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg" // register the jpeg decoder.
+	_ "image/png"  // register the png decoder.
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	t "gorgonia.org/tensor"
+)
+
+// Image is a decoded image in (channels, height, width) order with values between 0 and 1.
+type Image struct {
+	Channels, Height, Width int
+
+	// Pix are the values of the image, channel by channel.
+	Pix []float32
+}
+
+// NewImage returns a black image of the size.
+func NewImage(channels, height, width int) *Image {
+	return &Image{Channels: channels, Height: height, Width: width, Pix: make([]float32, channels*height*width)}
+}
+
+// At is the value of the channel at the pixel, zero outside the image.
+func (i *Image) At(c, y, x int) float32 {
+	if y < 0 || y >= i.Height || x < 0 || x >= i.Width {
+		return 0
+	}
+	return i.Pix[(c*i.Height+y)*i.Width+x]
+}
+
+// Set the value of the channel at the pixel.
+func (i *Image) Set(c, y, x int, v float32) {
+	i.Pix[(c*i.Height+y)*i.Width+x] = v
+}
+
+// Bilinear interpolates the value of the channel at a point between pixels.
+func (i *Image) Bilinear(c int, y, x float64) float32 {
+	y0, x0 := int(math.Floor(y)), int(math.Floor(x))
+	dy, dx := float32(y-float64(y0)), float32(x-float64(x0))
+	top := i.At(c, y0, x0)*(1-dx) + i.At(c, y0, x0+1)*dx
+	bottom := i.At(c, y0+1, x0)*(1-dx) + i.At(c, y0+1, x0+1)*dx
+	return top*(1-dy) + bottom*dy
+}
+
+// Resize the image with bilinear interpolation.
+func (i *Image) Resize(height, width int) *Image {
+	if height == i.Height && width == i.Width {
+		return i
+	}
+	ret := NewImage(i.Channels, height, width)
+	scaleY := float64(i.Height) / float64(height)
+	scaleX := float64(i.Width) / float64(width)
+	for c := 0; c < i.Channels; c++ {
+		for y := 0; y < height; y++ {
+			// sample at pixel centers, clamped to the edge so borders aren't darkened.
+			sy := math.Min(math.Max((float64(y)+0.5)*scaleY-0.5, 0), float64(i.Height-1))
+			for x := 0; x < width; x++ {
+				sx := math.Min(math.Max((float64(x)+0.5)*scaleX-0.5, 0), float64(i.Width-1))
+				ret.Set(c, y, x, i.Bilinear(c, sy, sx))
+			}
+		}
+	}
+	return ret
+}
+
+// Tensor returns the image as a tensor of shape (1, channels, height, width), the input shape of
+// Conv2D.
+func (i *Image) Tensor() *t.Dense {
+	pix := make([]float32, len(i.Pix))
+	copy(pix, i.Pix)
+	return t.New(t.WithShape(1, i.Channels, i.Height, i.Width), t.WithBacking(pix))
+}
+
+// FromImage converts a decoded image to an image with 1 (grayscale) or 3 (RGB) channels.
+func FromImage(img image.Image, channels int) (*Image, error) {
+	if channels != 1 && channels != 3 {
+		return nil, fmt.Errorf("images must have 1 or 3 channels, got %d", channels)
+	}
+	bounds := img.Bounds()
+	ret := NewImage(channels, bounds.Dy(), bounds.Dx())
+	for y := 0; y < ret.Height; y++ {
+		for x := 0; x < ret.Width; x++ {
+			c := img.At(bounds.Min.X+x, bounds.Min.Y+y)
+			if channels == 1 {
+				gray := color.Gray16Model.Convert(c).(color.Gray16)
+				ret.Set(0, y, x, float32(gray.Y)/math.MaxUint16)
+				continue
+			}
+			r, g, b, _ := c.RGBA()
+			ret.Set(0, y, x, float32(r)/math.MaxUint16)
+			ret.Set(1, y, x, float32(g)/math.MaxUint16)
+			ret.Set(2, y, x, float32(b)/math.MaxUint16)
+		}
+	}
+	return ret, nil
+}
+
+// ReadImageFile decodes the PNG or JPEG file at the path.
+func ReadImageFile(path string, channels int) (*Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %q: %w", path, err)
+	}
+	return FromImage(img, channels)
+}
+
+// ImageOpt is an option for an image dataset.
+type ImageOpt func(*ImageDataset)
+
+// WithImageSize resizes the images to the height and width.
+// Defaults to the size of the first image.
+func WithImageSize(height, width int) ImageOpt {
+	return func(d *ImageDataset) {
+		d.height, d.width = height, width
+	}
+}
+
+// WithGrayscale decodes the images with a single channel rather than RGB.
+func WithGrayscale() ImageOpt {
+	return func(d *ImageDataset) {
+		d.channels = 1
+	}
+}
+
+// WithClasses sets the class folders in label order.
+// Defaults to the sorted folder names.
+func WithClasses(classes ...string) ImageOpt {
+	return func(d *ImageDataset) {
+		d.Classes = classes
+	}
+}
+
+// WithLabelShape sets the shape of the one-hot labels e.g. the shape of the y input of the model.
+// Defaults to (1, classes).
+func WithLabelShape(shape ...int) ImageOpt {
+	return func(d *ImageDataset) {
+		d.labelShape = shape
+	}
+}
+
+// WithAugmentations applies the augmentations in order to each image as it is read.
+func WithAugmentations(augmentations ...Augmentation) ImageOpt {
+	return func(d *ImageDataset) {
+		d.augmentations = augmentations
+	}
+}
+
+// WithAugmentationSeed seeds the randomness of the augmentations.
+// Defaults to 0
+func WithAugmentationSeed(seed int64) ImageOpt {
+	return func(d *ImageDataset) {
+		d.rand = rand.New(rand.NewSource(seed))
+	}
+}
+
+// ImageDataset is a dataset of images in a folder per class, examples are an image of shape
+// (1, channels, height, width) and a one-hot label. Images are decoded and augmented as they are
+// read.
+type ImageDataset struct {
+	// Classes are the class names in label order.
+	Classes []string
+
+	files         []string
+	labels        []int
+	channels      int
+	height, width int
+	labelShape    t.Shape
+	augmentations []Augmentation
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// ImageFolder returns a dataset of the PNG and JPEG images in the directory, which has a folder
+// of images for each class e.g. dir/cat/1.png and dir/dog/1.jpg.
+func ImageFolder(dir string, opts ...ImageOpt) (*ImageDataset, error) {
+	d := &ImageDataset{channels: 3, rand: rand.New(rand.NewSource(0))}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.Classes == nil {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				d.Classes = append(d.Classes, entry.Name())
+			}
+		}
+		sort.Strings(d.Classes)
+	}
+	if len(d.Classes) == 0 {
+		return nil, fmt.Errorf("no class folders in %q", dir)
+	}
+	if d.labelShape == nil {
+		d.labelShape = t.Shape{1, len(d.Classes)}
+	}
+	if d.labelShape.TotalSize() < len(d.Classes) {
+		return nil, fmt.Errorf("label shape %v is too small for %d classes", d.labelShape, len(d.Classes))
+	}
+	for label, class := range d.Classes {
+		entries, err := ioutil.ReadDir(filepath.Join(dir, class))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			switch strings.ToLower(filepath.Ext(entry.Name())) {
+			case ".png", ".jpg", ".jpeg":
+				d.files = append(d.files, filepath.Join(dir, class, entry.Name()))
+				d.labels = append(d.labels, label)
+			}
+		}
+	}
+	if len(d.files) == 0 {
+		return nil, fmt.Errorf("no images in %q", dir)
+	}
+	if d.height == 0 || d.width == 0 {
+		img, err := ReadImageFile(d.files[0], d.channels)
+		if err != nil {
+			return nil, err
+		}
+		d.height, d.width = img.Height, img.Width
+	}
+	return d, nil
+}
+
+// Len is the number of images in the dataset.
+func (d *ImageDataset) Len() int {
+	return len(d.files)
+}
+
+// Get the example at the index, the image is augmented each time it is read.
+func (d *ImageDataset) Get(index int) (Example, error) {
+	if index < 0 || index >= len(d.files) {
+		return nil, fmt.Errorf("index %d out of range for dataset of size %d", index, len(d.files))
+	}
+	img, err := ReadImageFile(d.files[index], d.channels)
+	if err != nil {
+		return nil, err
+	}
+	img = img.Resize(d.height, d.width)
+	if len(d.augmentations) > 0 {
+		// augmentations are drawn from a single source so they are reproducible for the seed.
+		d.mu.Lock()
+		for _, augment := range d.augmentations {
+			img = augment(img, d.rand)
+		}
+		d.mu.Unlock()
+	}
+	label := t.New(t.WithShape(d.labelShape...), t.Of(t.Float32))
+	label.Float32s()[d.labels[index]] = 1
+	return Example{img.Tensor(), label}, nil
+}
+
+// Iter returns a new iterator over the dataset.
+func (d *ImageDataset) Iter() Iterator {
+	return &indexIterator{dataset: d}
+}
@@ -0,0 +1,93 @@
+package data_test
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/aunum/goro/pkg/v1/data"
+
+	"github.com/stretchr/testify/require"
+	t "gorgonia.org/tensor"
+)
+
+func writePNG(tt *testing.T, path string, c color.Color) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	require.NoError(tt, os.MkdirAll(filepath.Dir(path), 0755))
+	f, err := os.Create(path)
+	require.NoError(tt, err)
+	defer f.Close()
+	require.NoError(tt, png.Encode(f, img))
+}
+
+func TestImageFolder(tt *testing.T) {
+	dir, err := ioutil.TempDir("", "images")
+	require.NoError(tt, err)
+	defer os.RemoveAll(dir)
+	writePNG(tt, filepath.Join(dir, "red", "1.png"), color.RGBA{R: 255, A: 255})
+	writePNG(tt, filepath.Join(dir, "red", "2.png"), color.RGBA{R: 255, A: 255})
+	writePNG(tt, filepath.Join(dir, "white", "1.png"), color.White)
+	require.NoError(tt, ioutil.WriteFile(filepath.Join(dir, "white", "notes.txt"), []byte("skipped"), 0644))
+
+	ds, err := ImageFolder(dir, WithImageSize(2, 2), WithLabelShape(1, 10))
+	require.NoError(tt, err)
+	require.Equal(tt, []string{"red", "white"}, ds.Classes)
+	require.Equal(tt, 3, ds.Len())
+
+	ex, err := ds.Get(0)
+	require.NoError(tt, err)
+	require.Equal(tt, t.Shape{1, 3, 2, 2}, ex[0].Shape())
+	require.Equal(tt, []float32{1, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0}, ex[0].Float32s())
+	require.Equal(tt, t.Shape{1, 10}, ex[1].Shape())
+	require.Equal(tt, []float32{1, 0, 0, 0, 0, 0, 0, 0, 0, 0}, ex[1].Float32s())
+
+	batches, err := Collect(Batch(ds, 3, false))
+	require.NoError(tt, err)
+	require.Equal(tt, t.Shape{3, 3, 2, 2}, batches[0][0].Shape())
+	require.Equal(tt, float32(1), batches[0][1].Float32s()[21])
+
+	gray, err := ImageFolder(dir, WithGrayscale(), WithClasses("white"),
+		WithAugmentations(RandomCrop(3, 3), RandomFlip(true, true), RandomRotation(10), ColorJitter(0.2, 0.2), Normalize([]float32{0.5}, []float32{0.5})),
+	)
+	require.NoError(tt, err)
+	require.Equal(tt, 1, gray.Len())
+	ex, err = gray.Get(0)
+	require.NoError(tt, err)
+	require.Equal(tt, t.Shape{1, 1, 3, 3}, ex[0].Shape())
+
+	_, err = ImageFolder(dir, WithLabelShape(1, 1))
+	require.Error(tt, err)
+}
+
+func TestAugmentations(tt *testing.T) {
+	img := NewImage(1, 2, 3)
+	copy(img.Pix, []float32{0, 1, 2, 3, 4, 5})
+	r := rand.New(rand.NewSource(0))
+
+	flipped := img
+	for flipped == img {
+		flipped = RandomFlip(true, false)(img, r)
+	}
+	require.Equal(tt, []float32{2, 1, 0, 5, 4, 3}, flipped.Pix)
+
+	cropped := RandomCrop(2, 2)(img, r)
+	require.Equal(tt, 2, cropped.Width)
+	require.Contains(tt, [][]float32{{0, 1, 3, 4}, {1, 2, 4, 5}}, cropped.Pix)
+
+	require.Equal(tt, img.Pix, RandomRotation(0)(img, r).Pix)
+	require.Equal(tt, []float32{-1, 1, 3, 5, 7, 9}, Normalize([]float32{0.5}, []float32{0.5})(img, r).Pix)
+
+	resized := img.Resize(4, 6)
+	require.Equal(tt, float32(0), resized.At(0, 0, 0))
+	require.Equal(tt, float32(5), resized.At(0, 3, 5))
+}
@@ -0,0 +1,290 @@
+package data
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	t "gorgonia.org/tensor"
+)
+
+// NumPy array format, see https://numpy.org/doc/stable/reference/generated/numpy.lib.format.html
+
+const npyMagic = "\x93NUMPY"
+
+var (
+	npyDescr   = regexp.MustCompile(`'descr':\s*'([<>|=])([a-z])(\d+)'`)
+	npyFortran = regexp.MustCompile(`'fortran_order':\s*(True|False)`)
+	npyShape   = regexp.MustCompile(`'shape':\s*\(([^)]*)\)`)
+
+	npyDTypes = map[string]t.Dtype{
+		"b1": t.Bool,
+		"i1": t.Int8,
+		"i2": t.Int16,
+		"i4": t.Int32,
+		"i8": t.Int64,
+		"u1": t.Uint8,
+		"u2": t.Uint16,
+		"u4": t.Uint32,
+		"u8": t.Uint64,
+		"f4": t.Float32,
+		"f8": t.Float64,
+	}
+)
+
+// ReadNPY reads a NumPy .npy array into a tensor.
+func ReadNPY(r io.Reader) (*t.Dense, error) {
+	magic := make([]byte, len(npyMagic)+2)
+	_, err := io.ReadFull(r, magic)
+	if err != nil {
+		return nil, fmt.Errorf("could not read npy magic: %w", err)
+	}
+	if string(magic[:len(npyMagic)]) != npyMagic {
+		return nil, fmt.Errorf("not a npy file")
+	}
+	var headerLen int
+	switch major := magic[len(npyMagic)]; major {
+	case 1:
+		var l uint16
+		err = binary.Read(r, binary.LittleEndian, &l)
+		headerLen = int(l)
+	case 2, 3:
+		var l uint32
+		err = binary.Read(r, binary.LittleEndian, &l)
+		headerLen = int(l)
+	default:
+		return nil, fmt.Errorf("npy version %d is not supported", major)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read npy header length: %w", err)
+	}
+	header := make([]byte, headerLen)
+	_, err = io.ReadFull(r, header)
+	if err != nil {
+		return nil, fmt.Errorf("could not read npy header: %w", err)
+	}
+
+	match := npyDescr.FindSubmatch(header)
+	if match == nil {
+		return nil, fmt.Errorf("npy header %q has no supported descr", header)
+	}
+	dtype, ok := npyDTypes[string(match[2])+string(match[3])]
+	if !ok {
+		return nil, fmt.Errorf("npy dtype %q is not supported", string(match[2])+string(match[3]))
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if string(match[1]) == ">" {
+		order = binary.BigEndian
+	}
+	match = npyFortran.FindSubmatch(header)
+	if match == nil {
+		return nil, fmt.Errorf("npy header %q has no fortran_order", header)
+	}
+	fortran := string(match[1]) == "True"
+	match = npyShape.FindSubmatch(header)
+	if match == nil {
+		return nil, fmt.Errorf("npy header %q has no shape", header)
+	}
+	shape := t.Shape{}
+	for _, s := range strings.Split(string(match[1]), ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		size, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("npy shape %q is invalid: %w", match[1], err)
+		}
+		if size < 0 {
+			return nil, fmt.Errorf("npy shape %q has a negative dimension", match[1])
+		}
+		shape = append(shape, size)
+	}
+
+	// the data is read before allocating the backing so that the shape is checked against it.
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not read npy data: %w", err)
+	}
+	itemSize := int(dtype.Size())
+	size := 1
+	for _, s := range shape {
+		if s != 0 && size > len(data)/itemSize/s {
+			return nil, fmt.Errorf("npy shape %v is larger than the %d bytes of data", shape, len(data))
+		}
+		size *= s
+	}
+	if size*itemSize != len(data) {
+		return nil, fmt.Errorf("npy shape %v of %v needs %d bytes of data, got %d", shape, dtype, size*itemSize, len(data))
+	}
+	backing := reflect.MakeSlice(reflect.SliceOf(dtype.Type), size, size)
+	err = binary.Read(bytes.NewReader(data), order, backing.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("could not read npy data: %w", err)
+	}
+	if len(shape) == 0 {
+		return t.New(t.FromScalar(backing.Index(0).Interface())), nil
+	}
+	if !fortran || len(shape) == 1 {
+		return t.New(t.WithShape(shape...), t.WithBacking(backing.Interface())), nil
+	}
+	// fortran ordered data is the transpose of the reversed shape in row major order.
+	reversed := make(t.Shape, len(shape))
+	for i, s := range shape {
+		reversed[len(shape)-1-i] = s
+	}
+	d := t.New(t.WithShape(reversed...), t.WithBacking(backing.Interface()))
+	err = d.T()
+	if err != nil {
+		return nil, err
+	}
+	err = d.Transpose()
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ReadNPYFile reads a NumPy .npy array from the file at the path.
+func ReadNPYFile(path string) (*t.Dense, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadNPY(f)
+}
+
+// WriteNPY writes the tensor as a NumPy .npy array.
+func WriteNPY(w io.Writer, d *t.Dense) error {
+	var descr string
+	for name, dtype := range npyDTypes {
+		if dtype == d.Dtype() {
+			descr = name
+		}
+	}
+	if descr == "" {
+		return fmt.Errorf("dtype %v can not be written to npy", d.Dtype())
+	}
+	if descr != "b1" && descr != "i1" && descr != "u1" {
+		descr = "<" + descr
+	} else {
+		descr = "|" + descr
+	}
+	dims := []string{}
+	if d.Dims() > 0 {
+		for _, s := range d.Shape() {
+			dims = append(dims, strconv.Itoa(s))
+		}
+	}
+	shape := strings.Join(dims, ", ")
+	if len(dims) == 1 {
+		shape += ","
+	}
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%s), }", descr, shape)
+	// the header is padded so the data is aligned to 64 bytes, ending in a newline.
+	total := len(npyMagic) + 4 + len(header) + 1
+	header += strings.Repeat(" ", (64-total%64)%64) + "\n"
+
+	buf := bytes.NewBufferString(npyMagic)
+	buf.Write([]byte{1, 0})
+	err := binary.Write(buf, binary.LittleEndian, uint16(len(header)))
+	if err != nil {
+		return err
+	}
+	buf.WriteString(header)
+	_, err = w.Write(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, flat(d).Interface())
+}
+
+// WriteNPYFile writes the tensor as a NumPy .npy array to the file at the path.
+func WriteNPYFile(path string, d *t.Dense) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = WriteNPY(f, d)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadNPZ reads the arrays of a NumPy .npz archive by name, compressed archives are supported.
+func ReadNPZ(r io.ReaderAt, size int64) (map[string]*t.Dense, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	arrays := map[string]*t.Dense{}
+	for _, f := range archive.File {
+		name := strings.TrimSuffix(f.Name, ".npy")
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		arrays[name], err = ReadNPY(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("array %q: %w", name, err)
+		}
+	}
+	return arrays, nil
+}
+
+// ReadNPZFile reads the arrays of a NumPy .npz archive from the file at the path.
+func ReadNPZFile(path string) (map[string]*t.Dense, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ReadNPZ(bytes.NewReader(b), int64(len(b)))
+}
+
+// WriteNPZ writes the tensors as a compressed NumPy .npz archive of arrays by name.
+func WriteNPZ(w io.Writer, arrays map[string]*t.Dense) error {
+	names := []string{}
+	for name := range arrays {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	archive := zip.NewWriter(w)
+	for _, name := range names {
+		f, err := archive.Create(name + ".npy")
+		if err != nil {
+			return err
+		}
+		err = WriteNPY(f, arrays[name])
+		if err != nil {
+			return fmt.Errorf("array %q: %w", name, err)
+		}
+	}
+	return archive.Close()
+}
+
+// WriteNPZFile writes the tensors as a compressed NumPy .npz archive to the file at the path.
+func WriteNPZFile(path string, arrays map[string]*t.Dense) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = WriteNPZ(f, arrays)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
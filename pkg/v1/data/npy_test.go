@@ -0,0 +1,74 @@
+package data_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	. "github.com/aunum/goro/pkg/v1/data"
+
+	"github.com/stretchr/testify/require"
+	t "gorgonia.org/tensor"
+)
+
+// npy builds an array the way NumPy saves it.
+func npy(tt *testing.T, header string, data interface{}) []byte {
+	for (10+len(header)+1)%64 != 0 {
+		header += " "
+	}
+	header += "\n"
+	buf := bytes.NewBufferString("\x93NUMPY\x01\x00")
+	require.NoError(tt, binary.Write(buf, binary.LittleEndian, uint16(len(header))))
+	buf.WriteString(header)
+	require.NoError(tt, binary.Write(buf, binary.LittleEndian, data))
+	return buf.Bytes()
+}
+
+func TestNPY(tt *testing.T) {
+	b := npy(tt, "{'descr': '<f8', 'fortran_order': False, 'shape': (2, 3), }", []float64{0, 1, 2, 3, 4, 5})
+	d, err := ReadNPY(bytes.NewReader(b))
+	require.NoError(tt, err)
+	require.Equal(tt, t.Shape{2, 3}, d.Shape())
+	require.Equal(tt, []float64{0, 1, 2, 3, 4, 5}, d.Float64s())
+
+	b = npy(tt, "{'descr': '<i4', 'fortran_order': True, 'shape': (2, 3), }", []int32{0, 3, 1, 4, 2, 5})
+	d, err = ReadNPY(bytes.NewReader(b))
+	require.NoError(tt, err)
+	require.Equal(tt, t.Shape{2, 3}, d.Shape())
+	require.Equal(tt, []int32{0, 1, 2, 3, 4, 5}, d.Int32s())
+
+	converted, err := Convert(d, t.Float32)
+	require.NoError(tt, err)
+	require.Equal(tt, []float32{0, 1, 2, 3, 4, 5}, converted.Float32s())
+
+	var buf bytes.Buffer
+	x := t.New(t.WithShape(3), t.WithBacking([]float32{1, 2, 3}))
+	require.NoError(tt, WriteNPY(&buf, x))
+	require.Equal(tt, npy(tt, "{'descr': '<f4', 'fortran_order': False, 'shape': (3,), }", []float32{1, 2, 3}), buf.Bytes())
+
+	_, err = ReadNPY(bytes.NewReader([]byte("not numpy")))
+	require.Error(tt, err)
+
+	// shapes which do not match the data are rejected before allocating.
+	for _, shape := range []string{"(-1, 3)", "(2, 4)", "(1,)", "(4611686018427387904, 4)"} {
+		b = npy(tt, "{'descr': '<f8', 'fortran_order': False, 'shape': "+shape+", }", []float64{0, 1, 2, 3, 4, 5})
+		_, err = ReadNPY(bytes.NewReader(b))
+		require.Error(tt, err, shape)
+	}
+}
+
+func TestNPZ(tt *testing.T) {
+	arrays := map[string]*t.Dense{
+		"x": t.New(t.WithShape(2, 2), t.WithBacking([]float32{1, 2, 3, 4})),
+		"y": t.New(t.WithShape(2), t.WithBacking([]int64{0, 1})),
+	}
+	var buf bytes.Buffer
+	require.NoError(tt, WriteNPZ(&buf, arrays))
+
+	read, err := ReadNPZ(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(tt, err)
+	require.Len(tt, read, 2)
+	require.Equal(tt, t.Shape{2, 2}, read["x"].Shape())
+	require.Equal(tt, []float32{1, 2, 3, 4}, read["x"].Float32s())
+	require.Equal(tt, []int64{0, 1}, read["y"].Int64s())
+}
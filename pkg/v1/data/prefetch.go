@@ -0,0 +1,83 @@
+package data
+
+import (
+	"io"
+	"sync"
+)
+
+// Prefetch prepares up to the buffer size of examples from the dataset on a background goroutine,
+// so the next batch is ready while the current one is used e.g. while the VM runs.
+func Prefetch(ds Dataset, bufferSize int) Dataset {
+	return &prefetchDataset{upstream: ds, bufferSize: bufferSize}
+}
+
+type prefetchDataset struct {
+	upstream   Dataset
+	bufferSize int
+}
+
+func (p *prefetchDataset) Iter() Iterator {
+	size := p.bufferSize
+	if size < 1 {
+		size = 1
+	}
+	iter := &prefetchIterator{
+		upstream: p.upstream.Iter(),
+		items:    make(chan prefetched, size),
+		done:     make(chan struct{}),
+	}
+	iter.wg.Add(1)
+	go iter.run()
+	return iter
+}
+
+type prefetched struct {
+	example Example
+	err     error
+}
+
+type prefetchIterator struct {
+	upstream Iterator
+	items    chan prefetched
+	done     chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+	closeErr error
+}
+
+func (p *prefetchIterator) run() {
+	defer p.wg.Done()
+	defer close(p.items)
+	for {
+		ex, err := p.upstream.Next()
+		if err == io.EOF {
+			return
+		}
+		select {
+		case p.items <- prefetched{example: ex, err: err}:
+		case <-p.done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (p *prefetchIterator) Next() (Example, error) {
+	item, ok := <-p.items
+	if !ok {
+		return nil, io.EOF
+	}
+	return item.example, item.err
+}
+
+// Close stops prefetching and closes the underlying iterator.
+func (p *prefetchIterator) Close() error {
+	p.once.Do(func() {
+		close(p.done)
+		p.wg.Wait()
+		p.closeErr = p.upstream.Close()
+	})
+	return p.closeErr
+}
@@ -0,0 +1,299 @@
+package data
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"reflect"
+	"sync/atomic"
+
+	t "gorgonia.org/tensor"
+)
+
+// MapFn transforms an example.
+type MapFn func(Example) (Example, error)
+
+// Map applies the function to each example of the dataset.
+func Map(ds Dataset, fn MapFn) Dataset {
+	return &mapDataset{upstream: ds, fn: fn}
+}
+
+type mapDataset struct {
+	upstream Dataset
+	fn       MapFn
+}
+
+func (m *mapDataset) Iter() Iterator {
+	return &mapIterator{upstream: m.upstream.Iter(), fn: m.fn}
+}
+
+type mapIterator struct {
+	upstream Iterator
+	fn       MapFn
+}
+
+func (m *mapIterator) Next() (Example, error) {
+	ex, err := m.upstream.Next()
+	if err != nil {
+		return nil, err
+	}
+	return m.fn(ex)
+}
+
+func (m *mapIterator) Close() error {
+	return m.upstream.Close()
+}
+
+// FilterFn tells whether an example should be kept.
+type FilterFn func(Example) (bool, error)
+
+// Filter keeps only the examples of the dataset for which the function returns true.
+func Filter(ds Dataset, fn FilterFn) Dataset {
+	return &filterDataset{upstream: ds, fn: fn}
+}
+
+type filterDataset struct {
+	upstream Dataset
+	fn       FilterFn
+}
+
+func (f *filterDataset) Iter() Iterator {
+	return &filterIterator{upstream: f.upstream.Iter(), fn: f.fn}
+}
+
+type filterIterator struct {
+	upstream Iterator
+	fn       FilterFn
+}
+
+func (f *filterIterator) Next() (Example, error) {
+	for {
+		ex, err := f.upstream.Next()
+		if err != nil {
+			return nil, err
+		}
+		keep, err := f.fn(ex)
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			return ex, nil
+		}
+	}
+}
+
+func (f *filterIterator) Close() error {
+	return f.upstream.Close()
+}
+
+// Batch combines consecutive examples of the dataset into batches by concatenating each tensor of the
+// examples along the first dimension. The final batch may be smaller than the size unless the
+// remainder is dropped.
+func Batch(ds Dataset, size int, dropRemainder bool) Dataset {
+	return &batchDataset{upstream: ds, size: size, dropRemainder: dropRemainder}
+}
+
+type batchDataset struct {
+	upstream      Dataset
+	size          int
+	dropRemainder bool
+}
+
+func (b *batchDataset) Iter() Iterator {
+	return &batchIterator{upstream: b.upstream.Iter(), dataset: b}
+}
+
+type batchIterator struct {
+	upstream Iterator
+	dataset  *batchDataset
+	done     bool
+}
+
+func (b *batchIterator) Next() (Example, error) {
+	if b.dataset.size < 1 {
+		return nil, fmt.Errorf("batch size must be at least 1, got %d", b.dataset.size)
+	}
+	if b.done {
+		return nil, io.EOF
+	}
+	examples := []Example{}
+	for len(examples) < b.dataset.size {
+		ex, err := b.upstream.Next()
+		if err == io.EOF {
+			b.done = true
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		examples = append(examples, ex)
+	}
+	if len(examples) == 0 || (b.dataset.dropRemainder && len(examples) < b.dataset.size) {
+		return nil, io.EOF
+	}
+	return Stack(examples)
+}
+
+func (b *batchIterator) Close() error {
+	return b.upstream.Close()
+}
+
+// Stack concatenates the tensors of the examples along their first dimension.
+func Stack(examples []Example) (Example, error) {
+	if len(examples) == 0 {
+		return nil, fmt.Errorf("no examples to stack")
+	}
+	width := len(examples[0])
+	ret := make(Example, width)
+	for i := 0; i < width; i++ {
+		first := examples[0][i]
+		if first.Dims() == 0 {
+			return nil, fmt.Errorf("tensor %d is a scalar, tensors must have a first dimension to stack", i)
+		}
+		rest := first.Shape()[1:]
+		rows := 0
+		backing := reflect.MakeSlice(reflect.SliceOf(first.Dtype().Type), 0, 0)
+		for j, ex := range examples {
+			if len(ex) != width {
+				return nil, fmt.Errorf("example %d has %d tensors, expected %d", j, len(ex), width)
+			}
+			d := ex[i]
+			if d.Dtype() != first.Dtype() {
+				return nil, fmt.Errorf("tensor %d of example %d has dtype %v, expected %v", i, j, d.Dtype(), first.Dtype())
+			}
+			if d.Dims() == 0 || !d.Shape()[1:].Eq(rest) {
+				return nil, fmt.Errorf("tensor %d of example %d has shape %v, expected (n, %v)", i, j, d.Shape(), rest)
+			}
+			rows += d.Shape()[0]
+			backing = reflect.AppendSlice(backing, flat(d))
+		}
+		// tensors are stacked by copying their data rather than with Concat, which can't handle
+		// tensors of a single element.
+		shape := append(t.Shape{rows}, rest...)
+		ret[i] = t.New(t.WithShape(shape...), t.WithBacking(backing.Interface()))
+	}
+	return ret, nil
+}
+
+// flat returns the data of the tensor as a slice, including tensors of a single element whose data
+// is a scalar.
+func flat(d *t.Dense) reflect.Value {
+	d = d.Materialize().(*t.Dense)
+	data := reflect.ValueOf(d.Data())
+	if data.Kind() == reflect.Slice {
+		return data
+	}
+	ret := reflect.MakeSlice(reflect.SliceOf(data.Type()), 1, 1)
+	ret.Index(0).Set(data)
+	return ret
+}
+
+// Shuffle the examples of the dataset using a buffer of the given size, examples are drawn at
+// random from the buffer which is refilled from the dataset. A buffer at least the size of the
+// dataset gives a uniform shuffle. Each iterator is shuffled differently, deterministic for the seed.
+func Shuffle(ds Dataset, bufferSize int, seed int64) Dataset {
+	return &shuffleDataset{upstream: ds, bufferSize: bufferSize, seed: seed}
+}
+
+type shuffleDataset struct {
+	upstream   Dataset
+	bufferSize int
+	seed       int64
+	iters      int64
+}
+
+func (s *shuffleDataset) Iter() Iterator {
+	n := atomic.AddInt64(&s.iters, 1)
+	return &shuffleIterator{
+		upstream: s.upstream.Iter(),
+		size:     s.bufferSize,
+		rand:     rand.New(rand.NewSource(s.seed + n - 1)),
+	}
+}
+
+type shuffleIterator struct {
+	upstream  Iterator
+	size      int
+	rand      *rand.Rand
+	buffer    []Example
+	exhausted bool
+}
+
+func (s *shuffleIterator) Next() (Example, error) {
+	if s.size < 1 {
+		return nil, fmt.Errorf("shuffle buffer size must be at least 1, got %d", s.size)
+	}
+	for !s.exhausted && len(s.buffer) < s.size {
+		ex, err := s.upstream.Next()
+		if err == io.EOF {
+			s.exhausted = true
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.buffer = append(s.buffer, ex)
+	}
+	if len(s.buffer) == 0 {
+		return nil, io.EOF
+	}
+	i := s.rand.Intn(len(s.buffer))
+	ex := s.buffer[i]
+	last := len(s.buffer) - 1
+	s.buffer[i] = s.buffer[last]
+	s.buffer = s.buffer[:last]
+	return ex, nil
+}
+
+func (s *shuffleIterator) Close() error {
+	return s.upstream.Close()
+}
+
+// Repeat the dataset the number of times, a count less than 1 repeats forever.
+func Repeat(ds Dataset, count int) Dataset {
+	return &repeatDataset{upstream: ds, count: count}
+}
+
+type repeatDataset struct {
+	upstream Dataset
+	count    int
+}
+
+func (r *repeatDataset) Iter() Iterator {
+	return &repeatIterator{dataset: r, current: r.upstream.Iter(), pass: 1}
+}
+
+type repeatIterator struct {
+	dataset *repeatDataset
+	current Iterator
+	pass    int
+	empty   bool
+}
+
+func (r *repeatIterator) Next() (Example, error) {
+	for {
+		ex, err := r.current.Next()
+		if err == nil {
+			r.empty = false
+			return ex, nil
+		}
+		if err != io.EOF {
+			return nil, err
+		}
+		// stop rather than loop forever on an empty dataset.
+		if r.empty || (r.dataset.count > 0 && r.pass >= r.dataset.count) {
+			return nil, io.EOF
+		}
+		err = r.current.Close()
+		if err != nil {
+			return nil, err
+		}
+		r.current = r.dataset.upstream.Iter()
+		r.pass++
+		r.empty = true
+	}
+}
+
+func (r *repeatIterator) Close() error {
+	return r.current.Close()
+}
@@ -0,0 +1,593 @@
+// Package keras imports Keras sequential models.
+//
+// The architecture is the JSON written by Keras `model.to_json()` and the weights are NumPy arrays
+// by name e.g. from an .npz archive written with
+//
+//	np.savez("weights.npz", **{w.name: w.numpy() for w in model.weights})
+//
+// or, in layer order, with `np.savez("weights.npz", *model.get_weights())`. HDF5 files are not read
+// directly, convert them to .npz with the above after `keras.models.load_model`.
+//
+// Images in goro are channels first, models which are channels last in Keras are imported with the
+// input as (channels, height, width), so input images must be transposed from NHWC to NCHW.
+package keras
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aunum/goro/pkg/v1/data"
+	"github.com/aunum/goro/pkg/v1/layer"
+	"github.com/aunum/goro/pkg/v1/model"
+
+	g "gorgonia.org/gorgonia"
+	t "gorgonia.org/tensor"
+)
+
+// Model is a Keras model architecture.
+type Model struct {
+	// ClassName of the model, only Sequential is supported.
+	ClassName string `json:"class_name"`
+
+	// Config of the model, which is the layers in older versions of Keras.
+	Config json.RawMessage `json:"config"`
+
+	// KerasVersion the model was written with.
+	KerasVersion string `json:"keras_version,omitempty"`
+}
+
+// Layer is a Keras layer.
+type Layer struct {
+	// ClassName of the layer e.g. Dense.
+	ClassName string `json:"class_name"`
+
+	// Config of the layer.
+	Config LayerConfig `json:"config"`
+}
+
+// LayerConfig is the config of a Keras layer, only the fields used by supported layers are decoded.
+type LayerConfig struct {
+	Name            string          `json:"name"`
+	BatchInputShape []*int          `json:"batch_input_shape,omitempty"`
+	BatchShape      []*int          `json:"batch_shape,omitempty"`
+	DType           json.RawMessage `json:"dtype,omitempty"`
+	Units           int             `json:"units,omitempty"`
+	Activation      string          `json:"activation,omitempty"`
+	UseBias         *bool           `json:"use_bias,omitempty"`
+	Filters         int             `json:"filters,omitempty"`
+	KernelSize      []int           `json:"kernel_size,omitempty"`
+	Strides         []int           `json:"strides,omitempty"`
+	Padding         string          `json:"padding,omitempty"`
+	DataFormat      string          `json:"data_format,omitempty"`
+	DilationRate    []int           `json:"dilation_rate,omitempty"`
+	PoolSize        []int           `json:"pool_size,omitempty"`
+	Rate            float64         `json:"rate,omitempty"`
+	TargetShape     []int           `json:"target_shape,omitempty"`
+	Alpha           *float64        `json:"alpha,omitempty"`
+	NegativeSlope   *float64        `json:"negative_slope,omitempty"`
+}
+
+// supportedLayers are the Keras layers which can be imported.
+var supportedLayers = map[string]bool{
+	"InputLayer":   true,
+	"Dense":        true,
+	"Conv2D":       true,
+	"MaxPooling2D": true,
+	"Flatten":      true,
+	"Reshape":      true,
+	"Dropout":      true,
+	"Activation":   true,
+	"ReLU":         true,
+	"LeakyReLU":    true,
+	"Softmax":      true,
+}
+
+// Layers of the model.
+func (m *Model) Layers() ([]Layer, error) {
+	if m.ClassName != "Sequential" {
+		return nil, fmt.Errorf("only sequential models can be imported, got %q", m.ClassName)
+	}
+	layers := []Layer{}
+	if err := json.Unmarshal(m.Config, &layers); err == nil {
+		return layers, nil
+	}
+	config := struct {
+		Layers []Layer `json:"layers"`
+	}{}
+	err := json.Unmarshal(m.Config, &config)
+	if err != nil {
+		return nil, err
+	}
+	return config.Layers, nil
+}
+
+// Name of the model.
+func (m *Model) Name() string {
+	config := struct {
+		Name string `json:"name"`
+	}{}
+	json.Unmarshal(m.Config, &config)
+	return config.Name
+}
+
+// Import a Keras sequential model from its JSON architecture with the weights by name, returning it
+// compiled. If weights are nil the model is initialized as it would be in goro. Any options are
+// applied when the model is compiled.
+func Import(architecture io.Reader, weights map[string]*t.Dense, opts ...model.Opt) (*model.Sequential, error) {
+	m := &Model{}
+	err := json.NewDecoder(architecture).Decode(m)
+	if err != nil {
+		return nil, err
+	}
+	layers, err := m.Layers()
+	if err != nil {
+		return nil, err
+	}
+	unsupported := map[string]bool{}
+	for _, l := range layers {
+		if !supportedLayers[l.ClassName] {
+			unsupported[l.ClassName] = true
+		}
+	}
+	if len(unsupported) > 0 {
+		names := []string{}
+		for name := range unsupported {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unsupported keras layers: %s", strings.Join(names, ", "))
+	}
+	if len(layers) == 0 {
+		return nil, fmt.Errorf("model has no layers")
+	}
+
+	i := &importer{weights: weights, dtype: t.Float32}
+	err = i.input(layers[0].Config)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range layers {
+		if l.Config.DataFormat == "channels_first" {
+			i.channelsLast = false
+		}
+	}
+	shape := append(t.Shape{1}, i.shape...)
+	if i.channelsLast && len(i.shape) == 3 {
+		shape = t.Shape{1, i.shape[2], i.shape[0], i.shape[1]}
+	}
+	for _, l := range layers {
+		err = i.add(l)
+		if err != nil {
+			return nil, fmt.Errorf("layer %q %s: %w", l.Config.Name, l.ClassName, err)
+		}
+	}
+
+	chain, err := layer.NewChain(i.configs...)
+	if err != nil {
+		return nil, err
+	}
+	output, err := chain.InferShapes(shape)
+	if err != nil {
+		return nil, err
+	}
+	name := m.Name()
+	if name == "" {
+		name = "keras"
+	}
+	seq, err := model.NewSequential(name)
+	if err != nil {
+		return nil, err
+	}
+	err = seq.AddLayers(i.configs...)
+	if err != nil {
+		return nil, err
+	}
+	x := model.NewInput("x", shape, model.AsType(i.dtype))
+	y := model.NewInput("y", output, model.AsType(i.dtype))
+	err = seq.Compile(x, y, append([]model.Opt{model.WithDType(i.dtype)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	if weights == nil {
+		return seq, nil
+	}
+	scratch := g.NewGraph()
+	learnables := g.Nodes{}
+	for _, v := range i.learnables {
+		v, err = data.Convert(v, i.dtype)
+		if err != nil {
+			return nil, err
+		}
+		learnables = append(learnables, g.NodeFromAny(scratch, v))
+	}
+	err = seq.SetLearnables(learnables)
+	if err != nil {
+		return nil, err
+	}
+	return seq, nil
+}
+
+// ImportFiles imports a Keras sequential model from a JSON architecture file and a .npz weights file,
+// the weights path may be empty to initialize the model as it would be in goro.
+func ImportFiles(architecturePath, weightsPath string, opts ...model.Opt) (*model.Sequential, error) {
+	f, err := os.Open(architecturePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var weights map[string]*t.Dense
+	if weightsPath != "" {
+		weights, err = data.ReadNPZFile(weightsPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return Import(f, weights, opts...)
+}
+
+// importer converts Keras layers into layer configs.
+type importer struct {
+	weights map[string]*t.Dense
+
+	// ordered is the index of the next weight when they are in layer order.
+	ordered int
+
+	dtype        t.Dtype
+	configs      []layer.Config
+	learnables   []*t.Dense
+	channelsLast bool
+
+	// shape is the current shape in the Keras layout without the batch.
+	shape t.Shape
+
+	// flattened is the channels last shape which was flattened, if any, as the rows of the kernel of a
+	// following dense layer must be reordered for a channels first flatten.
+	flattened t.Shape
+
+	// canActivate is whether an activation can be folded into the last layer.
+	canActivate bool
+}
+
+// input reads the input shape and data type from the first layer.
+func (i *importer) input(config LayerConfig) error {
+	batchShape := config.BatchInputShape
+	if batchShape == nil {
+		batchShape = config.BatchShape
+	}
+	if len(batchShape) < 2 {
+		return fmt.Errorf("the first layer must have an input shape")
+	}
+	for _, d := range batchShape[1:] {
+		if d == nil {
+			return fmt.Errorf("input shape %v has dynamic dimensions, only the batch may be dynamic", batchShape)
+		}
+		i.shape = append(i.shape, *d)
+	}
+	var dtype string
+	if json.Unmarshal(config.DType, &dtype) == nil && dtype == "float64" {
+		i.dtype = t.Float64
+	}
+	i.channelsLast = true
+	return nil
+}
+
+func (i *importer) add(l Layer) error {
+	c := l.Config
+	canActivate := false
+	defer func() { i.canActivate = canActivate }()
+	if l.ClassName != "Dropout" && l.ClassName != "Dense" && l.ClassName != "InputLayer" {
+		if i.flattened != nil && l.ClassName != "Activation" {
+			return fmt.Errorf("only dropout may be between a flatten and a dense layer")
+		}
+	}
+
+	switch l.ClassName {
+	case "InputLayer":
+		return nil
+	case "Dense":
+		if len(i.shape) != 1 {
+			return fmt.Errorf("dense layers must have a flat input, got shape %v", i.shape)
+		}
+		activation, err := activation(c.Activation, nil)
+		if err != nil {
+			return err
+		}
+		bias := c.UseBias == nil || *c.UseBias
+		fc := layer.FC{Input: i.shape[0], Output: c.Units, Name: c.Name, Activation: activation, NoBias: !bias}
+		if i.weights != nil {
+			kernel, err := i.weight(c.Name, "kernel", t.Shape{fc.Input, fc.Output})
+			if err != nil {
+				return err
+			}
+			if i.flattened != nil {
+				kernel, err = channelsFirstRows(kernel, i.flattened)
+				if err != nil {
+					return err
+				}
+			}
+			i.learnables = append(i.learnables, kernel)
+			if bias {
+				b, err := i.weight(c.Name, "bias", t.Shape{fc.Output})
+				if err != nil {
+					return err
+				}
+				i.learnables = append(i.learnables, reshaped(b, 1, fc.Output))
+			}
+		}
+		i.flattened = nil
+		i.configs = append(i.configs, fc)
+		i.shape = t.Shape{c.Units}
+		canActivate = true
+	case "Conv2D":
+		if len(i.shape) != 3 {
+			return fmt.Errorf("conv2d layers must have an image input, got shape %v", i.shape)
+		}
+		if len(c.KernelSize) != 2 {
+			return fmt.Errorf("kernel size must be 2D, got %v", c.KernelSize)
+		}
+		activation, err := activation(c.Activation, nil)
+		if err != nil {
+			return err
+		}
+		height, width, channels := i.spatial()
+		strides := defaultInts(c.Strides, 1, 1)
+		dilation := defaultInts(c.DilationRate, 1, 1)
+		pad, err := padding(c.Padding, c.KernelSize, strides, dilation)
+		if err != nil {
+			return err
+		}
+		bias := c.UseBias == nil || *c.UseBias
+		conv := layer.Conv2D{
+			Input:      channels,
+			Output:     c.Filters,
+			Height:     c.KernelSize[0],
+			Width:      c.KernelSize[1],
+			Name:       c.Name,
+			Activation: activation,
+			Pad:        pad,
+			Stride:     strides,
+			Dilation:   dilation,
+			Bias:       bias,
+		}
+		if i.weights != nil {
+			kernel, err := i.weight(c.Name, "kernel", t.Shape{conv.Height, conv.Width, conv.Input, conv.Output})
+			if err != nil {
+				return err
+			}
+			// keras kernels are (height, width, input, output), goro filters are (output, input, height, width).
+			kernel, err = permuted(kernel, 3, 2, 0, 1)
+			if err != nil {
+				return err
+			}
+			i.learnables = append(i.learnables, kernel)
+			if bias {
+				b, err := i.weight(c.Name, "bias", t.Shape{conv.Output})
+				if err != nil {
+					return err
+				}
+				i.learnables = append(i.learnables, reshaped(b, 1, conv.Output, 1, 1))
+			}
+		}
+		i.configs = append(i.configs, conv)
+		height = (height+2*pad[0]-dilation[0]*(conv.Height-1)-1)/strides[0] + 1
+		width = (width+2*pad[1]-dilation[1]*(conv.Width-1)-1)/strides[1] + 1
+		i.setSpatial(height, width, c.Filters)
+		canActivate = true
+	case "MaxPooling2D":
+		if len(i.shape) != 3 {
+			return fmt.Errorf("pooling layers must have an image input, got shape %v", i.shape)
+		}
+		if c.Padding != "" && c.Padding != "valid" {
+			return fmt.Errorf("padding %q is not supported", c.Padding)
+		}
+		pool := defaultInts(c.PoolSize, 2, 2)
+		strides := defaultInts(c.Strides, pool...)
+		i.configs = append(i.configs, layer.MaxPooling2D{
+			Kernel: t.Shape(pool),
+			Pad:    []int{0, 0},
+			Stride: strides,
+			Name:   c.Name,
+		})
+		height, width, channels := i.spatial()
+		i.setSpatial((height-pool[0])/strides[0]+1, (width-pool[1])/strides[1]+1, channels)
+	case "Flatten":
+		if i.channelsLast && len(i.shape) == 3 && i.shape[2] > 1 {
+			i.flattened = i.shape.Clone()
+		}
+		i.configs = append(i.configs, layer.Flatten{})
+		i.shape = t.Shape{i.shape.TotalSize()}
+	case "Reshape":
+		if i.channelsLast && len(i.shape) == 3 && i.shape[2] > 1 {
+			return fmt.Errorf("reshaping channels last images with more than one channel is not supported")
+		}
+		to := t.Shape(c.TargetShape)
+		i.shape = to.Clone()
+		if i.channelsLast && len(to) == 3 {
+			if to[2] != 1 {
+				return fmt.Errorf("reshaping to channels last images with more than one channel is not supported")
+			}
+			to = t.Shape{1, to[0], to[1]}
+		}
+		i.configs = append(i.configs, layer.Reshape{To: to})
+	case "Dropout":
+		// a rate of zero is the dropout default in goro, and drops nothing in keras.
+		if c.Rate > 0 {
+			i.configs = append(i.configs, layer.Dropout{Probability: c.Rate})
+		}
+		canActivate = i.canActivate
+	default:
+		name := c.Activation
+		switch l.ClassName {
+		case "ReLU":
+			name = "relu"
+		case "LeakyReLU":
+			name = "leaky_relu"
+		case "Softmax":
+			name = "softmax"
+		}
+		slope := c.Alpha
+		if slope == nil {
+			slope = c.NegativeSlope
+		}
+		if slope == nil && l.ClassName == "LeakyReLU" {
+			// the default slope of the keras leaky relu layer.
+			alpha := 0.3
+			slope = &alpha
+		}
+		fn, err := activation(name, slope)
+		if err != nil {
+			return err
+		}
+		if !i.canActivate {
+			return fmt.Errorf("activations must follow a dense or conv2d layer")
+		}
+		switch last := i.configs[len(i.configs)-1].(type) {
+		case layer.FC:
+			if last.Activation != layer.Linear {
+				return fmt.Errorf("layer %q already has an activation", last.Name)
+			}
+			last.Activation = fn
+			i.configs[len(i.configs)-1] = last
+		case layer.Conv2D:
+			if last.Activation != layer.Linear {
+				return fmt.Errorf("layer %q already has an activation", last.Name)
+			}
+			last.Activation = fn
+			i.configs[len(i.configs)-1] = last
+		default:
+			// folding the activation into the layer before a dropout is only correct when predicting.
+			return fmt.Errorf("activations cannot follow a %T layer", last)
+		}
+	}
+	return nil
+}
+
+// spatial returns the height, width and channels of the current image shape.
+func (i *importer) spatial() (height, width, channels int) {
+	if i.channelsLast {
+		return i.shape[0], i.shape[1], i.shape[2]
+	}
+	return i.shape[1], i.shape[2], i.shape[0]
+}
+
+// setSpatial sets the current image shape in the Keras layout.
+func (i *importer) setSpatial(height, width, channels int) {
+	if i.channelsLast {
+		i.shape = t.Shape{height, width, channels}
+		return
+	}
+	i.shape = t.Shape{channels, height, width}
+}
+
+// weight returns a weight of a layer by name, or the next weight if they are in layer order.
+func (i *importer) weight(layerName, kind string, shape t.Shape) (*t.Dense, error) {
+	candidates := []string{
+		fmt.Sprintf("%s/%s:0", layerName, kind),
+		fmt.Sprintf("%s/%s", layerName, kind),
+		fmt.Sprintf("%s/%s/%s:0", layerName, layerName, kind),
+		fmt.Sprintf("%s/%s/%s", layerName, layerName, kind),
+	}
+	var w *t.Dense
+	for _, name := range candidates {
+		if w = i.weights[name]; w != nil {
+			break
+		}
+	}
+	if w == nil {
+		w = i.weights[fmt.Sprintf("arr_%d", i.ordered)]
+		i.ordered++
+	}
+	if w == nil {
+		return nil, fmt.Errorf("no %s weights, expected one of %v", kind, candidates)
+	}
+	if !w.Shape().Eq(shape) {
+		return nil, fmt.Errorf("%s weights have shape %v, expected %v", kind, w.Shape(), shape)
+	}
+	return w.Clone().(*t.Dense), nil
+}
+
+// activation returns the activation function by its Keras name.
+func activation(name string, alpha *float64) (layer.ActivationFn, error) {
+	switch name {
+	case "", "linear":
+		return layer.Linear, nil
+	case "relu":
+		return layer.NewReLU(), nil
+	case "leaky_relu":
+		if alpha == nil {
+			// the default slope of the keras leaky relu activation.
+			return layer.NewLeakyReLU(0.2), nil
+		}
+		return layer.NewLeakyReLU(*alpha), nil
+	case "sigmoid":
+		return layer.NewSigmoid(), nil
+	case "tanh":
+		return layer.NewTanh(), nil
+	case "softmax":
+		return layer.NewSoftmax(), nil
+	}
+	return nil, fmt.Errorf("unsupported activation %q", name)
+}
+
+// padding returns the padding for a Keras padding mode, same padding is supported when it is
+// symmetric.
+func padding(mode string, kernel, strides, dilation []int) ([]int, error) {
+	switch mode {
+	case "", "valid":
+		return []int{0, 0}, nil
+	case "same":
+		pad := []int{}
+		for d := range kernel {
+			total := dilation[d] * (kernel[d] - 1)
+			if strides[d] != 1 || total%2 != 0 {
+				return nil, fmt.Errorf("same padding is only supported with a stride of 1 and odd kernel sizes")
+			}
+			pad = append(pad, total/2)
+		}
+		return pad, nil
+	}
+	return nil, fmt.Errorf("padding %q is not supported", mode)
+}
+
+func defaultInts(vs []int, def ...int) []int {
+	if len(vs) == 0 {
+		return def
+	}
+	return vs
+}
+
+// reshaped returns the tensor reshaped.
+func reshaped(d *t.Dense, shape ...int) *t.Dense {
+	d.Reshape(shape...)
+	return d
+}
+
+// permuted returns a copy of the tensor with its axes permuted.
+func permuted(d *t.Dense, axes ...int) (*t.Dense, error) {
+	ret := d.Clone().(*t.Dense)
+	err := ret.T(axes...)
+	if err != nil {
+		return nil, err
+	}
+	err = ret.Transpose()
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// channelsFirstRows reorders the rows of a dense kernel which follows the flatten of a channels last
+// image of the shape, so that they are in the order of a channels first flatten.
+func channelsFirstRows(kernel *t.Dense, shape t.Shape) (*t.Dense, error) {
+	height, width, channels := shape[0], shape[1], shape[2]
+	units := kernel.Shape()[1]
+	image, err := permuted(reshaped(kernel, height, width, channels, units), 2, 0, 1, 3)
+	if err != nil {
+		return nil, err
+	}
+	return reshaped(image, height*width*channels, units), nil
+}
@@ -0,0 +1,94 @@
+package keras_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aunum/goro/pkg/v1/data"
+	. "github.com/aunum/goro/pkg/v1/keras"
+	"github.com/aunum/goro/pkg/v1/layer"
+	"github.com/aunum/goro/pkg/v1/model"
+
+	"github.com/stretchr/testify/require"
+	"gorgonia.org/tensor"
+)
+
+func TestImport(t *testing.T) {
+	dense, err := ImportFiles("testdata/dense.json", "testdata/dense.npz", model.WithoutTracker())
+	require.NoError(t, err)
+	require.Equal(t, "dense", dense.Name())
+	require.Len(t, dense.Chain.Layers, 3)
+	require.Equal(t, layer.Dropout{Probability: 0.2}, dense.Chain.Layers[1])
+	io, err := data.ReadNPZFile("testdata/dense_io.npz")
+	require.NoError(t, err)
+	predictions := predictAll(t, withoutDropout(t), io["input"].Float32s(), []int{1, 4})
+	require.InDeltaSlice(t, io["expected"].Float32s(), predictions, 1e-5)
+
+	conv, err := ImportFiles("testdata/conv.json", "testdata/conv.npz", model.WithoutTracker())
+	require.NoError(t, err)
+	require.Equal(t, tensor.Shape{1, 2, 5, 5}, conv.FwdInput().Shape())
+	io, err = data.ReadNPZFile("testdata/conv_io.npz")
+	require.NoError(t, err)
+	// keras images are channels last, goro images are channels first.
+	nhwc := io["input"]
+	require.NoError(t, nhwc.T(0, 3, 1, 2))
+	require.NoError(t, nhwc.Transpose())
+	predictions = predictAll(t, conv, nhwc.Float32s(), []int{1, 2, 5, 5})
+	require.InDeltaSlice(t, io["expected"].Float32s(), predictions, 1e-5)
+
+	initialized, err := ImportFiles("testdata/conv.json", "", model.WithoutTracker())
+	require.NoError(t, err)
+	require.Len(t, initialized.Learnables(), 4)
+
+	_, err = Import(strings.NewReader(`{"class_name": "Sequential", "config": {"layers": [
+		{"class_name": "LSTM", "config": {"batch_input_shape": [null, 3, 4]}},
+		{"class_name": "BatchNormalization", "config": {}},
+		{"class_name": "Dense", "config": {"units": 2}}]}}`), nil)
+	require.EqualError(t, err, "unsupported keras layers: BatchNormalization, LSTM")
+
+	// an activation after a dropout cannot be folded into the layer before it.
+	_, err = Import(strings.NewReader(`{"class_name": "Sequential", "config": {"layers": [
+		{"class_name": "Dense", "config": {"batch_input_shape": [null, 4], "units": 2}},
+		{"class_name": "Dropout", "config": {"rate": 0.5}},
+		{"class_name": "Activation", "config": {"activation": "softmax"}}]}}`), nil)
+	require.EqualError(t, err, `layer "" Activation: activations cannot follow a layer.Dropout layer`)
+
+	folded, err := Import(strings.NewReader(`{"class_name": "Sequential", "config": {"layers": [
+		{"class_name": "Dense", "config": {"batch_input_shape": [null, 4], "units": 2}},
+		{"class_name": "Dropout", "config": {"rate": 0}},
+		{"class_name": "Activation", "config": {"activation": "softmax"}}]}}`), nil, model.WithoutTracker())
+	require.NoError(t, err)
+	require.Len(t, folded.Chain.Layers, 1)
+	require.Equal(t, layer.Softmax, folded.Chain.Layers[0].(layer.FC).Activation)
+
+	_, err = Import(strings.NewReader(`{"class_name": "Functional", "config": {}}`), nil)
+	require.Error(t, err)
+}
+
+// withoutDropout imports the dense fixture with its dropout disabled, as dropout is applied when
+// predicting in goro.
+func withoutDropout(t *testing.T) *model.Sequential {
+	arch := strings.NewReader(`{"class_name": "Sequential", "config": {"name": "dense", "layers": [
+		{"class_name": "InputLayer", "config": {"batch_input_shape": [null, 4], "dtype": "float32"}},
+		{"class_name": "Dense", "config": {"name": "dense", "units": 3, "activation": "relu"}},
+		{"class_name": "Dense", "config": {"name": "dense_1", "units": 2, "activation": "softmax"}}]}}`)
+	weights, err := data.ReadNPZFile("testdata/dense.npz")
+	require.NoError(t, err)
+	m, err := Import(arch, weights, model.WithoutTracker())
+	require.NoError(t, err)
+	return m
+}
+
+// predictAll predicts each example of the flat inputs.
+func predictAll(t *testing.T, m *model.Sequential, x []float32, shape []int) []float32 {
+	size := tensor.Shape(shape).TotalSize()
+	predictions := []float32{}
+	for i := 0; i < len(x)/size; i++ {
+		example := make([]float32, size)
+		copy(example, x[i*size:(i+1)*size])
+		prediction, err := m.Predict(tensor.New(tensor.WithShape(shape...), tensor.WithBacking(example)))
+		require.NoError(t, err)
+		predictions = append(predictions, prediction.Data().([]float32)...)
+	}
+	return predictions
+}
@@ -0,0 +1,145 @@
+"""Generates the Keras import test fixtures without Keras or NumPy.
+
+The architectures are in the layout written by Keras `model.to_json()`, the weights are named as by
+`np.savez(path, **{w.name: w.numpy() for w in model.weights})`, and the expected outputs are computed
+with a reference implementation of the layers in Keras' channels last layout.
+
+    python3 generate.py
+"""
+import json
+import math
+import struct
+import zipfile
+
+
+def npy(shape, values):
+    dims = ", ".join("%d" % d for d in shape) + ("," if len(shape) == 1 else "")
+    header = "{'descr': '<f4', 'fortran_order': False, 'shape': (%s), }" % dims
+    header += " " * (63 - (10 + len(header)) % 64) + "\n"
+    return b"\x93NUMPY\x01\x00" + struct.pack("<H", len(header)) + header.encode() + struct.pack("<%df" % len(values), *values)
+
+
+def savez(path, arrays):
+    with zipfile.ZipFile(path, "w") as z:
+        for name, (shape, values) in arrays.items():
+            z.writestr(name + ".npy", npy(shape, values))
+
+
+def f32(v):
+    return struct.unpack("<f", struct.pack("<f", v))[0]
+
+
+def weights(shape, seed):
+    n = 1
+    for d in shape:
+        n *= d
+    return [f32(0.5 * math.sin(seed + 1.7 * i)) for i in range(n)]
+
+
+def relu(v):
+    return [max(0.0, x) for x in v]
+
+
+def softmax(v):
+    e = [math.exp(x - max(v)) for x in v]
+    return [x / sum(e) for x in e]
+
+
+def dense(x, kernel, bias, units):
+    return [sum(x[i] * kernel[i * units + u] for i in range(len(x))) + bias[u] for u in range(units)]
+
+
+def generate_dense():
+    model = {
+        "class_name": "Sequential",
+        "config": {
+            "name": "dense",
+            "layers": [
+                {"class_name": "InputLayer", "config": {"batch_input_shape": [None, 4], "dtype": "float32", "name": "input_1"}},
+                {"class_name": "Dense", "config": {"name": "dense", "units": 3, "activation": "relu", "use_bias": True}},
+                {"class_name": "Dropout", "config": {"name": "dropout", "rate": 0.2}},
+                {"class_name": "Dense", "config": {"name": "dense_1", "units": 2, "activation": "softmax", "use_bias": True}},
+            ],
+        },
+        "keras_version": "2.4.0",
+        "backend": "tensorflow",
+    }
+    w = {
+        "dense/kernel:0": ([4, 3], weights([4, 3], 1)),
+        "dense/bias:0": ([3], weights([3], 2)),
+        "dense_1/kernel:0": ([3, 2], weights([3, 2], 3)),
+        "dense_1/bias:0": ([2], weights([2], 4)),
+    }
+    x = [f32(math.cos(i)) for i in range(8)]
+    expected = []
+    for b in range(2):
+        h = relu(dense(x[b * 4:(b + 1) * 4], w["dense/kernel:0"][1], w["dense/bias:0"][1], 3))
+        expected += softmax(dense(h, w["dense_1/kernel:0"][1], w["dense_1/bias:0"][1], 2))
+    with open("dense.json", "w") as f:
+        json.dump(model, f, indent=2)
+    savez("dense.npz", w)
+    savez("dense_io.npz", {"input": ([2, 4], x), "expected": ([2, 2], expected)})
+
+
+def generate_conv():
+    # older versions of keras write the layers as the config.
+    model = {
+        "class_name": "Sequential",
+        "config": [
+            {"class_name": "Conv2D", "config": {
+                "name": "conv2d", "batch_input_shape": [None, 5, 5, 2], "dtype": "float32", "filters": 3,
+                "kernel_size": [3, 3], "strides": [1, 1], "padding": "same", "data_format": "channels_last",
+                "dilation_rate": [1, 1], "activation": "relu", "use_bias": True}},
+            {"class_name": "MaxPooling2D", "config": {
+                "name": "max_pooling2d", "pool_size": [2, 2], "padding": "valid", "strides": [2, 2],
+                "data_format": "channels_last"}},
+            {"class_name": "Flatten", "config": {"name": "flatten", "data_format": "channels_last"}},
+            {"class_name": "Dense", "config": {"name": "dense", "units": 2, "activation": "linear", "use_bias": True}},
+            {"class_name": "Activation", "config": {"name": "activation", "activation": "softmax"}},
+        ],
+        "keras_version": "2.2.4",
+        "backend": "tensorflow",
+    }
+    h, wd, c, filters = 5, 5, 2, 3
+    kernel = weights([3, 3, c, filters], 5)
+    bias = weights([filters], 6)
+    dkernel = weights([12, 2], 7)
+    dbias = weights([2], 8)
+    w = {
+        "conv2d/kernel:0": ([3, 3, c, filters], kernel),
+        "conv2d/bias:0": ([filters], bias),
+        "dense/kernel:0": ([12, 2], dkernel),
+        "dense/bias:0": ([2], dbias),
+    }
+    x = [f32(math.sin(0.3 * i)) for i in range(2 * h * wd * c)]
+    expected = []
+    for b in range(2):
+        img = x[b * h * wd * c:(b + 1) * h * wd * c]
+        conv = []
+        for y in range(h):
+            for xx in range(wd):
+                for o in range(filters):
+                    acc = bias[o]
+                    for i in range(3):
+                        for j in range(3):
+                            iy, ix = y + i - 1, xx + j - 1
+                            if iy < 0 or ix < 0 or iy >= h or ix >= wd:
+                                continue
+                            for ci in range(c):
+                                acc += img[(iy * wd + ix) * c + ci] * kernel[((i * 3 + j) * c + ci) * filters + o]
+                    conv.append(max(0.0, acc))
+        pooled = []
+        for y in range(2):
+            for xx in range(2):
+                for o in range(filters):
+                    pooled.append(max(conv[((y * 2 + i) * wd + xx * 2 + j) * filters + o] for i in range(2) for j in range(2)))
+        expected += softmax(dense(pooled, dkernel, dbias, 2))
+    with open("conv.json", "w") as f:
+        json.dump(model, f, indent=2)
+    savez("conv.npz", w)
+    savez("conv_io.npz", {"input": ([2, h, wd, c], x), "expected": ([2, 2], expected)})
+
+
+if __name__ == "__main__":
+    generate_dense()
+    generate_conv()
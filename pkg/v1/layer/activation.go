@@ -119,6 +119,11 @@ func NewLeakyReLU(alpha float64) *LeakyReLUActivation {
 	return &LeakyReLUActivation{alpha: alpha}
 }
 
+// Alpha is the slope of the activation for negative inputs.
+func (r *LeakyReLUActivation) Alpha() float64 {
+	return r.alpha
+}
+
 // Fwd is a forward pass through the layer.
 func (r *LeakyReLUActivation) Fwd(x *g.Node) (*g.Node, error) {
 	return g.LeakyRelu(x, r.alpha)
@@ -153,6 +158,11 @@ func NewSoftmax(axis ...int) *SoftmaxActivation {
 	return &SoftmaxActivation{axis: axis}
 }
 
+// Axis is the axis the softmax is computed over, the last axis if empty.
+func (s *SoftmaxActivation) Axis() []int {
+	return s.axis
+}
+
 // Fwd is a forward pass through the layer.
 func (s *SoftmaxActivation) Fwd(x *g.Node) (*g.Node, error) {
 	// fmt.Printf("running softmax with x shape: %v dims: %v \n", x.Shape(), x.Dims())
@@ -0,0 +1,352 @@
+package layer
+
+import (
+	"fmt"
+
+	"github.com/aunum/log"
+
+	g "gorgonia.org/gorgonia"
+	t "gorgonia.org/tensor"
+)
+
+// Bidirectional merge modes.
+const (
+	// MergeConcat concatenates the forward and backward outputs along their last axis.
+	MergeConcat = "concat"
+
+	// MergeSum sums the forward and backward outputs.
+	MergeSum = "sum"
+
+	// MergeAverage averages the forward and backward outputs.
+	MergeAverage = "average"
+)
+
+// Bidirectional runs a sequence layer forwards and backwards over a sequence of shape
+// (batch, time, x...) and merges the outputs. The backward layer has its own learnables.
+//
+// Over a recurrent layer, such as a simple rnn, the backward layer carries the context of the later
+// timesteps. A stateless layer, such as a time distributed layer, computes each timestep independently, so
+// running it backwards only reverses the order of its outputs, which are reversed back if they are a
+// sequence. Over a time distributed layer a bidirectional layer is then the same as two time distributed
+// layers merged.
+type Bidirectional struct {
+	// Layer to run forwards over the sequence.
+	// required
+	Layer Config `json:"-"`
+
+	// Backward is the layer to run backwards over the sequence. Its learnables must be named differently
+	// to those of the layer, as learnables of the same name and shape are the same node in a graph.
+	// Defaults to a clone of the layer with its name prefixed by backward, if it is a NamePrefixer.
+	Backward Config `json:"-"`
+
+	// Merge is how the forward and backward outputs are merged, one of concat, sum or average.
+	// Defaults to concat
+	Merge string `json:"merge,omitempty"`
+
+	// Sequences indicates the layers output a sequence of shape (batch, time, x...), in which case the
+	// backward outputs are reversed to align with the forward outputs.
+	Sequences bool `json:"sequences,omitempty"`
+}
+
+// Validate the config.
+func (b Bidirectional) Validate() error {
+	if b.Layer == nil {
+		return &ConfigError{Name: "bidirectional", Reason: "layer must be set"}
+	}
+	switch b.Merge {
+	case "", MergeConcat, MergeSum, MergeAverage:
+	default:
+		return &ConfigError{Name: "bidirectional", Reason: fmt.Sprintf("unknown merge %q, must be one of %s, %s or %s", b.Merge, MergeConcat, MergeSum, MergeAverage)}
+	}
+	if err := b.Layer.Validate(); err != nil {
+		return fmt.Errorf("bidirectional: %w", err)
+	}
+	if b.Backward != nil {
+		if err := b.Backward.Validate(); err != nil {
+			return fmt.Errorf("bidirectional backward: %w", err)
+		}
+	}
+	return nil
+}
+
+// ApplyDefaults to the config.
+func (b Bidirectional) ApplyDefaults() Config {
+	if b.Merge == "" {
+		b.Merge = MergeConcat
+	}
+	if b.Layer == nil {
+		return b
+	}
+	b.Layer = b.Layer.ApplyDefaults()
+	if b.Backward == nil {
+		b.Backward = b.Layer.Clone()
+		if prefixer, ok := b.Backward.(NamePrefixer); ok {
+			b.Backward = prefixer.WithNamePrefix("backward")
+		}
+	}
+	b.Backward = b.Backward.ApplyDefaults()
+	return b
+}
+
+// InferShape infers the input of the layers from the input shape and returns the output shape.
+func (b Bidirectional) InferShape(input t.Shape) (Config, t.Shape, error) {
+	if input != nil && len(input) < 3 {
+		return nil, nil, &ShapeError{Name: "bidirectional", Expected: "(batch, time, x...)", Actual: input}
+	}
+	forward, ok := b.Layer.(ShapeInferer)
+	if !ok {
+		return b, nil, nil
+	}
+	backward, ok := b.Backward.(ShapeInferer)
+	if !ok {
+		return b, nil, nil
+	}
+	layer, output, err := forward.InferShape(input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bidirectional: %w", err)
+	}
+	b.Layer = layer
+	layer, _, err = backward.InferShape(input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bidirectional backward: %w", err)
+	}
+	b.Backward = layer
+	if output == nil || b.Merge != MergeConcat {
+		return b, output, nil
+	}
+	output = output.Clone()
+	output[len(output)-1] *= 2
+	return b, output, nil
+}
+
+// Compile the layer into the graph.
+func (b Bidirectional) Compile(graph *g.ExprGraph, opts ...CompileOpt) (Layer, error) {
+	bi := newBidirectional(&b)
+	for _, opt := range opts {
+		if err := opt(bi); err != nil {
+			return nil, err
+		}
+	}
+	forwardOpts := []CompileOpt{AsType(bi.dtype)}
+	backwardOpts := []CompileOpt{AsType(bi.dtype)}
+	if bi.isBatched {
+		forwardOpts = append(forwardOpts, AsBatch())
+		backwardOpts = append(backwardOpts, AsBatch())
+	}
+	if bi.shared != nil {
+		forwardOpts = append(forwardOpts, WithSharedLearnables(bi.shared.forward))
+		backwardOpts = append(backwardOpts, WithSharedLearnables(bi.shared.backward))
+	}
+	var err error
+	bi.forward, err = b.Layer.Compile(graph, forwardOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("bidirectional: %w", err)
+	}
+	bi.backward, err = b.Backward.Compile(graph, backwardOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("bidirectional backward: %w", err)
+	}
+	return bi, nil
+}
+
+// WithNamePrefix returns the config with the prefix added to the names of its layers.
+func (b Bidirectional) WithNamePrefix(prefix string) Config {
+	if prefixer, ok := b.Layer.(NamePrefixer); ok {
+		b.Layer = prefixer.WithNamePrefix(prefix)
+	}
+	if prefixer, ok := b.Backward.(NamePrefixer); ok {
+		b.Backward = prefixer.WithNamePrefix(prefix)
+	}
+	return b
+}
+
+// Clone the config.
+func (b Bidirectional) Clone() Config {
+	c := Bidirectional{Merge: b.Merge, Sequences: b.Sequences}
+	if b.Layer != nil {
+		c.Layer = b.Layer.Clone()
+	}
+	if b.Backward != nil {
+		c.Backward = b.Backward.Clone()
+	}
+	return c
+}
+
+type bidirectional struct {
+	*Bidirectional
+	forward   Layer
+	backward  Layer
+	dtype     t.Dtype
+	isBatched bool
+	shared    *bidirectional
+	mask      *g.Node
+}
+
+func newBidirectional(config *Bidirectional) *bidirectional {
+	return &bidirectional{
+		Bidirectional: config,
+		dtype:         t.Float32,
+	}
+}
+
+// SetSharedLearnables sets the layer to share the learnables of another bidirectional layer.
+func (b *bidirectional) SetSharedLearnables(shared Layer) error {
+	s, ok := shared.(*bidirectional)
+	if !ok {
+		return &ConfigError{Name: "bidirectional", Reason: fmt.Sprintf("cannot share learnables with %T", shared)}
+	}
+	b.shared = s
+	return nil
+}
+
+// SetBatched sets whether the layer is compiled as a batch.
+func (b *bidirectional) SetBatched(batched bool) {
+	b.isBatched = batched
+}
+
+// SetDType sets the data type of the layer.
+func (b *bidirectional) SetDType(dtype t.Dtype) {
+	b.dtype = dtype
+}
+
+// SetMask sets the mask of the input, which is passed to the layers and reversed for the backward layer.
+// The masked timesteps of sequence outputs are zeroed.
+func (b *bidirectional) SetMask(mask *g.Node) {
+	b.mask = mask
+}
+
+// Mask of the output, which is the mask of the input if the output is a sequence.
+func (b *bidirectional) Mask() *g.Node {
+	if !b.Sequences {
+		return nil
+	}
+	return b.mask
+}
+
+// Fwd is a forward pass through the layer.
+func (b *bidirectional) Fwd(x *g.Node) (*g.Node, error) {
+	if setter, ok := b.forward.(MaskSetter); ok {
+		setter.SetMask(b.mask)
+	}
+	forward, err := b.forward.Fwd(x)
+	if err != nil {
+		return nil, fmt.Errorf("bidirectional: %w", err)
+	}
+	reversed, err := reverseSequence(x)
+	if err != nil {
+		return nil, err
+	}
+	if setter, ok := b.backward.(MaskSetter); ok {
+		var mask *g.Node
+		if b.mask != nil {
+			mask, err = reverseMask(b.mask)
+			if err != nil {
+				return nil, err
+			}
+		}
+		setter.SetMask(mask)
+	}
+	backward, err := b.backward.Fwd(reversed)
+	if err != nil {
+		return nil, fmt.Errorf("bidirectional backward: %w", err)
+	}
+	if b.Sequences {
+		backward, err = reverseSequence(backward)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var n *g.Node
+	switch b.Merge {
+	case MergeSum:
+		n, err = g.Add(forward, backward)
+	case MergeAverage:
+		n, err = g.Add(forward, backward)
+		if err != nil {
+			return nil, err
+		}
+		n, err = g.Mul(n, scalar(n, 0.5))
+	default:
+		n, err = g.Concat(len(forward.Shape())-1, forward, backward)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if b.Sequences && b.mask != nil {
+		n, err = ApplyMask(n, b.mask)
+		if err != nil {
+			return nil, err
+		}
+	}
+	log.Debugf("bidirectional output shape: %v", n.Shape())
+	return n, nil
+}
+
+// Learnables returns all learnable nodes within this layer.
+func (b *bidirectional) Learnables() g.Nodes {
+	return append(b.forward.Learnables(), b.backward.Learnables()...)
+}
+
+// Clone the layer without any nodes. (nodes cannot be shared)
+func (b *bidirectional) Clone() Layer {
+	configCloned := b.Bidirectional.Clone().(Bidirectional)
+	return &bidirectional{
+		Bidirectional: &configCloned,
+		forward:       b.forward.Clone(),
+		backward:      b.backward.Clone(),
+		dtype:         b.dtype,
+		isBatched:     b.isBatched,
+		shared:        b.shared,
+	}
+}
+
+// Graph returns the graph for this layer.
+func (b *bidirectional) Graph() *g.ExprGraph {
+	return b.forward.Graph()
+}
+
+// reverseSequence reverses a sequence of shape (batch, time, x...) along its time axis.
+func reverseSequence(x *g.Node) (*g.Node, error) {
+	s := x.Shape().Clone()
+	if len(s) < 3 {
+		return nil, &ShapeError{Name: "sequence", Expected: "(batch, time, x...)", Actual: s}
+	}
+	batch, steps, size := s[0], s[1], product(s[2:])
+	if steps == 1 {
+		return x, nil
+	}
+	// each timestep is sliced as a row of the time major sequence.
+	n, err := g.Reshape(x, t.Shape{batch, steps, size})
+	if err != nil {
+		return nil, err
+	}
+	n, err = g.Transpose(n, 1, 0, 2)
+	if err != nil {
+		return nil, err
+	}
+	n, err = g.Reshape(n, t.Shape{steps, batch * size})
+	if err != nil {
+		return nil, err
+	}
+	rows := make(g.Nodes, steps)
+	for i := range rows {
+		rows[i], err = g.Slice(n, g.S(steps-1-i))
+		if err != nil {
+			return nil, err
+		}
+	}
+	n, err = g.Concat(0, rows...)
+	if err != nil {
+		return nil, err
+	}
+	n, err = g.Reshape(n, t.Shape{steps, batch, size})
+	if err != nil {
+		return nil, err
+	}
+	n, err = g.Transpose(n, 1, 0, 2)
+	if err != nil {
+		return nil, err
+	}
+	return g.Reshape(n, s)
+}
@@ -0,0 +1,43 @@
+package layer_test
+
+import (
+	"testing"
+
+	. "github.com/aunum/goro/pkg/v1/layer"
+
+	"github.com/stretchr/testify/require"
+	g "gorgonia.org/gorgonia"
+	"gorgonia.org/tensor"
+)
+
+func TestBidirectional(tt *testing.T) {
+	identity := Lambda{Fn: func(x *g.Node) (*g.Node, error) { return x, nil }}
+	for _, test := range []struct {
+		config   Bidirectional
+		expected []float32
+	}{
+		{
+			config:   Bidirectional{Layer: identity},
+			expected: []float32{0, 1, 4, 5, 2, 3, 2, 3, 4, 5, 0, 1, 6, 7, 10, 11, 8, 9, 8, 9, 10, 11, 6, 7},
+		},
+		{
+			config:   Bidirectional{Layer: identity, Sequences: true, Merge: MergeSum},
+			expected: []float32{0, 2, 4, 6, 8, 10, 12, 14, 16, 18, 20, 22},
+		},
+	} {
+		chain, err := NewChain(test.config)
+		require.NoError(tt, err)
+		shape, err := chain.InferShapes(tensor.Shape{2, 3, 2})
+		require.NoError(tt, err)
+
+		graph := g.NewGraph()
+		require.NoError(tt, chain.Compile(graph, WithLayerOpts(AsBatch())))
+		x := g.NewTensor(graph, g.Float32, 3, g.WithShape(2, 3, 2), g.WithValue(tensor.New(tensor.WithShape(2, 3, 2), tensor.WithBacking(tensor.Range(tensor.Float32, 0, 12)))))
+		y, err := chain.Fwd(x)
+		require.NoError(tt, err)
+		require.Equal(tt, shape, y.Shape())
+		vm := g.NewTapeMachine(graph)
+		require.NoError(tt, vm.RunAll())
+		require.Equal(tt, test.expected, y.Value().Data())
+	}
+}
@@ -1,7 +1,7 @@
 package layer
 
 import (
-	"github.com/aunum/log"
+	"fmt"
 
 	g "gorgonia.org/gorgonia"
 )
@@ -14,26 +14,94 @@ type Chain struct {
 	sharedLearnables *Chain
 	compileOpts      []CompileOpt
 	layers           []Layer
+
+	// nodes added to the graph by each layer when compiled and in the last forward pass.
+	compileNodes []g.Nodes
+	fwdNodes     []g.Nodes
+	outputs      g.Nodes
+
+	// mask of the input and of the output of the last forward pass.
+	inputMask *g.Node
+	mask      *g.Node
 }
 
 // NewChain returns a new chain of layers.
-func NewChain(layers ...Config) *Chain {
+func NewChain(layers ...Config) (*Chain, error) {
 	c := &Chain{}
-	c.Add(layers...)
-	return c
+	err := c.Add(layers...)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
 // Fwd is a forward pass thorugh all layers of the chain.
 func (c *Chain) Fwd(x *g.Node) (prediction *g.Node, err error) {
 	prediction = x
+	c.fwdNodes = nil
+	c.outputs = nil
+	mask := c.inputMask
 	for _, layer := range c.layers {
+		before := len(x.Graph().AllNodes())
+		if setter, ok := layer.(MaskSetter); ok {
+			setter.SetMask(mask)
+		}
 		if prediction, err = layer.Fwd(prediction); err != nil {
 			return nil, err
 		}
+		// only layers which declare the mask of their output propagate it.
+		mask = nil
+		if masker, ok := layer.(Masker); ok {
+			mask = masker.Mask()
+		}
+		c.fwdNodes = append(c.fwdNodes, addedNodes(x.Graph(), before))
+		c.outputs = append(c.outputs, prediction)
 	}
+	c.mask = mask
 	return prediction, nil
 }
 
+// SetMask sets the mask of the input sequence for subsequent forward passes, nil if the input is not
+// masked. The mask is of shape (batch, time) with ones for the timesteps to use and zeros for those
+// to ignore.
+func (c *Chain) SetMask(mask *g.Node) {
+	c.inputMask = mask
+}
+
+// Mask of the output of the last forward pass, nil if the output is not masked.
+func (c *Chain) Mask() *g.Node {
+	return c.mask
+}
+
+// Outputs are the outputs of each layer from the last forward pass.
+func (c *Chain) Outputs() g.Nodes {
+	return c.outputs
+}
+
+// LayerNodes are the nodes added to the graph by each layer when it was compiled and in the last
+// forward pass e.g. to group the nodes of a graph by layer.
+func (c *Chain) LayerNodes() []g.Nodes {
+	ret := make([]g.Nodes, len(c.compileNodes))
+	for i, nodes := range c.compileNodes {
+		ret[i] = append(ret[i], nodes...)
+		if i < len(c.fwdNodes) {
+			ret[i] = append(ret[i], c.fwdNodes[i]...)
+		}
+	}
+	return ret
+}
+
+// addedNodes returns the nodes added to the graph since it had the given number of nodes.
+func addedNodes(graph *g.ExprGraph, before int) g.Nodes {
+	all := graph.AllNodes()
+	if before >= len(all) {
+		return nil
+	}
+	added := make(g.Nodes, len(all)-before)
+	copy(added, all[before:])
+	return added
+}
+
 // Learnables are all of the learnable parameters in the chain.
 func (c *Chain) Learnables() g.Nodes {
 	retVal := []*g.Node{}
@@ -43,23 +111,25 @@ func (c *Chain) Learnables() g.Nodes {
 	return retVal
 }
 
-// Add to the chain.
-func (c *Chain) Add(l ...Config) {
-	for _, layer := range l {
+// Add to the chain. Layers are validated before any are added.
+func (c *Chain) Add(l ...Config) error {
+	for i, layer := range l {
 		err := layer.Validate()
 		if err != nil {
-			log.Fatalf("layer %#v \nfailed validation: %v", layer, err)
+			return fmt.Errorf("layer %d %T: %w", len(c.Layers)+i, layer, err)
 		}
-		layer = layer.ApplyDefaults()
-		c.Layers = append(c.Layers, layer)
 	}
+	for _, layer := range l {
+		c.Layers = append(c.Layers, layer.ApplyDefaults())
+	}
+	return nil
 }
 
 // Clone the chain without any nodes.
 func (c *Chain) Clone() *Chain {
 	ch := &Chain{}
 	for _, layer := range c.Layers {
-		ch.Add(layer.Clone())
+		ch.Layers = append(ch.Layers, layer.Clone())
 	}
 	return ch
 }
@@ -82,20 +152,25 @@ func WithLayerOpts(opts ...CompileOpt) func(*Chain) {
 }
 
 // Compile the chain of layers into the model.
-func (c *Chain) Compile(graph *g.ExprGraph, opts ...ChainOpt) {
+func (c *Chain) Compile(graph *g.ExprGraph, opts ...ChainOpt) error {
 	for _, opt := range opts {
 		opt(c)
 	}
-	if c.sharedLearnables != nil {
-		for i, layer := range c.Layers {
-			compileOpts := append(c.compileOpts, WithSharedLearnables(c.sharedLearnables.layers[i]))
-			l := layer.Compile(graph, compileOpts...)
-			c.layers = append(c.layers, l)
-		}
-		return
+	if c.sharedLearnables != nil && len(c.sharedLearnables.layers) != len(c.Layers) {
+		return &ConfigError{Name: "chain", Reason: fmt.Sprintf("cannot share learnables with a chain of %d compiled layers, have %d layers", len(c.sharedLearnables.layers), len(c.Layers))}
 	}
-	for _, layer := range c.Layers {
-		l := layer.Compile(graph, c.compileOpts...)
+	for i, layer := range c.Layers {
+		compileOpts := c.compileOpts
+		if c.sharedLearnables != nil {
+			compileOpts = append(compileOpts[:len(compileOpts):len(compileOpts)], WithSharedLearnables(c.sharedLearnables.layers[i]))
+		}
+		before := len(graph.AllNodes())
+		l, err := layer.Compile(graph, compileOpts...)
+		if err != nil {
+			return fmt.Errorf("layer %d %T: %w", i, layer, err)
+		}
 		c.layers = append(c.layers, l)
+		c.compileNodes = append(c.compileNodes, addedNodes(graph, before))
 	}
+	return nil
 }
@@ -0,0 +1,45 @@
+package layer_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/aunum/goro/pkg/v1/layer"
+
+	"github.com/stretchr/testify/require"
+	g "gorgonia.org/gorgonia"
+)
+
+func TestChainErrors(tt *testing.T) {
+	_, err := NewChain(FC{Input: 4, Output: 2}, FC{Input: 2})
+	var configErr *ConfigError
+	require.True(tt, errors.As(err, &configErr))
+	require.Contains(tt, err.Error(), "layer 1")
+
+	chain, err := NewChain(
+		Conv2D{Input: 1, Output: 2, Width: 3, Height: 3},
+		MaxPooling2D{},
+		Flatten{},
+		Reshape{To: []int{2, 4}},
+		Flatten{},
+		Dropout{},
+		FC{Input: 8, Output: 2},
+	)
+	require.NoError(tt, err)
+	err = chain.Compile(g.NewGraph())
+	require.NoError(tt, err)
+	for _, config := range chain.Layers {
+		l, err := config.Compile(g.NewGraph())
+		require.NoError(tt, err)
+		require.NotNil(tt, l.Clone())
+	}
+
+	shared := chain.Clone()
+	err = shared.Compile(g.NewGraph(), WithSharedChainLearnables(chain))
+	require.NoError(tt, err)
+
+	other, err := NewChain(FC{Input: 8, Output: 2})
+	require.NoError(tt, err)
+	err = other.Compile(g.NewGraph(), WithSharedChainLearnables(chain))
+	require.True(tt, errors.As(err, &configErr))
+}
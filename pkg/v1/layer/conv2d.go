@@ -12,72 +12,85 @@ import (
 // Conv2D is a 2D convolution.
 type Conv2D struct {
 	// Input channels.
-	// required
-	Input int
+	// Inferred from the input shape if not set.
+	Input int `json:"input,omitempty"`
 
 	// Output channels.
 	// required
-	Output int
+	Output int `json:"output"`
 
 	// Height of the filter.
 	// required
-	Height int
+	Height int `json:"height"`
 
 	// Width of the filter.
 	// required
-	Width int
+	Width int `json:"width"`
 
 	// Name of the layer.
-	Name string
+	Name string `json:"name,omitempty"`
 
 	// Activation function for the layer.
 	// Defaults to ReLU
-	Activation ActivationFn
+	Activation ActivationFn `json:"-"`
 
 	// Pad
 	// Defaults to (1, 1)
-	Pad []int
+	Pad []int `json:"pad,omitempty"`
 
 	// Stride
 	// Defaults to (1, 1)
-	Stride []int
+	Stride []int `json:"stride,omitempty"`
 
 	// Dilation
 	// Defaults to (1, 1)
-	Dilation []int
+	Dilation []int `json:"dilation,omitempty"`
 
 	// Init function fot the weights.
 	// Defaults to GlorotN(1)
-	Init g.InitWFn
+	Init g.InitWFn `json:"-"`
+
+	// Bias adds a learnable bias for each output channel.
+	// Defaults to false.
+	Bias bool `json:"bias,omitempty"`
+
+	// BiasInit is the init function for the bias.
+	// Defaults to Zeroes
+	BiasInit g.InitWFn `json:"-"`
 }
 
 // Compile the config into a layer.
-func (c Conv2D) Compile(graph *g.ExprGraph, opts ...CompileOpt) Layer {
+func (c Conv2D) Compile(graph *g.ExprGraph, opts ...CompileOpt) (Layer, error) {
 	cnv := newConv2D(&c)
 	for _, opt := range opts {
-		opt(cnv)
+		if err := opt(cnv); err != nil {
+			return nil, err
+		}
 	}
 	if cnv.shared != nil {
 		cnv.filter = g.NewTensor(graph, cnv.dtype, 4, g.WithShape(cnv.filterShape...), g.WithInit(c.Init), g.WithName(c.Name), g.WithValue(cnv.shared.filter.Value()))
-		return cnv
+		if c.Bias {
+			cnv.bias = g.NewTensor(graph, cnv.dtype, 4, g.WithShape(1, c.Output, 1, 1), g.WithName(fmt.Sprintf("%s-bias", c.Name)), g.WithValue(cnv.shared.bias.Value()))
+		}
+		return cnv, nil
 	}
 	cnv.filter = g.NewTensor(graph, cnv.dtype, 4, g.WithShape(cnv.filterShape...), g.WithInit(c.Init), g.WithName(c.Name))
-	return cnv
+	if c.Bias {
+		cnv.bias = g.NewTensor(graph, cnv.dtype, 4, g.WithShape(1, c.Output, 1, 1), g.WithInit(c.BiasInit), g.WithName(fmt.Sprintf("%s-bias", c.Name)))
+	}
+	return cnv, nil
 }
 
 // Validate the config.
 func (c Conv2D) Validate() error {
-	if c.Input == 0 {
-		return fmt.Errorf("input must be set")
-	}
 	if c.Output == 0 {
-		return fmt.Errorf("output must be set")
+		return &ConfigError{Name: fmt.Sprintf("conv2d %q", c.Name), Reason: "output must be set"}
 	}
 	if c.Width == 0 {
-		return fmt.Errorf("width must be set")
+		return &ConfigError{Name: fmt.Sprintf("conv2d %q", c.Name), Reason: "width must be set"}
 	}
 	if c.Height == 0 {
-		return fmt.Errorf("height must be set")
+		return &ConfigError{Name: fmt.Sprintf("conv2d %q", c.Name), Reason: "height must be set"}
 	}
 	return nil
 }
@@ -99,6 +112,34 @@ func (c Conv2D) ApplyDefaults() Config {
 	if c.Init == nil {
 		c.Init = g.GlorotU(1)
 	}
+	if c.BiasInit == nil {
+		c.BiasInit = g.Zeroes()
+	}
+	return c
+}
+
+// InferShape infers the input channels from the input shape and returns the output shape.
+func (c Conv2D) InferShape(input t.Shape) (Config, t.Shape, error) {
+	c = c.ApplyDefaults().(Conv2D)
+	if input == nil {
+		if c.Input == 0 {
+			return nil, nil, &ConfigError{Name: fmt.Sprintf("conv2d %q", c.Name), Reason: "input must be set as it cannot be inferred"}
+		}
+		return c, nil, nil
+	}
+	if c.Input == 0 && len(input) == 4 {
+		c.Input = input[1]
+	}
+	output, err := inferConvShape(fmt.Sprintf("conv2d %q", c.Name), input, c.Input, c.Output, c.Height, c.Width, c.Pad, c.Stride, c.Dilation)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, output, nil
+}
+
+// WithNamePrefix returns the config with the prefix added to its name.
+func (c Conv2D) WithNamePrefix(prefix string) Config {
+	c.Name = prefixName(prefix, c.Name)
 	return c
 }
 
@@ -115,6 +156,8 @@ func (c Conv2D) Clone() Config {
 		Stride:     c.Stride,
 		Dilation:   c.Dilation,
 		Init:       c.Init,
+		Bias:       c.Bias,
+		BiasInit:   c.BiasInit,
 	}
 }
 
@@ -126,6 +169,7 @@ type conv2D struct {
 	filterShape t.Shape
 	kernelShape t.Shape
 	filter      *g.Node
+	bias        *g.Node
 	shared      *conv2D
 	isBatched   bool
 }
@@ -140,6 +184,26 @@ func newConv2D(config *Conv2D) *conv2D {
 	}
 }
 
+// SetSharedLearnables sets the layer to share the learnables of another conv2d layer.
+func (c *conv2D) SetSharedLearnables(shared Layer) error {
+	s, ok := shared.(*conv2D)
+	if !ok {
+		return &ConfigError{Name: fmt.Sprintf("conv2d %q", c.Name), Reason: fmt.Sprintf("cannot share learnables with %T", shared)}
+	}
+	c.shared = s
+	return nil
+}
+
+// SetBatched sets whether the layer is compiled as a batch.
+func (c *conv2D) SetBatched(batched bool) {
+	c.isBatched = batched
+}
+
+// SetDType sets the data type of the layer.
+func (c *conv2D) SetDType(dtype t.Dtype) {
+	c.dtype = dtype
+}
+
 // Fwd is a forward pass through the layer.
 func (c *conv2D) Fwd(x *g.Node) (*g.Node, error) {
 	log.Debug("conv fwd")
@@ -153,6 +217,12 @@ func (c *conv2D) Fwd(x *g.Node) (*g.Node, error) {
 	if err != nil {
 		return nil, err
 	}
+	if c.bias != nil {
+		n, err = addChannelBias(n, c.bias, c.Name, c.dtype)
+		if err != nil {
+			return nil, err
+		}
+	}
 	n, err = c.Activation.Fwd(n)
 	if err != nil {
 		return nil, err
@@ -163,16 +233,21 @@ func (c *conv2D) Fwd(x *g.Node) (*g.Node, error) {
 
 // Learnables returns all learnable nodes within this layer.
 func (c *conv2D) Learnables() g.Nodes {
+	if c.bias != nil {
+		return g.Nodes{c.filter, c.bias}
+	}
 	return g.Nodes{c.filter}
 }
 
-// Clone the layer.
+// Clone the layer without any nodes. (nodes cannot be shared)
 func (c *conv2D) Clone() Layer {
+	configCloned := c.Conv2D.Clone().(Conv2D)
 	return &conv2D{
-		Conv2D:    c.Conv2D.Clone().(*Conv2D),
-		dtype:     c.dtype,
-		filter:    c.filter,
-		isBatched: c.isBatched,
+		Conv2D:      &configCloned,
+		dtype:       c.dtype,
+		filterShape: c.filterShape,
+		shared:      c.shared,
+		isBatched:   c.isBatched,
 	}
 }
 
@@ -183,3 +258,28 @@ func (c *conv2D) Graph() *g.ExprGraph {
 	}
 	return c.filter.Graph()
 }
+
+// addChannelBias adds the bias of each channel to the output of a convolution. Broadcasting the bias over
+// the batch, height and width at once fails in the backward pass, so it is expanded over the height and
+// width with a matmul and only broadcast over the batch.
+func addChannelBias(n, bias *g.Node, name string, dtype t.Dtype) (*g.Node, error) {
+	shape := n.Shape()
+	channels, size := shape[1], shape[2]*shape[3]
+	bias, err := g.Reshape(bias, t.Shape{channels, 1})
+	if err != nil {
+		return nil, err
+	}
+	ones := g.NewMatrix(n.Graph(), dtype, g.WithShape(1, size), g.WithInit(g.Ones()), g.WithName(fmt.Sprintf("%s-bias-ones", name)))
+	expanded, err := g.Mul(bias, ones)
+	if err != nil {
+		return nil, err
+	}
+	expanded, err = g.Reshape(expanded, t.Shape{1, channels, shape[2], shape[3]})
+	if err != nil {
+		return nil, err
+	}
+	if shape[0] == 1 {
+		return g.Add(n, expanded)
+	}
+	return g.BroadcastAdd(n, expanded, nil, []byte{0})
+}
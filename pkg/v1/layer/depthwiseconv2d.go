@@ -0,0 +1,320 @@
+package layer
+
+import (
+	"fmt"
+
+	"github.com/aunum/log"
+
+	g "gorgonia.org/gorgonia"
+	t "gorgonia.org/tensor"
+)
+
+// DepthwiseConv2D is a depthwise 2D convolution, which convolves each input channel with its own
+// filters. The output has multiplier channels for each input channel, the output channels of an
+// input channel being adjacent.
+type DepthwiseConv2D struct {
+	// Input channels.
+	// Inferred from the input shape if not set.
+	Input int `json:"input,omitempty"`
+
+	// Multiplier is the number of output channels for each input channel.
+	// Defaults to 1
+	Multiplier int `json:"multiplier,omitempty"`
+
+	// Height of the filter.
+	// required
+	Height int `json:"height"`
+
+	// Width of the filter.
+	// required
+	Width int `json:"width"`
+
+	// Name of the layer.
+	Name string `json:"name,omitempty"`
+
+	// Activation function for the layer.
+	// Defaults to ReLU
+	Activation ActivationFn `json:"-"`
+
+	// Pad
+	// Defaults to (1, 1)
+	Pad []int `json:"pad,omitempty"`
+
+	// Stride
+	// Defaults to (1, 1)
+	Stride []int `json:"stride,omitempty"`
+
+	// Dilation
+	// Defaults to (1, 1)
+	Dilation []int `json:"dilation,omitempty"`
+
+	// Init function for the depthwise kernel.
+	// Defaults to GlorotU(1)
+	Init g.InitWFn `json:"-"`
+
+	// Bias adds a learnable bias for each output channel.
+	// Defaults to false.
+	Bias bool `json:"bias,omitempty"`
+
+	// BiasInit is the init function for the bias.
+	// Defaults to Zeroes
+	BiasInit g.InitWFn `json:"-"`
+}
+
+// Compile the config into a layer.
+func (c DepthwiseConv2D) Compile(graph *g.ExprGraph, opts ...CompileOpt) (Layer, error) {
+	cnv := newDepthwiseConv2D(&c)
+	for _, opt := range opts {
+		if err := opt(cnv); err != nil {
+			return nil, err
+		}
+	}
+	output := c.Input * c.Multiplier
+	if cnv.shared != nil {
+		cnv.kernel = g.NewTensor(graph, cnv.dtype, 4, g.WithShape(cnv.depthwiseShape...), g.WithName(c.Name), g.WithValue(cnv.shared.kernel.Value()))
+		if c.Bias {
+			cnv.bias = g.NewTensor(graph, cnv.dtype, 4, g.WithShape(1, output, 1, 1), g.WithName(fmt.Sprintf("%s-bias", c.Name)), g.WithValue(cnv.shared.bias.Value()))
+		}
+		return cnv, nil
+	}
+	cnv.kernel = g.NewTensor(graph, cnv.dtype, 4, g.WithShape(cnv.depthwiseShape...), g.WithInit(c.Init), g.WithName(c.Name))
+	if c.Bias {
+		cnv.bias = g.NewTensor(graph, cnv.dtype, 4, g.WithShape(1, output, 1, 1), g.WithInit(c.BiasInit), g.WithName(fmt.Sprintf("%s-bias", c.Name)))
+	}
+	return cnv, nil
+}
+
+// Validate the config.
+func (c DepthwiseConv2D) Validate() error {
+	if c.Multiplier < 0 {
+		return &ConfigError{Name: fmt.Sprintf("depthwise conv2d %q", c.Name), Reason: fmt.Sprintf("multiplier must not be negative, got %d", c.Multiplier)}
+	}
+	if c.Width == 0 {
+		return &ConfigError{Name: fmt.Sprintf("depthwise conv2d %q", c.Name), Reason: "width must be set"}
+	}
+	if c.Height == 0 {
+		return &ConfigError{Name: fmt.Sprintf("depthwise conv2d %q", c.Name), Reason: "height must be set"}
+	}
+	return nil
+}
+
+// ApplyDefaults to the config.
+func (c DepthwiseConv2D) ApplyDefaults() Config {
+	if c.Multiplier == 0 {
+		c.Multiplier = 1
+	}
+	if c.Activation == nil {
+		c.Activation = ReLU
+	}
+	if len(c.Pad) == 0 {
+		c.Pad = []int{1, 1}
+	}
+	if len(c.Stride) == 0 {
+		c.Stride = []int{1, 1}
+	}
+	if len(c.Dilation) == 0 {
+		c.Dilation = []int{1, 1}
+	}
+	if c.Init == nil {
+		c.Init = g.GlorotU(1)
+	}
+	if c.BiasInit == nil {
+		c.BiasInit = g.Zeroes()
+	}
+	return c
+}
+
+// InferShape infers the input channels from the input shape and returns the output shape.
+func (c DepthwiseConv2D) InferShape(input t.Shape) (Config, t.Shape, error) {
+	c = c.ApplyDefaults().(DepthwiseConv2D)
+	name := fmt.Sprintf("depthwise conv2d %q", c.Name)
+	if input == nil {
+		if c.Input == 0 {
+			return nil, nil, &ConfigError{Name: name, Reason: "input must be set as it cannot be inferred"}
+		}
+		return c, nil, nil
+	}
+	if c.Input == 0 && len(input) == 4 {
+		c.Input = input[1]
+	}
+	output, err := inferConvShape(name, input, c.Input, c.Input*c.Multiplier, c.Height, c.Width, c.Pad, c.Stride, c.Dilation)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, output, nil
+}
+
+// WithNamePrefix returns the config with the prefix added to its name.
+func (c DepthwiseConv2D) WithNamePrefix(prefix string) Config {
+	c.Name = prefixName(prefix, c.Name)
+	return c
+}
+
+// Clone the config.
+func (c DepthwiseConv2D) Clone() Config {
+	return DepthwiseConv2D{
+		Input:      c.Input,
+		Multiplier: c.Multiplier,
+		Height:     c.Height,
+		Width:      c.Width,
+		Name:       c.Name,
+		Activation: c.Activation.Clone(),
+		Pad:        c.Pad,
+		Stride:     c.Stride,
+		Dilation:   c.Dilation,
+		Init:       c.Init,
+		Bias:       c.Bias,
+		BiasInit:   c.BiasInit,
+	}
+}
+
+// depthwiseConv2D is a two dimensional depthwise convolution layer.
+type depthwiseConv2D struct {
+	*DepthwiseConv2D
+
+	dtype          t.Dtype
+	depthwiseShape t.Shape
+	kernelShape    t.Shape
+	kernel         *g.Node
+	bias           *g.Node
+	shared         *depthwiseConv2D
+	isBatched      bool
+}
+
+func newDepthwiseConv2D(config *DepthwiseConv2D) *depthwiseConv2D {
+	return &depthwiseConv2D{
+		DepthwiseConv2D: config,
+		dtype:           t.Float32,
+		kernelShape:     []int{config.Height, config.Width},
+		depthwiseShape:  []int{config.Input * config.Multiplier, 1, config.Height, config.Width},
+	}
+}
+
+// SetSharedLearnables sets the layer to share the learnables of another depthwise conv2d layer.
+func (c *depthwiseConv2D) SetSharedLearnables(shared Layer) error {
+	s, ok := shared.(*depthwiseConv2D)
+	if !ok {
+		return &ConfigError{Name: fmt.Sprintf("depthwise conv2d %q", c.Name), Reason: fmt.Sprintf("cannot share learnables with %T", shared)}
+	}
+	c.shared = s
+	return nil
+}
+
+// SetBatched sets whether the layer is compiled as a batch.
+func (c *depthwiseConv2D) SetBatched(batched bool) {
+	c.isBatched = batched
+}
+
+// SetDType sets the data type of the layer.
+func (c *depthwiseConv2D) SetDType(dtype t.Dtype) {
+	c.dtype = dtype
+}
+
+// Fwd is a forward pass through the layer.
+func (c *depthwiseConv2D) Fwd(x *g.Node) (*g.Node, error) {
+	filter, err := depthwiseFilter(c.kernel, c.Input, c.Multiplier, c.Name)
+	if err != nil {
+		return nil, err
+	}
+	n, err := g.Conv2d(x, filter, c.kernelShape, c.Pad, c.Stride, c.Dilation)
+	if err != nil {
+		return nil, err
+	}
+	if c.bias != nil {
+		n, err = addChannelBias(n, c.bias, c.Name, c.dtype)
+		if err != nil {
+			return nil, err
+		}
+	}
+	n, err = c.Activation.Fwd(n)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("depthwise conv2d name: %q output shape: %v", c.Name, n.Shape())
+	return n, nil
+}
+
+// Learnables returns all learnable nodes within this layer.
+func (c *depthwiseConv2D) Learnables() g.Nodes {
+	if c.bias != nil {
+		return g.Nodes{c.kernel, c.bias}
+	}
+	return g.Nodes{c.kernel}
+}
+
+// Clone the layer without any nodes. (nodes cannot be shared)
+func (c *depthwiseConv2D) Clone() Layer {
+	configCloned := c.DepthwiseConv2D.Clone().(DepthwiseConv2D)
+	return &depthwiseConv2D{
+		DepthwiseConv2D: &configCloned,
+		dtype:           c.dtype,
+		depthwiseShape:  c.depthwiseShape,
+		kernelShape:     c.kernelShape,
+		shared:          c.shared,
+		isBatched:       c.isBatched,
+	}
+}
+
+// Graph returns the graph for this layer.
+func (c *depthwiseConv2D) Graph() *g.ExprGraph {
+	if c.kernel == nil {
+		return nil
+	}
+	return c.kernel.Graph()
+}
+
+// depthwiseFilter expands a depthwise kernel of shape (channels*multiplier, 1, height, width) to the
+// filter of a convolution over all the channels, in which each output channel only sees its input channel.
+// There is no grouped convolution, so the kernel is tiled over the channels with a matmul and the tiles
+// of the other channels are zeroed.
+func depthwiseFilter(kernel *g.Node, channels, multiplier int, name string) (*g.Node, error) {
+	if channels == 1 {
+		return kernel, nil
+	}
+	s := kernel.Shape()
+	outputs, size := s[0], s[2]*s[3]
+	n, err := g.Reshape(kernel, t.Shape{outputs, size})
+	if err != nil {
+		return nil, err
+	}
+	tile := constMatrix(kernel, size, channels*size, fmt.Sprintf("%s-depthwise-tile", name), func(row, col int) bool {
+		return col%size == row
+	})
+	n, err = g.Mul(n, tile)
+	if err != nil {
+		return nil, err
+	}
+	mask := constMatrix(kernel, outputs, channels*size, fmt.Sprintf("%s-depthwise-mask", name), func(row, col int) bool {
+		return col/size == row/multiplier
+	})
+	n, err = g.HadamardProd(n, mask)
+	if err != nil {
+		return nil, err
+	}
+	return g.Reshape(n, t.Shape{outputs, channels, s[2], s[3]})
+}
+
+// constMatrix returns a constant matrix with the data type of the node, which is one where the
+// function is true and zero elsewhere.
+func constMatrix(like *g.Node, rows, cols int, name string, one func(row, col int) bool) *g.Node {
+	var backing interface{}
+	if like.Dtype() == t.Float64 {
+		data := make([]float64, rows*cols)
+		for i := range data {
+			if one(i/cols, i%cols) {
+				data[i] = 1
+			}
+		}
+		backing = data
+	} else {
+		data := make([]float32, rows*cols)
+		for i := range data {
+			if one(i/cols, i%cols) {
+				data[i] = 1
+			}
+		}
+		backing = data
+	}
+	value := t.New(t.WithShape(rows, cols), t.WithBacking(backing))
+	return g.NewMatrix(like.Graph(), like.Dtype(), g.WithShape(rows, cols), g.WithName(name), g.WithValue(value))
+}
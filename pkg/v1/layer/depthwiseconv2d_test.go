@@ -0,0 +1,52 @@
+package layer_test
+
+import (
+	"testing"
+
+	. "github.com/aunum/goro/pkg/v1/layer"
+
+	"github.com/stretchr/testify/require"
+	g "gorgonia.org/gorgonia"
+	"gorgonia.org/tensor"
+)
+
+func TestDepthwiseConv2D(tt *testing.T) {
+	chain, err := NewChain(
+		DepthwiseConv2D{Multiplier: 2, Height: 3, Width: 3, Name: "depthwise", Activation: Linear, Init: g.Ones()},
+		SeparableConv2D{Output: 3, Height: 3, Width: 3, Name: "separable", Activation: Linear, Init: g.Ones(), PointwiseInit: g.Ones(), Bias: true},
+	)
+	require.NoError(tt, err)
+	shape, err := chain.InferShapes(tensor.Shape{1, 2, 2, 2})
+	require.NoError(tt, err)
+	require.Equal(tt, tensor.Shape{1, 3, 2, 2}, shape)
+	require.Equal(tt, 4, chain.Layers[1].(SeparableConv2D).Input)
+
+	graph := g.NewGraph()
+	require.NoError(tt, chain.Compile(graph, WithLayerOpts(AsBatch())))
+	x := g.NewTensor(graph, g.Float32, 4, g.WithShape(1, 2, 2, 2), g.WithValue(tensor.New(tensor.WithShape(1, 2, 2, 2), tensor.WithBacking([]float32{1, 1, 1, 1, 2, 2, 2, 2}))))
+	y, err := chain.Fwd(x)
+	require.NoError(tt, err)
+	cost, err := g.Sum(chain.Outputs()[0])
+	require.NoError(tt, err)
+	learnables := chain.Learnables()
+	require.Len(tt, learnables, 4)
+	_, err = g.Grad(cost, learnables[0])
+	require.NoError(tt, err)
+	vm := g.NewTapeMachine(graph)
+	require.NoError(tt, vm.RunAll())
+
+	// each depthwise output channel only convolves its input channel.
+	require.Equal(tt, []float32{4, 4, 4, 4, 4, 4, 4, 4, 8, 8, 8, 8, 8, 8, 8, 8}, chain.Outputs()[0].Value().Data())
+	require.Equal(tt, []float32{96, 96, 96, 96, 96, 96, 96, 96, 96, 96, 96, 96}, y.Value().Data())
+	grad, err := learnables[0].Grad()
+	require.NoError(tt, err)
+	require.Equal(tt, tensor.Shape{4, 1, 3, 3}, grad.Shape())
+	window := []float32{1, 2, 1, 2, 4, 2, 1, 2, 1}
+	expected := []float32{}
+	for _, scale := range []float32{1, 1, 2, 2} {
+		for _, v := range window {
+			expected = append(expected, v*scale)
+		}
+	}
+	require.Equal(tt, expected, grad.Data())
+}
@@ -1,22 +1,21 @@
 package layer
 
 import (
-	"fmt"
-
 	g "gorgonia.org/gorgonia"
+	t "gorgonia.org/tensor"
 )
 
 // Dropout implements layer dropout.
 type Dropout struct {
 	// Probability of dropping out.
 	// Defaults to 0.6
-	Probability float64
+	Probability float64 `json:"probability,omitempty"`
 }
 
 // Validate the config.
 func (d Dropout) Validate() error {
 	if d.Probability > 1.0 || d.Probability < 0 {
-		return fmt.Errorf("dropout probability must be between 0 and 1")
+		return &ConfigError{Name: "dropout", Reason: "probability must be between 0 and 1"}
 	}
 	return nil
 }
@@ -29,16 +28,21 @@ func (d Dropout) ApplyDefaults() Config {
 	return d
 }
 
+// InferShape returns the output shape for the input shape.
+func (d Dropout) InferShape(input t.Shape) (Config, t.Shape, error) {
+	return d, input, nil
+}
+
 // Compile the config as a layer.
-func (d Dropout) Compile(graph *g.ExprGraph, opts ...CompileOpt) Layer {
+func (d Dropout) Compile(graph *g.ExprGraph, opts ...CompileOpt) (Layer, error) {
 	drop := newDropout(&d)
 	drop.graph = graph
-	return drop
+	return drop, nil
 }
 
 // Clone the config.
 func (d Dropout) Clone() Config {
-	return &Dropout{
+	return Dropout{
 		Probability: d.Probability,
 	}
 }
@@ -67,8 +71,10 @@ func (d *dropout) Learnables() g.Nodes {
 
 // Clone the layer.
 func (d *dropout) Clone() Layer {
+	configCloned := d.Dropout.Clone().(Dropout)
 	return &dropout{
-		Dropout: d.Dropout.Clone().(*Dropout),
+		Dropout: &configCloned,
+		graph:   d.graph,
 	}
 }
 
@@ -0,0 +1,325 @@
+package layer
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Encoded is a layer config encoded with its type so that it can be decoded.
+type Encoded struct {
+	// Type of the layer e.g. fc.
+	Type string `json:"type"`
+
+	// Config of the layer.
+	Config json.RawMessage `json:"config"`
+}
+
+// Encode a layer config, the config type must be registered with RegisterConfig.
+func Encode(config Config) (*Encoded, error) {
+	registry.RLock()
+	typ, ok := registry.configNames[configType(config)]
+	registry.RUnlock()
+	if !ok {
+		return nil, &ConfigError{Name: fmt.Sprintf("%T", config), Reason: "layer type cannot be encoded, it is not registered"}
+	}
+	b, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Encoded{Type: typ, Config: b}, nil
+}
+
+// Decode a layer config, defaults are applied to the config.
+func Decode(e *Encoded) (Config, error) {
+	registry.RLock()
+	typ, ok := registry.configTypes[e.Type]
+	registry.RUnlock()
+	if !ok {
+		return nil, &ConfigError{Name: fmt.Sprintf("layer type %q", e.Type), Reason: fmt.Sprintf("unknown layer type, registered types are %v", ConfigTypes())}
+	}
+	v := reflect.New(typ)
+	if len(e.Config) > 0 {
+		err := json.Unmarshal(e.Config, v.Interface())
+		if err != nil {
+			return nil, fmt.Errorf("could not decode layer type %q: %w", e.Type, err)
+		}
+	}
+	config := v.Elem().Interface().(Config)
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return config.ApplyDefaults(), nil
+}
+
+// EncodedActivation is an activation function encoded with its type and parameters.
+type EncodedActivation struct {
+	// Type of the activation e.g. relu.
+	Type string `json:"type"`
+
+	// Alpha of a leaky relu.
+	Alpha float64 `json:"alpha,omitempty"`
+
+	// Axis of a softmax.
+	Axis []int `json:"axis,omitempty"`
+}
+
+// UnmarshalJSON decodes the activation from JSON, which may also be just the name of its type e.g.
+// "relu".
+func (e *EncodedActivation) UnmarshalJSON(b []byte) error {
+	var typ string
+	if err := json.Unmarshal(b, &typ); err == nil {
+		*e = EncodedActivation{Type: typ}
+		return nil
+	}
+	type encoded EncodedActivation
+	return json.Unmarshal(b, (*encoded)(e))
+}
+
+// EncodeActivation encodes an activation function, a nil activation encodes as nil. The activation
+// type must be registered with RegisterActivation.
+func EncodeActivation(fn ActivationFn) (*EncodedActivation, error) {
+	if fn == nil {
+		return nil, nil
+	}
+	registry.RLock()
+	defer registry.RUnlock()
+	name, ok := registry.activationNames[reflect.TypeOf(fn)]
+	if !ok {
+		return nil, &ConfigError{Name: fmt.Sprintf("%T", fn), Reason: "activation type cannot be encoded, it is not registered"}
+	}
+	return registry.activations[name].encode(fn), nil
+}
+
+// DecodeActivation decodes an activation function, a nil encoding decodes as nil.
+func DecodeActivation(e *EncodedActivation) (ActivationFn, error) {
+	if e == nil {
+		return nil, nil
+	}
+	registry.RLock()
+	codec, ok := registry.activations[e.Type]
+	registry.RUnlock()
+	if !ok {
+		return nil, &ConfigError{Name: fmt.Sprintf("activation type %q", e.Type), Reason: fmt.Sprintf("unknown activation type, registered types are %v", ActivationTypes())}
+	}
+	return codec.decode(e), nil
+}
+
+// MarshalJSON encodes the config as JSON, init functions are not encoded.
+func (f FC) MarshalJSON() ([]byte, error) {
+	type config FC
+	activation, err := EncodeActivation(f.Activation)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		config
+		Activation *EncodedActivation `json:"activation,omitempty"`
+	}{config(f), activation})
+}
+
+// UnmarshalJSON decodes the config from JSON.
+func (f *FC) UnmarshalJSON(b []byte) error {
+	type config FC
+	aux := struct {
+		*config
+		Activation *EncodedActivation `json:"activation,omitempty"`
+	}{config: (*config)(f)}
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+	f.Activation, err = DecodeActivation(aux.Activation)
+	return err
+}
+
+// MarshalJSON encodes the config as JSON, init functions are not encoded.
+func (r SimpleRNN) MarshalJSON() ([]byte, error) {
+	type config SimpleRNN
+	activation, err := EncodeActivation(r.Activation)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		config
+		Activation *EncodedActivation `json:"activation,omitempty"`
+	}{config(r), activation})
+}
+
+// UnmarshalJSON decodes the config from JSON.
+func (r *SimpleRNN) UnmarshalJSON(b []byte) error {
+	type config SimpleRNN
+	aux := struct {
+		*config
+		Activation *EncodedActivation `json:"activation,omitempty"`
+	}{config: (*config)(r)}
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+	r.Activation, err = DecodeActivation(aux.Activation)
+	return err
+}
+
+// MarshalJSON encodes the config as JSON, init functions are not encoded.
+func (c Conv2D) MarshalJSON() ([]byte, error) {
+	type config Conv2D
+	activation, err := EncodeActivation(c.Activation)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		config
+		Activation *EncodedActivation `json:"activation,omitempty"`
+	}{config(c), activation})
+}
+
+// UnmarshalJSON decodes the config from JSON.
+func (c *Conv2D) UnmarshalJSON(b []byte) error {
+	type config Conv2D
+	aux := struct {
+		*config
+		Activation *EncodedActivation `json:"activation,omitempty"`
+	}{config: (*config)(c)}
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+	c.Activation, err = DecodeActivation(aux.Activation)
+	return err
+}
+
+// MarshalJSON encodes the config as JSON, init functions are not encoded.
+func (c DepthwiseConv2D) MarshalJSON() ([]byte, error) {
+	type config DepthwiseConv2D
+	activation, err := EncodeActivation(c.Activation)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		config
+		Activation *EncodedActivation `json:"activation,omitempty"`
+	}{config(c), activation})
+}
+
+// UnmarshalJSON decodes the config from JSON.
+func (c *DepthwiseConv2D) UnmarshalJSON(b []byte) error {
+	type config DepthwiseConv2D
+	aux := struct {
+		*config
+		Activation *EncodedActivation `json:"activation,omitempty"`
+	}{config: (*config)(c)}
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+	c.Activation, err = DecodeActivation(aux.Activation)
+	return err
+}
+
+// MarshalJSON encodes the config as JSON, init functions are not encoded.
+func (c SeparableConv2D) MarshalJSON() ([]byte, error) {
+	type config SeparableConv2D
+	activation, err := EncodeActivation(c.Activation)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		config
+		Activation *EncodedActivation `json:"activation,omitempty"`
+	}{config(c), activation})
+}
+
+// UnmarshalJSON decodes the config from JSON.
+func (c *SeparableConv2D) UnmarshalJSON(b []byte) error {
+	type config SeparableConv2D
+	aux := struct {
+		*config
+		Activation *EncodedActivation `json:"activation,omitempty"`
+	}{config: (*config)(c)}
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+	c.Activation, err = DecodeActivation(aux.Activation)
+	return err
+}
+
+// MarshalJSON encodes the config as JSON along with the encoded layer.
+func (d TimeDistributed) MarshalJSON() ([]byte, error) {
+	layer, err := encodeLayer(d.Layer)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		Layer *Encoded `json:"layer"`
+	}{layer})
+}
+
+// UnmarshalJSON decodes the config from JSON.
+func (d *TimeDistributed) UnmarshalJSON(b []byte) error {
+	aux := struct {
+		Layer *Encoded `json:"layer"`
+	}{}
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+	d.Layer, err = decodeLayer(aux.Layer)
+	return err
+}
+
+// MarshalJSON encodes the config as JSON along with the encoded layers.
+func (b Bidirectional) MarshalJSON() ([]byte, error) {
+	type config Bidirectional
+	layer, err := encodeLayer(b.Layer)
+	if err != nil {
+		return nil, err
+	}
+	backward, err := encodeLayer(b.Backward)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		config
+		Layer    *Encoded `json:"layer"`
+		Backward *Encoded `json:"backward,omitempty"`
+	}{config(b), layer, backward})
+}
+
+// UnmarshalJSON decodes the config from JSON.
+func (b *Bidirectional) UnmarshalJSON(data []byte) error {
+	type config Bidirectional
+	aux := struct {
+		*config
+		Layer    *Encoded `json:"layer"`
+		Backward *Encoded `json:"backward,omitempty"`
+	}{config: (*config)(b)}
+	err := json.Unmarshal(data, &aux)
+	if err != nil {
+		return err
+	}
+	b.Layer, err = decodeLayer(aux.Layer)
+	if err != nil {
+		return err
+	}
+	b.Backward, err = decodeLayer(aux.Backward)
+	return err
+}
+
+// encodeLayer encodes a layer config wrapped by another, a nil config encodes as nil.
+func encodeLayer(config Config) (*Encoded, error) {
+	if config == nil {
+		return nil, nil
+	}
+	return Encode(config)
+}
+
+// decodeLayer decodes a layer config wrapped by another, a nil encoding decodes as nil.
+func decodeLayer(e *Encoded) (Config, error) {
+	if e == nil {
+		return nil, nil
+	}
+	return Decode(e)
+}
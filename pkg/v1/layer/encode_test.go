@@ -0,0 +1,108 @@
+package layer_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/aunum/goro/pkg/v1/layer"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode(t *testing.T) {
+	configs := []Config{
+		FC{Input: 4, Output: 2, Name: "w0", Activation: NewLeakyReLU(0.2), NoBias: true},
+		Conv2D{Input: 1, Output: 2, Height: 3, Width: 3, Name: "c0", Activation: NewSoftmax(1), Bias: true},
+		DepthwiseConv2D{Input: 2, Multiplier: 2, Height: 3, Width: 3, Name: "d0", Stride: []int{2, 2}, Bias: true},
+		SeparableConv2D{Input: 2, Output: 4, Multiplier: 2, Height: 3, Width: 3, Name: "s0", Activation: Tanh},
+		MaxPooling2D{},
+		Flatten{},
+		Reshape{To: []int{2, 4}},
+		Dropout{Probability: 0.2},
+		SimpleRNN{Input: 2, Output: 3, Name: "r0", Activation: Sigmoid, Sequences: true},
+	}
+	for _, config := range configs {
+		config = config.ApplyDefaults()
+		encoded, err := Encode(config)
+		require.NoError(t, err)
+
+		b, err := json.Marshal(encoded)
+		require.NoError(t, err)
+		decoded := &Encoded{}
+		err = json.Unmarshal(b, decoded)
+		require.NoError(t, err)
+
+		c, err := Decode(decoded)
+		require.NoError(t, err)
+		switch expected := config.(type) {
+		case FC:
+			actual := c.(FC)
+			require.Equal(t, expected.Activation, actual.Activation)
+			expected.Activation, actual.Activation = nil, nil
+			expected.Init, actual.Init, expected.BiasInit, actual.BiasInit = nil, nil, nil, nil
+			require.Equal(t, expected, actual)
+		case Conv2D:
+			actual := c.(Conv2D)
+			require.Equal(t, expected.Activation, actual.Activation)
+			expected.Activation, actual.Activation = nil, nil
+			expected.Init, actual.Init, expected.BiasInit, actual.BiasInit = nil, nil, nil, nil
+			require.Equal(t, expected, actual)
+		case DepthwiseConv2D:
+			actual := c.(DepthwiseConv2D)
+			require.Equal(t, expected.Activation, actual.Activation)
+			expected.Activation, actual.Activation = nil, nil
+			expected.Init, actual.Init, expected.BiasInit, actual.BiasInit = nil, nil, nil, nil
+			require.Equal(t, expected, actual)
+		case SeparableConv2D:
+			actual := c.(SeparableConv2D)
+			require.Equal(t, expected.Activation, actual.Activation)
+			expected.Activation, actual.Activation = nil, nil
+			expected.Init, actual.Init, expected.BiasInit, actual.BiasInit = nil, nil, nil, nil
+			expected.PointwiseInit, actual.PointwiseInit = nil, nil
+			require.Equal(t, expected, actual)
+		case SimpleRNN:
+			actual := c.(SimpleRNN)
+			require.Equal(t, expected.Activation, actual.Activation)
+			expected.Activation, actual.Activation = nil, nil
+			expected.Init, actual.Init, expected.BiasInit, actual.BiasInit = nil, nil, nil, nil
+			expected.RecurrentInit, actual.RecurrentInit = nil, nil
+			require.Equal(t, expected, actual)
+		default:
+			require.Equal(t, config, c)
+		}
+	}
+
+	_, err := Decode(&Encoded{Type: "unknown"})
+	require.Error(t, err)
+
+	require.Error(t, RegisterConfig("fc", custom{}))
+	require.Error(t, RegisterConfig("flat", Flatten{}))
+	require.NoError(t, RegisterConfig("custom", custom{}))
+	require.NoError(t, RegisterActivation("custom", &customActivation{}))
+	encoded, err := Encode(&custom{Scale: 2})
+	require.NoError(t, err)
+	require.Equal(t, "custom", encoded.Type)
+	decoded, err := Decode(encoded)
+	require.NoError(t, err)
+	require.Equal(t, custom{Scale: 2}, decoded)
+
+	fc := FC{}
+	require.NoError(t, json.Unmarshal([]byte(`{"output": 2, "activation": "custom"}`), &fc))
+	require.Equal(t, &customActivation{}, fc.Activation)
+	b, err := json.Marshal(fc)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"activation":{"type":"custom"}`)
+}
+
+type custom struct {
+	Flatten
+	Scale float64 `json:"scale"`
+}
+
+func (c custom) ApplyDefaults() Config { return c }
+
+type customActivation struct {
+	LinearActivation
+}
+
+func (c *customActivation) Clone() ActivationFn { return &customActivation{} }
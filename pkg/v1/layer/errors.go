@@ -0,0 +1,55 @@
+package layer
+
+import (
+	"fmt"
+
+	t "gorgonia.org/tensor"
+)
+
+// ShapeError is returned when a shape does not match what is expected.
+type ShapeError struct {
+	// Name of the layer or input with the mismatch.
+	Name string
+
+	// Expected shape description e.g. (batch, 10).
+	Expected string
+
+	// Actual shape.
+	Actual t.Shape
+}
+
+// Error implements the error interface.
+func (s *ShapeError) Error() string {
+	return fmt.Sprintf("shape mismatch: %s expects %s got %v", s.Name, s.Expected, s.Actual)
+}
+
+// DTypeError is returned when a data type does not match what is expected.
+type DTypeError struct {
+	// Name of the layer or input with the mismatch.
+	Name string
+
+	// Expected data type.
+	Expected t.Dtype
+
+	// Actual data type.
+	Actual t.Dtype
+}
+
+// Error implements the error interface.
+func (d *DTypeError) Error() string {
+	return fmt.Sprintf("data type mismatch: %s expects %v got %v", d.Name, d.Expected, d.Actual)
+}
+
+// ConfigError is returned when a configuration is invalid.
+type ConfigError struct {
+	// Name of the layer, model or option with the invalid configuration.
+	Name string
+
+	// Reason the configuration is invalid.
+	Reason string
+}
+
+// Error implements the error interface.
+func (c *ConfigError) Error() string {
+	return fmt.Sprintf("invalid config for %s: %s", c.Name, c.Reason)
+}
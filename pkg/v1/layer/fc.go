@@ -12,31 +12,31 @@ import (
 // FC is a fully connected layer of neurons.
 type FC struct {
 	// Input is the number of units in input.
-	// required
-	Input int
+	// Inferred from the input shape if not set.
+	Input int `json:"input,omitempty"`
 
 	// Output is the number of units in the output.
 	// required
-	Output int
+	Output int `json:"output"`
 
 	// Name of the layer.
-	Name string
+	Name string `json:"name,omitempty"`
 
 	// Activation is the activation function.
 	// Defaults to ReLU
-	Activation ActivationFn
+	Activation ActivationFn `json:"-"`
 
 	// Init is the init function.
 	// Defaults to GlorotN(1)
-	Init g.InitWFn
+	Init g.InitWFn `json:"-"`
 
 	// NoBias indicates to not use a bias with the layer
 	// Defaults to true.
-	NoBias bool
+	NoBias bool `json:"noBias,omitempty"`
 
 	// BiasInit is the init function for the bias.
 	// Defaults to GlorotN(1)
-	BiasInit g.InitWFn
+	BiasInit g.InitWFn `json:"-"`
 }
 
 type fc struct {
@@ -59,11 +59,8 @@ func newFC(config *FC) *fc {
 
 // Validate the config.
 func (f FC) Validate() error {
-	if f.Input == 0 {
-		return fmt.Errorf("input must be set")
-	}
 	if f.Output == 0 {
-		return fmt.Errorf("output must be set")
+		return &ConfigError{Name: fmt.Sprintf("fc %q", f.Name), Reason: "output must be set"}
 	}
 	return nil
 }
@@ -82,29 +79,58 @@ func (f FC) ApplyDefaults() Config {
 	return f
 }
 
+// InferShape infers the input size from the input shape and returns the output shape.
+func (f FC) InferShape(input t.Shape) (Config, t.Shape, error) {
+	if input == nil {
+		if f.Input == 0 {
+			return nil, nil, &ConfigError{Name: fmt.Sprintf("fc %q", f.Name), Reason: "input must be set as it cannot be inferred"}
+		}
+		return f, nil, nil
+	}
+	input = normalizeBatch(input)
+	if len(input) != 2 {
+		return nil, nil, &ShapeError{Name: fmt.Sprintf("fc %q", f.Name), Expected: fmt.Sprintf("(batch, %d)", f.Input), Actual: input}
+	}
+	if f.Input == 0 {
+		f.Input = input[1]
+	}
+	if f.Input != input[1] {
+		return nil, nil, &ShapeError{Name: fmt.Sprintf("fc %q", f.Name), Expected: fmt.Sprintf("(batch, %d)", f.Input), Actual: input}
+	}
+	return f, t.Shape{input[0], f.Output}, nil
+}
+
 // Compile the layer into the graph.
-func (f FC) Compile(graph *g.ExprGraph, opts ...CompileOpt) Layer {
+func (f FC) Compile(graph *g.ExprGraph, opts ...CompileOpt) (Layer, error) {
 	fcn := newFC(&f)
 	for _, opt := range opts {
-		opt(fcn)
+		if err := opt(fcn); err != nil {
+			return nil, err
+		}
 	}
 	if fcn.shared != nil {
 		fcn.weights = g.NewMatrix(graph, fcn.dtype, g.WithShape(f.Input, f.Output), g.WithName(f.Name), g.WithValue(fcn.shared.weights.Value()))
 		if !fcn.NoBias {
 			fcn.bias = g.NewMatrix(graph, fcn.dtype, g.WithShape(1, f.Output), g.WithName(fmt.Sprintf("%s-bias", f.Name)), g.WithValue(fcn.shared.bias.Value()))
 		}
-		return fcn
+		return fcn, nil
 	}
 	fcn.weights = g.NewMatrix(graph, fcn.dtype, g.WithShape(f.Input, f.Output), g.WithInit(f.Init), g.WithName(f.Name))
 	if !f.NoBias {
 		fcn.bias = g.NewMatrix(graph, fcn.dtype, g.WithShape(1, f.Output), g.WithInit(f.BiasInit), g.WithName(fmt.Sprintf("%s-bias", f.Name)))
 	}
-	return fcn
+	return fcn, nil
+}
+
+// WithNamePrefix returns the config with the prefix added to its name.
+func (f FC) WithNamePrefix(prefix string) Config {
+	f.Name = prefixName(prefix, f.Name)
+	return f
 }
 
 // Clone the config.
 func (f FC) Clone() Config {
-	return &FC{
+	return FC{
 		Input:      f.Input,
 		Output:     f.Output,
 		Name:       f.Name,
@@ -115,6 +141,26 @@ func (f FC) Clone() Config {
 	}
 }
 
+// SetSharedLearnables sets the layer to share the learnables of another fc layer.
+func (f *fc) SetSharedLearnables(shared Layer) error {
+	s, ok := shared.(*fc)
+	if !ok {
+		return &ConfigError{Name: fmt.Sprintf("fc %q", f.Name), Reason: fmt.Sprintf("cannot share learnables with %T", shared)}
+	}
+	f.shared = s
+	return nil
+}
+
+// SetBatched sets whether the layer is compiled as a batch.
+func (f *fc) SetBatched(batched bool) {
+	f.isBatched = batched
+}
+
+// SetDType sets the data type of the layer.
+func (f *fc) SetDType(dtype t.Dtype) {
+	f.dtype = dtype
+}
+
 // Fwd is a forward pass on a single fully connected layer.
 func (f *fc) Fwd(x *g.Node) (*g.Node, error) {
 	var xw, xwb *g.Node
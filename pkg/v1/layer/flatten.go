@@ -1,11 +1,10 @@
 package layer
 
 import (
-	"fmt"
-
 	"github.com/aunum/log"
 
 	g "gorgonia.org/gorgonia"
+	t "gorgonia.org/tensor"
 )
 
 // Flatten reshapes the incoming tensor to be flat, preserving the batch.
@@ -14,11 +13,22 @@ type Flatten struct{}
 // ApplyDefaults to the flatten layer.
 func (f Flatten) ApplyDefaults() Config { return f }
 
+// InferShape returns the output shape for the input shape.
+func (f Flatten) InferShape(input t.Shape) (Config, t.Shape, error) {
+	if input == nil {
+		return f, nil, nil
+	}
+	if len(input) < 2 {
+		return nil, nil, &ShapeError{Name: "flatten", Expected: "(batch, x...)", Actual: input}
+	}
+	return f, t.Shape{input[0], product(input[1:])}, nil
+}
+
 // Compile the layer.
-func (f Flatten) Compile(graph *g.ExprGraph, opts ...CompileOpt) Layer {
+func (f Flatten) Compile(graph *g.ExprGraph, opts ...CompileOpt) (Layer, error) {
 	flat := newFlatten(&f)
 	flat.graph = graph
-	return flat
+	return flat, nil
 }
 
 // Clone the config.
@@ -43,7 +53,7 @@ func newFlatten(config *Flatten) *flatten {
 // Fwd is a forward pass through the layer.
 func (f *flatten) Fwd(x *g.Node) (*g.Node, error) {
 	if len(x.Shape()) < 2 {
-		return nil, fmt.Errorf("flatten expects input in the shape (batch, x...), to few dimensions in %v", x.Shape())
+		return nil, &ShapeError{Name: "flatten", Expected: "(batch, x...)", Actual: x.Shape()}
 	}
 	batch := x.Shape()[0]
 	s := x.Shape()[1:]
@@ -67,7 +77,8 @@ func (f *flatten) Learnables() g.Nodes {
 
 // Clone the layer.
 func (f *flatten) Clone() Layer {
-	return &flatten{Flatten: f.Flatten.Clone().(*Flatten)}
+	configCloned := f.Flatten.Clone().(Flatten)
+	return &flatten{Flatten: &configCloned, graph: f.graph}
 }
 
 // Graph returns the graph for this layer.
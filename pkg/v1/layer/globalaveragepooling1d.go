@@ -0,0 +1,157 @@
+package layer
+
+import (
+	"fmt"
+
+	"github.com/aunum/log"
+
+	g "gorgonia.org/gorgonia"
+	t "gorgonia.org/tensor"
+)
+
+// GlobalAveragePooling1D averages a sequence of shape (batch, time, features) over its timesteps,
+// ignoring any masked timesteps.
+type GlobalAveragePooling1D struct{}
+
+// Validate the config.
+func (p GlobalAveragePooling1D) Validate() error {
+	return nil
+}
+
+// ApplyDefaults to the config.
+func (p GlobalAveragePooling1D) ApplyDefaults() Config {
+	return p
+}
+
+// InferShape returns the output shape for the input shape.
+func (p GlobalAveragePooling1D) InferShape(input t.Shape) (Config, t.Shape, error) {
+	if input == nil {
+		return p, nil, nil
+	}
+	if len(input) != 3 {
+		return nil, nil, &ShapeError{Name: "global average pooling 1d", Expected: "(batch, time, features)", Actual: input}
+	}
+	return p, t.Shape{input[0], input[2]}, nil
+}
+
+// Compile the layer into the graph.
+func (p GlobalAveragePooling1D) Compile(graph *g.ExprGraph, opts ...CompileOpt) (Layer, error) {
+	pool := newGlobalAveragePooling1D(&p)
+	pool.graph = graph
+	return pool, nil
+}
+
+// Clone the config.
+func (p GlobalAveragePooling1D) Clone() Config {
+	return GlobalAveragePooling1D{}
+}
+
+type globalAveragePooling1D struct {
+	*GlobalAveragePooling1D
+	graph *g.ExprGraph
+	mask  *g.Node
+}
+
+func newGlobalAveragePooling1D(config *GlobalAveragePooling1D) *globalAveragePooling1D {
+	return &globalAveragePooling1D{GlobalAveragePooling1D: config}
+}
+
+// SetMask sets the mask of the input, masked timesteps are not averaged.
+func (p *globalAveragePooling1D) SetMask(mask *g.Node) {
+	p.mask = mask
+}
+
+// Mask of the output, which is not a sequence.
+func (p *globalAveragePooling1D) Mask() *g.Node {
+	return nil
+}
+
+// Fwd is a forward pass through the layer.
+func (p *globalAveragePooling1D) Fwd(x *g.Node) (*g.Node, error) {
+	s := x.Shape().Clone()
+	if len(s) != 3 {
+		return nil, &ShapeError{Name: "global average pooling 1d", Expected: "(batch, time, features)", Actual: s}
+	}
+	batch, steps, features := s[0], s[1], s[2]
+	var err error
+	if p.mask != nil {
+		x, err = ApplyMask(x, p.mask)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// the timesteps are summed by multiplying the flattened sequence with stacked identity matrices.
+	n, err := g.Reshape(x, t.Shape{batch, steps * features})
+	if err != nil {
+		return nil, err
+	}
+	n, err = g.Mul(n, sumSteps(x, steps, features))
+	if err != nil {
+		return nil, err
+	}
+	if p.mask == nil {
+		n, err = g.Mul(n, scalar(x, 1/float64(steps)))
+	} else {
+		var count *g.Node
+		count, err = g.Mul(p.mask, sumSteps(x, steps, 1))
+		if err != nil {
+			return nil, err
+		}
+		// a fully masked sequence has a count of zero which is clamped to one so its average is zero.
+		var empty *g.Node
+		empty, err = g.Eq(count, scalar(x, 0), true)
+		if err != nil {
+			return nil, err
+		}
+		count, err = g.Add(count, empty)
+		if err != nil {
+			return nil, err
+		}
+		count, err = g.Inverse(count)
+		if err != nil {
+			return nil, err
+		}
+		n, err = g.BroadcastHadamardProd(n, count, nil, []byte{1})
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("global average pooling 1d output shape: %v", n.Shape())
+	return n, nil
+}
+
+// Learnables returns all learnable nodes within this layer.
+func (p *globalAveragePooling1D) Learnables() g.Nodes {
+	return g.Nodes{}
+}
+
+// Clone the layer.
+func (p *globalAveragePooling1D) Clone() Layer {
+	return &globalAveragePooling1D{GlobalAveragePooling1D: &GlobalAveragePooling1D{}, graph: p.graph}
+}
+
+// Graph returns the graph for this layer.
+func (p *globalAveragePooling1D) Graph() *g.ExprGraph {
+	return p.graph
+}
+
+// sumSteps returns a matrix of shape (steps*size, size) of stacked identity matrices, which sums the
+// timesteps of a flattened sequence.
+func sumSteps(like *g.Node, steps, size int) *g.Node {
+	var backing interface{}
+	if like.Dtype() == t.Float64 {
+		data := make([]float64, steps*size*size)
+		for i := 0; i < steps*size; i++ {
+			data[i*size+i%size] = 1
+		}
+		backing = data
+	} else {
+		data := make([]float32, steps*size*size)
+		for i := 0; i < steps*size; i++ {
+			data[i*size+i%size] = 1
+		}
+		backing = data
+	}
+	value := t.New(t.WithShape(steps*size, size), t.WithBacking(backing))
+	return g.NewMatrix(like.Graph(), like.Dtype(), g.WithShape(steps*size, size), g.WithName(fmt.Sprintf("sum-steps-%dx%d", steps, size)), g.WithValue(value))
+}
@@ -0,0 +1,218 @@
+package layer
+
+import (
+	"fmt"
+
+	"github.com/aunum/log"
+
+	g "gorgonia.org/gorgonia"
+	t "gorgonia.org/tensor"
+)
+
+// Lambda is a layer which applies an arbitrary function to its input e.g. to scale, clip or slice it.
+// Lambda layers cannot be encoded, so models containing them cannot be saved or exported.
+type Lambda struct {
+	// Name of the layer.
+	Name string
+
+	// Fn is the function applied to the input.
+	// Either Fn or LearnableFn is required.
+	Fn func(x *g.Node) (*g.Node, error)
+
+	// LearnableFn is the function applied to the input along with the learnables of the layer, in the
+	// order they are declared.
+	// Either Fn or LearnableFn is required.
+	LearnableFn func(x *g.Node, learnables g.Nodes) (*g.Node, error)
+
+	// Learnables the layer declares, which are passed to the LearnableFn.
+	Learnables []Learnable
+
+	// OutputShape returns the output shape of the function for the input shape, both including the batch
+	// dimension.
+	// Defaults to the input shape.
+	OutputShape func(input t.Shape) (t.Shape, error)
+}
+
+// Learnable is a learnable declared by a layer.
+type Learnable struct {
+	// Name of the learnable.
+	// required
+	Name string
+
+	// Shape of the learnable.
+	// required
+	Shape t.Shape
+
+	// Init is the init function for the learnable.
+	// Defaults to GlorotN(1)
+	Init g.InitWFn
+}
+
+// Validate the config.
+func (l Lambda) Validate() error {
+	name := fmt.Sprintf("lambda %q", l.Name)
+	if (l.Fn == nil) == (l.LearnableFn == nil) {
+		return &ConfigError{Name: name, Reason: "exactly one of fn or learnable fn must be set"}
+	}
+	if len(l.Learnables) > 0 && l.LearnableFn == nil {
+		return &ConfigError{Name: name, Reason: "learnables require a learnable fn"}
+	}
+	names := map[string]bool{}
+	for i, learnable := range l.Learnables {
+		if learnable.Name == "" {
+			return &ConfigError{Name: name, Reason: fmt.Sprintf("learnable %d name must be set", i)}
+		}
+		if names[learnable.Name] {
+			return &ConfigError{Name: name, Reason: fmt.Sprintf("duplicate learnable %q", learnable.Name)}
+		}
+		names[learnable.Name] = true
+		if len(learnable.Shape) == 0 {
+			return &ConfigError{Name: name, Reason: fmt.Sprintf("learnable %q shape must be set", learnable.Name)}
+		}
+	}
+	return nil
+}
+
+// ApplyDefaults to the config.
+func (l Lambda) ApplyDefaults() Config {
+	learnables := make([]Learnable, len(l.Learnables))
+	for i, learnable := range l.Learnables {
+		if learnable.Init == nil {
+			learnable.Init = g.GlorotN(1)
+		}
+		learnables[i] = learnable
+	}
+	l.Learnables = learnables
+	return l
+}
+
+// InferShape returns the output shape for the input shape.
+func (l Lambda) InferShape(input t.Shape) (Config, t.Shape, error) {
+	if input == nil {
+		return l, nil, nil
+	}
+	if l.OutputShape == nil {
+		return l, input.Clone(), nil
+	}
+	output, err := l.OutputShape(input.Clone())
+	if err != nil {
+		return nil, nil, fmt.Errorf("lambda %q: %w", l.Name, err)
+	}
+	return l, output, nil
+}
+
+// Compile the layer into the graph.
+func (l Lambda) Compile(graph *g.ExprGraph, opts ...CompileOpt) (Layer, error) {
+	lam := newLambda(&l)
+	lam.graph = graph
+	for _, opt := range opts {
+		if err := opt(lam); err != nil {
+			return nil, err
+		}
+	}
+	for i, learnable := range l.Learnables {
+		name := learnable.Name
+		if l.Name != "" {
+			name = fmt.Sprintf("%s-%s", l.Name, learnable.Name)
+		}
+		var n *g.Node
+		if lam.shared != nil {
+			n = g.NewTensor(graph, lam.dtype, len(learnable.Shape), g.WithShape(learnable.Shape...), g.WithName(name), g.WithValue(lam.shared.learnables[i].Value()))
+		} else {
+			n = g.NewTensor(graph, lam.dtype, len(learnable.Shape), g.WithShape(learnable.Shape...), g.WithName(name), g.WithInit(learnable.Init))
+		}
+		lam.learnables = append(lam.learnables, n)
+	}
+	return lam, nil
+}
+
+// WithNamePrefix returns the config with the prefix added to its name.
+func (l Lambda) WithNamePrefix(prefix string) Config {
+	l.Name = prefixName(prefix, l.Name)
+	return l
+}
+
+// Clone the config.
+func (l Lambda) Clone() Config {
+	learnables := make([]Learnable, len(l.Learnables))
+	for i, learnable := range l.Learnables {
+		learnables[i] = Learnable{Name: learnable.Name, Shape: learnable.Shape.Clone(), Init: learnable.Init}
+	}
+	return Lambda{
+		Name:        l.Name,
+		Fn:          l.Fn,
+		LearnableFn: l.LearnableFn,
+		Learnables:  learnables,
+		OutputShape: l.OutputShape,
+	}
+}
+
+type lambda struct {
+	*Lambda
+	graph      *g.ExprGraph
+	dtype      t.Dtype
+	learnables g.Nodes
+	shared     *lambda
+}
+
+func newLambda(config *Lambda) *lambda {
+	return &lambda{
+		Lambda: config,
+		dtype:  t.Float32,
+	}
+}
+
+// SetSharedLearnables sets the layer to share the learnables of another lambda layer.
+func (l *lambda) SetSharedLearnables(shared Layer) error {
+	s, ok := shared.(*lambda)
+	if !ok {
+		return &ConfigError{Name: fmt.Sprintf("lambda %q", l.Name), Reason: fmt.Sprintf("cannot share learnables with %T", shared)}
+	}
+	if len(s.learnables) != len(l.Lambda.Learnables) {
+		return &ConfigError{Name: fmt.Sprintf("lambda %q", l.Name), Reason: fmt.Sprintf("cannot share %d learnables, have %d", len(s.learnables), len(l.Lambda.Learnables))}
+	}
+	l.shared = s
+	return nil
+}
+
+// SetDType sets the data type of the layer.
+func (l *lambda) SetDType(dtype t.Dtype) {
+	l.dtype = dtype
+}
+
+// Fwd is a forward pass through the layer.
+func (l *lambda) Fwd(x *g.Node) (*g.Node, error) {
+	var n *g.Node
+	var err error
+	if l.LearnableFn != nil {
+		n, err = l.LearnableFn(x, l.learnables)
+	} else {
+		n, err = l.Fn(x)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lambda %q: %w", l.Name, err)
+	}
+	log.Debugf("lambda %q output shape: %v", l.Name, n.Shape())
+	return n, nil
+}
+
+// Learnables returns all learnable nodes within this layer.
+func (l *lambda) Learnables() g.Nodes {
+	return l.learnables
+}
+
+// Clone the layer without any nodes. (nodes cannot be shared)
+func (l *lambda) Clone() Layer {
+	configCloned := l.Lambda.Clone().(Lambda)
+	return &lambda{
+		Lambda: &configCloned,
+		graph:  l.graph,
+		dtype:  l.dtype,
+		shared: l.shared,
+	}
+}
+
+// Graph returns the graph for this layer.
+func (l *lambda) Graph() *g.ExprGraph {
+	return l.graph
+}
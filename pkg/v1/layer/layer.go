@@ -2,6 +2,8 @@
 package layer
 
 import (
+	"fmt"
+
 	g "gorgonia.org/gorgonia"
 	t "gorgonia.org/tensor"
 )
@@ -9,7 +11,7 @@ import (
 // Config is the config for a layer.
 type Config interface {
 	// Compile the layer.
-	Compile(graph *g.ExprGraph, opts ...CompileOpt) Layer
+	Compile(graph *g.ExprGraph, opts ...CompileOpt) (Layer, error)
 
 	// ApplyDefaults to the config.
 	ApplyDefaults() Config
@@ -37,40 +39,69 @@ type Layer interface {
 }
 
 // CompileOpt is a layer compile option.
-type CompileOpt func(Layer)
-
-// WithSharedLearnables shares the learnables from another layer.
-func WithSharedLearnables(shared Layer) func(Layer) {
-	return func(l Layer) {
-		switch lay := l.(type) {
-		case *fc:
-			lay.shared = shared.(*fc)
-		case *conv2D:
-			lay.shared = shared.(*conv2D)
+type CompileOpt func(Layer) error
+
+// SharedLearnablesSetter is a layer which can share the learnables of another layer, such as the layers
+// compiled into the batch and online graphs of a model which share those of its train graph.
+type SharedLearnablesSetter interface {
+	// SetSharedLearnables sets the layer to share the learnables of the other layer when compiled.
+	SetSharedLearnables(shared Layer) error
+}
+
+// Batchable is a layer which is informed when it is compiled as a batch.
+type Batchable interface {
+	// SetBatched sets whether the layer is compiled as a batch.
+	SetBatched(batched bool)
+}
+
+// Typed is a layer which is compiled with a data type.
+type Typed interface {
+	// SetDType sets the data type of the layer.
+	SetDType(dtype t.Dtype)
+}
+
+// NamePrefixer is a layer config whose name can be prefixed, such as to give a copy of the layer compiled
+// into the same graph distinct learnables.
+type NamePrefixer interface {
+	// WithNamePrefix returns the config with the prefix added to its name.
+	WithNamePrefix(prefix string) Config
+}
+
+// prefixName adds the prefix to the name.
+func prefixName(prefix, name string) string {
+	if name == "" {
+		return prefix
+	}
+	return fmt.Sprintf("%s-%s", prefix, name)
+}
+
+// WithSharedLearnables shares the learnables from another layer, if the layer is a
+// SharedLearnablesSetter.
+func WithSharedLearnables(shared Layer) func(Layer) error {
+	return func(l Layer) error {
+		if setter, ok := l.(SharedLearnablesSetter); ok {
+			return setter.SetSharedLearnables(shared)
 		}
+		return nil
 	}
 }
 
-// AsBatch informs the layer compilation that it is a batch.
-func AsBatch() func(Layer) {
-	return func(l Layer) {
-		switch lay := l.(type) {
-		case *fc:
-			lay.isBatched = true
-		case *conv2D:
-			lay.isBatched = true
+// AsBatch informs the layer compilation that it is a batch, if the layer is Batchable.
+func AsBatch() func(Layer) error {
+	return func(l Layer) error {
+		if b, ok := l.(Batchable); ok {
+			b.SetBatched(true)
 		}
+		return nil
 	}
 }
 
-// AsType sets the datatype for the layer.
-func AsType(dtype t.Dtype) func(Layer) {
-	return func(l Layer) {
-		switch lay := l.(type) {
-		case *fc:
-			lay.dtype = dtype
-		case *conv2D:
-			lay.dtype = dtype
+// AsType sets the datatype for the layer, if the layer is Typed.
+func AsType(dtype t.Dtype) func(Layer) error {
+	return func(l Layer) error {
+		if typed, ok := l.(Typed); ok {
+			typed.SetDType(dtype)
 		}
+		return nil
 	}
 }
@@ -0,0 +1,203 @@
+package layer
+
+import (
+	"fmt"
+
+	"github.com/aunum/log"
+
+	g "gorgonia.org/gorgonia"
+	t "gorgonia.org/tensor"
+)
+
+// Masks are of shape (batch, time) with ones for the timesteps of a sequence of shape
+// (batch, time, x...) to use and zeros for those to ignore, such as padding. A chain propagates the
+// mask of its input through its layers which are a Masker, the mask is dropped by any other layer.
+
+// MaskSetter is a layer which uses the mask of its input sequence.
+type MaskSetter interface {
+	// SetMask sets the mask of the input of the next forward pass, which is nil if the input is not masked.
+	SetMask(mask *g.Node)
+}
+
+// Masker is a layer which determines the mask of its output, such as a layer which computes, passes on or
+// consumes a mask.
+type Masker interface {
+	// Mask of the output of the last forward pass, nil if the output is not masked.
+	Mask() *g.Node
+}
+
+// Masking masks the timesteps of a sequence of shape (batch, time, x...) in which every value is the
+// mask value, such as padding. Masked timesteps are zeroed and subsequent layers ignore them.
+type Masking struct {
+	// Value of the masked timesteps.
+	// Defaults to 0
+	Value float64 `json:"value,omitempty"`
+}
+
+// Validate the config.
+func (m Masking) Validate() error {
+	return nil
+}
+
+// ApplyDefaults to the config.
+func (m Masking) ApplyDefaults() Config {
+	return m
+}
+
+// InferShape returns the output shape for the input shape.
+func (m Masking) InferShape(input t.Shape) (Config, t.Shape, error) {
+	if input != nil && len(input) < 3 {
+		return nil, nil, &ShapeError{Name: "masking", Expected: "(batch, time, x...)", Actual: input}
+	}
+	return m, input, nil
+}
+
+// Compile the layer into the graph.
+func (m Masking) Compile(graph *g.ExprGraph, opts ...CompileOpt) (Layer, error) {
+	mask := newMasking(&m)
+	mask.graph = graph
+	return mask, nil
+}
+
+// Clone the config.
+func (m Masking) Clone() Config {
+	return Masking{Value: m.Value}
+}
+
+type masking struct {
+	*Masking
+	graph *g.ExprGraph
+	input *g.Node
+	mask  *g.Node
+}
+
+func newMasking(config *Masking) *masking {
+	return &masking{Masking: config}
+}
+
+// SetMask sets the mask of the input, which is combined with the computed mask.
+func (m *masking) SetMask(mask *g.Node) {
+	m.input = mask
+}
+
+// Mask of the output of the last forward pass.
+func (m *masking) Mask() *g.Node {
+	return m.mask
+}
+
+// Fwd is a forward pass through the layer.
+func (m *masking) Fwd(x *g.Node) (*g.Node, error) {
+	s := x.Shape().Clone()
+	if len(s) < 3 {
+		return nil, &ShapeError{Name: "masking", Expected: "(batch, time, x...)", Actual: s}
+	}
+	// the values of each timestep which are not the mask value are counted with a matrix of ones.
+	// x is compared before it is reshaped as a comparison of the reshaped view may be done in place.
+	size := product(s[2:])
+	n, err := g.Ne(x, scalar(x, m.Value), true)
+	if err != nil {
+		return nil, err
+	}
+	n, err = g.Reshape(n, t.Shape{s[0] * s[1], size})
+	if err != nil {
+		return nil, err
+	}
+	n, err = g.Mul(n, sumSteps(x, size, 1))
+	if err != nil {
+		return nil, err
+	}
+	n, err = g.Gt(n, scalar(x, 0), true)
+	if err != nil {
+		return nil, err
+	}
+	mask, err := g.Reshape(n, t.Shape{s[0], s[1]})
+	if err != nil {
+		return nil, err
+	}
+	if m.input != nil {
+		mask, err = g.HadamardProd(mask, m.input)
+		if err != nil {
+			return nil, err
+		}
+	}
+	m.mask = mask
+	n, err = ApplyMask(x, mask)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("masking output shape: %v", n.Shape())
+	return n, nil
+}
+
+// Learnables returns all learnable nodes within this layer.
+func (m *masking) Learnables() g.Nodes {
+	return g.Nodes{}
+}
+
+// Clone the layer.
+func (m *masking) Clone() Layer {
+	configCloned := m.Masking.Clone().(Masking)
+	return &masking{Masking: &configCloned, graph: m.graph}
+}
+
+// Graph returns the graph for this layer.
+func (m *masking) Graph() *g.ExprGraph {
+	return m.graph
+}
+
+// ApplyMask zeroes the masked timesteps of a sequence of shape (batch, time, x...).
+func ApplyMask(x, mask *g.Node) (*g.Node, error) {
+	s := x.Shape().Clone()
+	if !masks(mask, s) {
+		return nil, &ShapeError{Name: "mask", Expected: fmt.Sprintf("a sequence of shape (%d, %d, x...)", mask.Shape()[0], mask.Shape()[1]), Actual: s}
+	}
+	size := product(s[2:])
+	n, err := g.Reshape(x, t.Shape{s[0], s[1], size})
+	if err != nil {
+		return nil, err
+	}
+	m, err := g.Reshape(mask, t.Shape{s[0], s[1], 1})
+	if err != nil {
+		return nil, err
+	}
+	if size == 1 {
+		n, err = g.HadamardProd(n, m)
+	} else {
+		n, err = g.BroadcastHadamardProd(n, m, nil, []byte{2})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return g.Reshape(n, s)
+}
+
+// masks tests whether the mask applies to an output of the shape.
+func masks(mask *g.Node, shape t.Shape) bool {
+	if mask == nil || len(shape) < 2 {
+		return false
+	}
+	m := mask.Shape()
+	return len(m) == 2 && shape[0] == m[0] && shape[1] == m[1]
+}
+
+// reverseMask reverses a mask along its time axis.
+func reverseMask(mask *g.Node) (*g.Node, error) {
+	s := mask.Shape().Clone()
+	n, err := g.Reshape(mask, t.Shape{s[0], s[1], 1})
+	if err != nil {
+		return nil, err
+	}
+	n, err = reverseSequence(n)
+	if err != nil {
+		return nil, err
+	}
+	return g.Reshape(n, s)
+}
+
+// scalar returns a constant scalar of the value with the data type of the node.
+func scalar(like *g.Node, value float64) *g.Node {
+	if like.Dtype() == t.Float64 {
+		return g.NewConstant(value)
+	}
+	return g.NewConstant(float32(value))
+}
@@ -0,0 +1,71 @@
+package layer_test
+
+import (
+	"testing"
+
+	. "github.com/aunum/goro/pkg/v1/layer"
+
+	"github.com/stretchr/testify/require"
+	g "gorgonia.org/gorgonia"
+	"gorgonia.org/tensor"
+)
+
+func TestMasking(tt *testing.T) {
+	identity := Lambda{Fn: func(x *g.Node) (*g.Node, error) { return x, nil }}
+	chain, err := NewChain(Masking{}, TimeDistributed{Layer: identity}, GlobalAveragePooling1D{})
+	require.NoError(tt, err)
+	shape, err := chain.InferShapes(tensor.Shape{2, 3, 2})
+	require.NoError(tt, err)
+	require.Equal(tt, tensor.Shape{2, 2}, shape)
+
+	graph := g.NewGraph()
+	require.NoError(tt, chain.Compile(graph, WithLayerOpts(AsBatch())))
+	x := g.NewTensor(graph, g.Float32, 3, g.WithShape(2, 3, 2), g.WithValue(tensor.New(tensor.WithShape(2, 3, 2), tensor.WithBacking([]float32{1, 2, 3, 4, 0, 0, 5, 0, 0, 0, 0, 0}))))
+	y, err := chain.Fwd(x)
+	require.NoError(tt, err)
+	require.Nil(tt, chain.Mask())
+	mask := chain.Outputs()[0]
+	vm := g.NewTapeMachine(graph)
+	require.NoError(tt, vm.RunAll())
+	require.Equal(tt, []float32{1, 2, 3, 4, 0, 0, 5, 0, 0, 0, 0, 0}, mask.Value().Data())
+	require.Equal(tt, []float32{2, 3, 5, 0}, y.Value().Data())
+
+	// a fully masked sequence averages to zero.
+	chain, err = NewChain(Masking{}, GlobalAveragePooling1D{})
+	require.NoError(tt, err)
+	graph = g.NewGraph()
+	require.NoError(tt, chain.Compile(graph, WithLayerOpts(AsBatch())))
+	x = g.NewTensor(graph, g.Float32, 3, g.WithShape(2, 2, 2), g.WithValue(tensor.New(tensor.WithShape(2, 2, 2), tensor.WithBacking([]float32{1, 2, 3, 4, 0, 0, 0, 0}))))
+	y, err = chain.Fwd(x)
+	require.NoError(tt, err)
+	vm = g.NewTapeMachine(graph)
+	require.NoError(tt, vm.RunAll())
+	require.Equal(tt, []float32{2, 3, 0, 0}, y.Value().Data())
+
+	// a mask of the input is propagated through the layers which keep the timesteps.
+	chain, err = NewChain(TimeDistributed{Layer: identity})
+	require.NoError(tt, err)
+	graph = g.NewGraph()
+	require.NoError(tt, chain.Compile(graph, WithLayerOpts(AsBatch())))
+	x = g.NewTensor(graph, g.Float32, 3, g.WithShape(1, 3, 2), g.WithValue(tensor.New(tensor.WithShape(1, 3, 2), tensor.WithBacking([]float32{1, 2, 3, 4, 5, 6}))))
+	m := g.NewMatrix(graph, g.Float32, g.WithShape(1, 3), g.WithValue(tensor.New(tensor.WithShape(1, 3), tensor.WithBacking([]float32{1, 0, 1}))))
+	chain.SetMask(m)
+	y, err = chain.Fwd(x)
+	require.NoError(tt, err)
+	require.Equal(tt, m, chain.Mask())
+	vm = g.NewTapeMachine(graph)
+	require.NoError(tt, vm.RunAll())
+	require.Equal(tt, []float32{1, 2, 0, 0, 5, 6}, y.Value().Data())
+
+	// layers which do not declare the mask of their output drop it, even if they keep the timesteps.
+	chain, err = NewChain(TimeDistributed{Layer: identity}, identity)
+	require.NoError(tt, err)
+	graph = g.NewGraph()
+	require.NoError(tt, chain.Compile(graph, WithLayerOpts(AsBatch())))
+	x = g.NewTensor(graph, g.Float32, 3, g.WithShape(1, 3, 2), g.WithValue(tensor.New(tensor.WithShape(1, 3, 2), tensor.WithBacking([]float32{1, 2, 3, 4, 5, 6}))))
+	m = g.NewMatrix(graph, g.Float32, g.WithShape(1, 3), g.WithValue(tensor.New(tensor.WithShape(1, 3), tensor.WithBacking([]float32{1, 0, 1}))))
+	chain.SetMask(m)
+	_, err = chain.Fwd(x)
+	require.NoError(tt, err)
+	require.Nil(tt, chain.Mask())
+}
@@ -1,6 +1,8 @@
 package layer
 
 import (
+	"fmt"
+
 	"github.com/aunum/log"
 
 	g "gorgonia.org/gorgonia"
@@ -11,18 +13,18 @@ import (
 type MaxPooling2D struct {
 	// Shape of the kernel.
 	// Defaults to (2, 2)
-	Kernel t.Shape
+	Kernel t.Shape `json:"kernel,omitempty"`
 
 	// Pad
 	// Defaults to (0, 0)
-	Pad []int
+	Pad []int `json:"pad,omitempty"`
 
 	// Stride
 	// Defaults to (2, 2)
-	Stride []int
+	Stride []int `json:"stride,omitempty"`
 
 	// Name
-	Name string
+	Name string `json:"name,omitempty"`
 }
 
 // Validate the config.
@@ -44,16 +46,33 @@ func (m MaxPooling2D) ApplyDefaults() Config {
 	return m
 }
 
+// InferShape returns the output shape for the input shape.
+func (m MaxPooling2D) InferShape(input t.Shape) (Config, t.Shape, error) {
+	m = m.ApplyDefaults().(MaxPooling2D)
+	if input == nil {
+		return m, nil, nil
+	}
+	if len(input) != 4 {
+		return nil, nil, &ShapeError{Name: fmt.Sprintf("maxpooling2d %q", m.Name), Expected: "(batch, channels, height, width)", Actual: input}
+	}
+	height := convOutput(input[2], m.Kernel[0], m.Pad[0], m.Stride[0], 1)
+	width := convOutput(input[3], m.Kernel[1], m.Pad[1], m.Stride[1], 1)
+	if height <= 0 || width <= 0 {
+		return nil, nil, &ShapeError{Name: fmt.Sprintf("maxpooling2d %q", m.Name), Expected: fmt.Sprintf("height and width of at least the kernel %v", m.Kernel), Actual: input}
+	}
+	return m, t.Shape{input[0], input[1], height, width}, nil
+}
+
 // Compile the config as a layer.
-func (m MaxPooling2D) Compile(graph *g.ExprGraph, opts ...CompileOpt) Layer {
+func (m MaxPooling2D) Compile(graph *g.ExprGraph, opts ...CompileOpt) (Layer, error) {
 	mp := newMaxPooling2d(&m)
 	mp.graph = graph
-	return mp
+	return mp, nil
 }
 
 // Clone the config.
 func (m MaxPooling2D) Clone() Config {
-	return &MaxPooling2D{
+	return MaxPooling2D{
 		Kernel: m.Kernel,
 		Pad:    m.Pad,
 		Stride: m.Stride,
@@ -90,8 +109,10 @@ func (m *maxPooling2D) Learnables() g.Nodes {
 
 // Clone the layer.
 func (m *maxPooling2D) Clone() Layer {
+	configCloned := m.MaxPooling2D.Clone().(MaxPooling2D)
 	return &maxPooling2D{
-		MaxPooling2D: m.MaxPooling2D.Clone().(*MaxPooling2D),
+		MaxPooling2D: &configCloned,
+		graph:        m.graph,
 	}
 }
 
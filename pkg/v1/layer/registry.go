@@ -0,0 +1,154 @@
+package layer
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// registry of the layer config and activation types which can be encoded and decoded.
+var registry = struct {
+	sync.RWMutex
+
+	configTypes map[string]reflect.Type
+	configNames map[reflect.Type]string
+
+	activations     map[string]activationCodec
+	activationNames map[reflect.Type]string
+}{
+	configTypes:     map[string]reflect.Type{},
+	configNames:     map[reflect.Type]string{},
+	activations:     map[string]activationCodec{},
+	activationNames: map[reflect.Type]string{},
+}
+
+// activationCodec encodes and decodes an activation type.
+type activationCodec struct {
+	encode func(fn ActivationFn) *EncodedActivation
+	decode func(e *EncodedActivation) ActivationFn
+}
+
+func init() {
+	for name, config := range map[string]Config{
+		"fc":                       FC{},
+		"conv2d":                   Conv2D{},
+		"depthwise_conv2d":         DepthwiseConv2D{},
+		"separable_conv2d":         SeparableConv2D{},
+		"max_pooling2d":            MaxPooling2D{},
+		"flatten":                  Flatten{},
+		"reshape":                  Reshape{},
+		"dropout":                  Dropout{},
+		"time_distributed":         TimeDistributed{},
+		"bidirectional":            Bidirectional{},
+		"simple_rnn":               SimpleRNN{},
+		"masking":                  Masking{},
+		"global_average_pooling1d": GlobalAveragePooling1D{},
+	} {
+		if err := RegisterConfig(name, config); err != nil {
+			panic(err)
+		}
+	}
+	for name, fn := range map[string]ActivationFn{
+		"sigmoid": Sigmoid,
+		"tanh":    Tanh,
+		"relu":    ReLU,
+		"linear":  Linear,
+	} {
+		if err := RegisterActivation(name, fn); err != nil {
+			panic(err)
+		}
+	}
+	registerActivation("leaky_relu", &LeakyReLUActivation{}, activationCodec{
+		encode: func(fn ActivationFn) *EncodedActivation {
+			return &EncodedActivation{Type: "leaky_relu", Alpha: fn.(*LeakyReLUActivation).alpha}
+		},
+		decode: func(e *EncodedActivation) ActivationFn { return NewLeakyReLU(e.Alpha) },
+	})
+	registerActivation("softmax", &SoftmaxActivation{}, activationCodec{
+		encode: func(fn ActivationFn) *EncodedActivation {
+			return &EncodedActivation{Type: "softmax", Axis: fn.(*SoftmaxActivation).axis}
+		},
+		decode: func(e *EncodedActivation) ActivationFn { return NewSoftmax(e.Axis...) },
+	})
+}
+
+// RegisterConfig registers a layer config type by name so that it can be encoded and decoded, as in
+// saved models and model definitions. The config is decoded from JSON into a new value of its type.
+func RegisterConfig(name string, config Config) error {
+	typ := configType(config)
+	if name == "" || typ == nil {
+		return &ConfigError{Name: fmt.Sprintf("layer type %q", name), Reason: "name and config must be set to register a layer"}
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.configTypes[name]; ok {
+		return &ConfigError{Name: fmt.Sprintf("layer type %q", name), Reason: "already registered"}
+	}
+	if existing, ok := registry.configNames[typ]; ok {
+		return &ConfigError{Name: fmt.Sprintf("layer type %q", name), Reason: fmt.Sprintf("%v is already registered as %q", typ, existing)}
+	}
+	registry.configTypes[name] = typ
+	registry.configNames[typ] = name
+	return nil
+}
+
+// ConfigTypes returns the names of the registered layer config types.
+func ConfigTypes() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	names := []string{}
+	for name := range registry.configTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RegisterActivation registers an activation function type by name so that it can be encoded and
+// decoded, the registered activation is cloned when decoded so it should have no parameters.
+func RegisterActivation(name string, fn ActivationFn) error {
+	return registerActivation(name, fn, activationCodec{
+		encode: func(ActivationFn) *EncodedActivation { return &EncodedActivation{Type: name} },
+		decode: func(*EncodedActivation) ActivationFn { return fn.Clone() },
+	})
+}
+
+func registerActivation(name string, fn ActivationFn, codec activationCodec) error {
+	if name == "" || fn == nil {
+		return &ConfigError{Name: fmt.Sprintf("activation type %q", name), Reason: "name and activation must be set to register an activation"}
+	}
+	typ := reflect.TypeOf(fn)
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.activations[name]; ok {
+		return &ConfigError{Name: fmt.Sprintf("activation type %q", name), Reason: "already registered"}
+	}
+	if existing, ok := registry.activationNames[typ]; ok {
+		return &ConfigError{Name: fmt.Sprintf("activation type %q", name), Reason: fmt.Sprintf("%v is already registered as %q", typ, existing)}
+	}
+	registry.activations[name] = codec
+	registry.activationNames[typ] = name
+	return nil
+}
+
+// ActivationTypes returns the names of the registered activation types.
+func ActivationTypes() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	names := []string{}
+	for name := range registry.activations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// configType returns the type of a config, pointers to configs are registered as the config type.
+func configType(config Config) reflect.Type {
+	typ := reflect.TypeOf(config)
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
@@ -11,17 +11,28 @@ import (
 type Reshape struct {
 	// To shape
 	// required
-	To t.Shape
+	To t.Shape `json:"to"`
 }
 
 // ApplyDefaults to the flatten layer.
 func (r Reshape) ApplyDefaults() Config { return r }
 
+// InferShape returns the output shape for the input shape.
+func (r Reshape) InferShape(input t.Shape) (Config, t.Shape, error) {
+	if input == nil {
+		return r, nil, nil
+	}
+	if len(input) < 1 || product(input[1:]) != product(r.To) {
+		return nil, nil, &ShapeError{Name: "reshape", Expected: fmt.Sprintf("(batch, x...) with %d elements per batch to reshape to %v", product(r.To), r.To), Actual: input}
+	}
+	return r, append(t.Shape{input[0]}, r.To...), nil
+}
+
 // Compile the layer.
-func (r Reshape) Compile(graph *g.ExprGraph, opts ...CompileOpt) Layer {
+func (r Reshape) Compile(graph *g.ExprGraph, opts ...CompileOpt) (Layer, error) {
 	rshp := newReshape(&r)
 	rshp.graph = graph
-	return rshp
+	return rshp, nil
 }
 
 // Clone the config.
@@ -32,7 +43,7 @@ func (r Reshape) Clone() Config {
 // Validate the config.
 func (r Reshape) Validate() error {
 	if len(r.To) == 0 {
-		return fmt.Errorf("Shape must be set on reshape")
+		return &ConfigError{Name: "reshape", Reason: "to shape must be set"}
 	}
 	return nil
 }
@@ -48,6 +59,9 @@ func newReshape(config *Reshape) *reshape {
 
 // Fwd is a forward pass through the layer.
 func (r *reshape) Fwd(x *g.Node) (*g.Node, error) {
+	if len(x.Shape()) < 1 {
+		return nil, &ShapeError{Name: "reshape", Expected: "(batch, x...)", Actual: x.Shape()}
+	}
 	batch := []int{x.Shape()[0]}
 	newShape := append(batch, r.To...)
 	return g.Reshape(x, newShape)
@@ -60,7 +74,8 @@ func (r *reshape) Learnables() g.Nodes {
 
 // Clone the layer.
 func (r *reshape) Clone() Layer {
-	return &reshape{Reshape: r.Reshape.Clone().(*Reshape)}
+	configCloned := r.Reshape.Clone().(Reshape)
+	return &reshape{Reshape: &configCloned, graph: r.graph}
 }
 
 // Graph returns the graph for this layer.
@@ -0,0 +1,290 @@
+package layer
+
+import (
+	"fmt"
+
+	"github.com/aunum/log"
+
+	g "gorgonia.org/gorgonia"
+	t "gorgonia.org/tensor"
+)
+
+// SeparableConv2D is a depthwise separable 2D convolution, a depthwise convolution followed by a
+// pointwise 1x1 convolution which mixes the channels.
+type SeparableConv2D struct {
+	// Input channels.
+	// Inferred from the input shape if not set.
+	Input int `json:"input,omitempty"`
+
+	// Output channels.
+	// required
+	Output int `json:"output"`
+
+	// Multiplier is the number of depthwise output channels for each input channel.
+	// Defaults to 1
+	Multiplier int `json:"multiplier,omitempty"`
+
+	// Height of the depthwise filter.
+	// required
+	Height int `json:"height"`
+
+	// Width of the depthwise filter.
+	// required
+	Width int `json:"width"`
+
+	// Name of the layer.
+	Name string `json:"name,omitempty"`
+
+	// Activation function for the layer.
+	// Defaults to ReLU
+	Activation ActivationFn `json:"-"`
+
+	// Pad of the depthwise convolution.
+	// Defaults to (1, 1)
+	Pad []int `json:"pad,omitempty"`
+
+	// Stride of the depthwise convolution.
+	// Defaults to (1, 1)
+	Stride []int `json:"stride,omitempty"`
+
+	// Dilation of the depthwise convolution.
+	// Defaults to (1, 1)
+	Dilation []int `json:"dilation,omitempty"`
+
+	// Init function for the depthwise kernel.
+	// Defaults to GlorotU(1)
+	Init g.InitWFn `json:"-"`
+
+	// PointwiseInit is the init function for the pointwise kernel.
+	// Defaults to GlorotU(1)
+	PointwiseInit g.InitWFn `json:"-"`
+
+	// Bias adds a learnable bias for each output channel.
+	// Defaults to false.
+	Bias bool `json:"bias,omitempty"`
+
+	// BiasInit is the init function for the bias.
+	// Defaults to Zeroes
+	BiasInit g.InitWFn `json:"-"`
+}
+
+// Compile the config into a layer.
+func (c SeparableConv2D) Compile(graph *g.ExprGraph, opts ...CompileOpt) (Layer, error) {
+	cnv := newSeparableConv2D(&c)
+	for _, opt := range opts {
+		if err := opt(cnv); err != nil {
+			return nil, err
+		}
+	}
+	depthwiseName := fmt.Sprintf("%s-depthwise", c.Name)
+	pointwiseName := fmt.Sprintf("%s-pointwise", c.Name)
+	if cnv.shared != nil {
+		cnv.depthwise = g.NewTensor(graph, cnv.dtype, 4, g.WithShape(cnv.depthwiseShape...), g.WithName(depthwiseName), g.WithValue(cnv.shared.depthwise.Value()))
+		cnv.pointwise = g.NewTensor(graph, cnv.dtype, 4, g.WithShape(cnv.pointwiseShape...), g.WithName(pointwiseName), g.WithValue(cnv.shared.pointwise.Value()))
+		if c.Bias {
+			cnv.bias = g.NewTensor(graph, cnv.dtype, 4, g.WithShape(1, c.Output, 1, 1), g.WithName(fmt.Sprintf("%s-bias", c.Name)), g.WithValue(cnv.shared.bias.Value()))
+		}
+		return cnv, nil
+	}
+	cnv.depthwise = g.NewTensor(graph, cnv.dtype, 4, g.WithShape(cnv.depthwiseShape...), g.WithInit(c.Init), g.WithName(depthwiseName))
+	cnv.pointwise = g.NewTensor(graph, cnv.dtype, 4, g.WithShape(cnv.pointwiseShape...), g.WithInit(c.PointwiseInit), g.WithName(pointwiseName))
+	if c.Bias {
+		cnv.bias = g.NewTensor(graph, cnv.dtype, 4, g.WithShape(1, c.Output, 1, 1), g.WithInit(c.BiasInit), g.WithName(fmt.Sprintf("%s-bias", c.Name)))
+	}
+	return cnv, nil
+}
+
+// Validate the config.
+func (c SeparableConv2D) Validate() error {
+	if c.Output == 0 {
+		return &ConfigError{Name: fmt.Sprintf("separable conv2d %q", c.Name), Reason: "output must be set"}
+	}
+	if c.Multiplier < 0 {
+		return &ConfigError{Name: fmt.Sprintf("separable conv2d %q", c.Name), Reason: fmt.Sprintf("multiplier must not be negative, got %d", c.Multiplier)}
+	}
+	if c.Width == 0 {
+		return &ConfigError{Name: fmt.Sprintf("separable conv2d %q", c.Name), Reason: "width must be set"}
+	}
+	if c.Height == 0 {
+		return &ConfigError{Name: fmt.Sprintf("separable conv2d %q", c.Name), Reason: "height must be set"}
+	}
+	return nil
+}
+
+// ApplyDefaults to the config.
+func (c SeparableConv2D) ApplyDefaults() Config {
+	if c.Multiplier == 0 {
+		c.Multiplier = 1
+	}
+	if c.Activation == nil {
+		c.Activation = ReLU
+	}
+	if len(c.Pad) == 0 {
+		c.Pad = []int{1, 1}
+	}
+	if len(c.Stride) == 0 {
+		c.Stride = []int{1, 1}
+	}
+	if len(c.Dilation) == 0 {
+		c.Dilation = []int{1, 1}
+	}
+	if c.Init == nil {
+		c.Init = g.GlorotU(1)
+	}
+	if c.PointwiseInit == nil {
+		c.PointwiseInit = g.GlorotU(1)
+	}
+	if c.BiasInit == nil {
+		c.BiasInit = g.Zeroes()
+	}
+	return c
+}
+
+// InferShape infers the input channels from the input shape and returns the output shape.
+func (c SeparableConv2D) InferShape(input t.Shape) (Config, t.Shape, error) {
+	c = c.ApplyDefaults().(SeparableConv2D)
+	name := fmt.Sprintf("separable conv2d %q", c.Name)
+	if input == nil {
+		if c.Input == 0 {
+			return nil, nil, &ConfigError{Name: name, Reason: "input must be set as it cannot be inferred"}
+		}
+		return c, nil, nil
+	}
+	if c.Input == 0 && len(input) == 4 {
+		c.Input = input[1]
+	}
+	output, err := inferConvShape(name, input, c.Input, c.Output, c.Height, c.Width, c.Pad, c.Stride, c.Dilation)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, output, nil
+}
+
+// WithNamePrefix returns the config with the prefix added to its name.
+func (c SeparableConv2D) WithNamePrefix(prefix string) Config {
+	c.Name = prefixName(prefix, c.Name)
+	return c
+}
+
+// Clone the config.
+func (c SeparableConv2D) Clone() Config {
+	return SeparableConv2D{
+		Input:         c.Input,
+		Output:        c.Output,
+		Multiplier:    c.Multiplier,
+		Height:        c.Height,
+		Width:         c.Width,
+		Name:          c.Name,
+		Activation:    c.Activation.Clone(),
+		Pad:           c.Pad,
+		Stride:        c.Stride,
+		Dilation:      c.Dilation,
+		Init:          c.Init,
+		PointwiseInit: c.PointwiseInit,
+		Bias:          c.Bias,
+		BiasInit:      c.BiasInit,
+	}
+}
+
+// separableConv2D is a two dimensional depthwise separable convolution layer.
+type separableConv2D struct {
+	*SeparableConv2D
+
+	dtype          t.Dtype
+	depthwiseShape t.Shape
+	pointwiseShape t.Shape
+	kernelShape    t.Shape
+	depthwise      *g.Node
+	pointwise      *g.Node
+	bias           *g.Node
+	shared         *separableConv2D
+	isBatched      bool
+}
+
+func newSeparableConv2D(config *SeparableConv2D) *separableConv2D {
+	return &separableConv2D{
+		SeparableConv2D: config,
+		dtype:           t.Float32,
+		kernelShape:     []int{config.Height, config.Width},
+		depthwiseShape:  []int{config.Input * config.Multiplier, 1, config.Height, config.Width},
+		pointwiseShape:  []int{config.Output, config.Input * config.Multiplier, 1, 1},
+	}
+}
+
+// SetSharedLearnables sets the layer to share the learnables of another separable conv2d layer.
+func (c *separableConv2D) SetSharedLearnables(shared Layer) error {
+	s, ok := shared.(*separableConv2D)
+	if !ok {
+		return &ConfigError{Name: fmt.Sprintf("separable conv2d %q", c.Name), Reason: fmt.Sprintf("cannot share learnables with %T", shared)}
+	}
+	c.shared = s
+	return nil
+}
+
+// SetBatched sets whether the layer is compiled as a batch.
+func (c *separableConv2D) SetBatched(batched bool) {
+	c.isBatched = batched
+}
+
+// SetDType sets the data type of the layer.
+func (c *separableConv2D) SetDType(dtype t.Dtype) {
+	c.dtype = dtype
+}
+
+// Fwd is a forward pass through the layer.
+func (c *separableConv2D) Fwd(x *g.Node) (*g.Node, error) {
+	filter, err := depthwiseFilter(c.depthwise, c.Input, c.Multiplier, c.Name)
+	if err != nil {
+		return nil, err
+	}
+	n, err := g.Conv2d(x, filter, c.kernelShape, c.Pad, c.Stride, c.Dilation)
+	if err != nil {
+		return nil, err
+	}
+	n, err = g.Conv2d(n, c.pointwise, t.Shape{1, 1}, []int{0, 0}, []int{1, 1}, []int{1, 1})
+	if err != nil {
+		return nil, err
+	}
+	if c.bias != nil {
+		n, err = addChannelBias(n, c.bias, c.Name, c.dtype)
+		if err != nil {
+			return nil, err
+		}
+	}
+	n, err = c.Activation.Fwd(n)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("separable conv2d name: %q output shape: %v", c.Name, n.Shape())
+	return n, nil
+}
+
+// Learnables returns all learnable nodes within this layer.
+func (c *separableConv2D) Learnables() g.Nodes {
+	if c.bias != nil {
+		return g.Nodes{c.depthwise, c.pointwise, c.bias}
+	}
+	return g.Nodes{c.depthwise, c.pointwise}
+}
+
+// Clone the layer without any nodes. (nodes cannot be shared)
+func (c *separableConv2D) Clone() Layer {
+	configCloned := c.SeparableConv2D.Clone().(SeparableConv2D)
+	return &separableConv2D{
+		SeparableConv2D: &configCloned,
+		dtype:           c.dtype,
+		depthwiseShape:  c.depthwiseShape,
+		pointwiseShape:  c.pointwiseShape,
+		kernelShape:     c.kernelShape,
+		shared:          c.shared,
+		isBatched:       c.isBatched,
+	}
+}
+
+// Graph returns the graph for this layer.
+func (c *separableConv2D) Graph() *g.ExprGraph {
+	if c.depthwise == nil {
+		return nil
+	}
+	return c.depthwise.Graph()
+}
@@ -0,0 +1,72 @@
+package layer
+
+import (
+	"fmt"
+
+	t "gorgonia.org/tensor"
+)
+
+// ShapeInferer is a layer config which can infer its input size from the shape of its input and
+// compute its output shape.
+type ShapeInferer interface {
+	// InferShape returns the config with any unset input sizes inferred from the input shape, along with
+	// the output shape of the layer. The input shape includes the batch dimension and is nil if unknown,
+	// in which case a nil output shape may be returned.
+	InferShape(input t.Shape) (Config, t.Shape, error)
+}
+
+// InferShapes propagates the input shape through the chain, inferring any unset layer input sizes.
+// Propagation stops at any layer which is not a ShapeInferer. The output shape of the chain is
+// returned, or nil if it cannot be determined.
+func (c *Chain) InferShapes(input t.Shape) (t.Shape, error) {
+	shape := input.Clone()
+	for i, layer := range c.Layers {
+		inferer, ok := layer.(ShapeInferer)
+		if !ok {
+			shape = nil
+			continue
+		}
+		config, output, err := inferer.InferShape(shape)
+		if err != nil {
+			return nil, fmt.Errorf("layer %d %T: %w", i, layer, err)
+		}
+		c.Layers[i] = config
+		shape = output
+	}
+	return shape, nil
+}
+
+// normalizeBatch ensures a vector input is represented as a batch of one.
+func normalizeBatch(input t.Shape) t.Shape {
+	if len(input) == 1 {
+		return t.Shape{1, input[0]}
+	}
+	return input
+}
+
+// inferConvShape returns the output shape of a convolution with the given output channels for an input
+// shape of (batch, channels, height, width).
+func inferConvShape(name string, input t.Shape, channels, output, height, width int, pad, stride, dilation []int) (t.Shape, error) {
+	if len(input) != 4 || channels != input[1] {
+		return nil, &ShapeError{Name: name, Expected: fmt.Sprintf("(batch, %d, height, width)", channels), Actual: input}
+	}
+	h := convOutput(input[2], height, pad[0], stride[0], dilation[0])
+	w := convOutput(input[3], width, pad[1], stride[1], dilation[1])
+	if h <= 0 || w <= 0 {
+		return nil, &ShapeError{Name: name, Expected: fmt.Sprintf("height and width of at least the filter (%d, %d)", height, width), Actual: input}
+	}
+	return t.Shape{input[0], output, h, w}, nil
+}
+
+// convOutput computes the output size of a convolution or pooling along a dimension.
+func convOutput(size, kernel, pad, stride, dilation int) int {
+	return (size+2*pad-(dilation*(kernel-1)+1))/stride + 1
+}
+
+func product(s []int) int {
+	p := 1
+	for _, d := range s {
+		p *= d
+	}
+	return p
+}
@@ -0,0 +1,46 @@
+package layer_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/aunum/goro/pkg/v1/layer"
+
+	"github.com/stretchr/testify/require"
+	t "gorgonia.org/tensor"
+)
+
+func TestInferShapes(tt *testing.T) {
+	chain, err := NewChain(
+		Conv2D{Output: 32, Width: 3, Height: 3},
+		MaxPooling2D{},
+		Conv2D{Output: 64, Width: 3, Height: 3},
+		MaxPooling2D{},
+		Conv2D{Output: 128, Width: 3, Height: 3},
+		MaxPooling2D{},
+		Flatten{},
+		FC{Output: 100},
+		Dropout{},
+		FC{Output: 10, Activation: Softmax},
+	)
+	require.NoError(tt, err)
+	output, err := chain.InferShapes(t.Shape{1, 1, 28, 28})
+	require.NoError(tt, err)
+	require.Equal(tt, t.Shape{1, 10}, output)
+	require.Equal(tt, 1, chain.Layers[0].(Conv2D).Input)
+	require.Equal(tt, 64, chain.Layers[4].(Conv2D).Input)
+	require.Equal(tt, 128*3*3, chain.Layers[7].(FC).Input)
+	require.Equal(tt, 100, chain.Layers[9].(FC).Input)
+
+	chain, err = NewChain(
+		FC{Input: 4, Output: 10, Name: "w0"},
+		FC{Input: 12, Output: 2, Name: "w1"},
+	)
+	require.NoError(tt, err)
+	_, err = chain.InferShapes(t.Shape{1, 4})
+	var shapeErr *ShapeError
+	require.True(tt, errors.As(err, &shapeErr))
+	require.Equal(tt, t.Shape{1, 10}, shapeErr.Actual)
+	require.Contains(tt, err.Error(), `"w1"`)
+	require.Contains(tt, err.Error(), "(batch, 12)")
+}
@@ -0,0 +1,392 @@
+package layer
+
+import (
+	"fmt"
+
+	"github.com/aunum/log"
+
+	g "gorgonia.org/gorgonia"
+	t "gorgonia.org/tensor"
+)
+
+// SimpleRNN is a fully connected recurrent layer over a sequence of shape (batch, time, input). The state
+// of each timestep is activation(x·W + h·U + b) of the input of the timestep x and the state of the
+// previous timestep h, which starts at zero.
+type SimpleRNN struct {
+	// Input is the number of units of each timestep of the input.
+	// Inferred from the input shape if not set.
+	Input int `json:"input,omitempty"`
+
+	// Output is the number of units of the state.
+	// required
+	Output int `json:"output"`
+
+	// Name of the layer.
+	Name string `json:"name,omitempty"`
+
+	// Activation is the activation function of the state.
+	// Defaults to Tanh
+	Activation ActivationFn `json:"-"`
+
+	// Init is the init function of the input weights.
+	// Defaults to GlorotN(1)
+	Init g.InitWFn `json:"-"`
+
+	// RecurrentInit is the init function of the recurrent weights.
+	// Defaults to GlorotN(1)
+	RecurrentInit g.InitWFn `json:"-"`
+
+	// NoBias indicates to not use a bias with the layer.
+	NoBias bool `json:"noBias,omitempty"`
+
+	// BiasInit is the init function for the bias.
+	// Defaults to Zeroes
+	BiasInit g.InitWFn `json:"-"`
+
+	// Sequences indicates to output the state of every timestep as a sequence of shape
+	// (batch, time, output), rather than the state of the last timestep of shape (batch, output).
+	Sequences bool `json:"sequences,omitempty"`
+}
+
+// Validate the config.
+func (r SimpleRNN) Validate() error {
+	if r.Output == 0 {
+		return &ConfigError{Name: fmt.Sprintf("simple rnn %q", r.Name), Reason: "output must be set"}
+	}
+	return nil
+}
+
+// ApplyDefaults to the config.
+func (r SimpleRNN) ApplyDefaults() Config {
+	if r.Activation == nil {
+		r.Activation = Tanh
+	}
+	if r.Init == nil {
+		r.Init = g.GlorotN(1)
+	}
+	if r.RecurrentInit == nil {
+		r.RecurrentInit = g.GlorotN(1)
+	}
+	if r.BiasInit == nil {
+		r.BiasInit = g.Zeroes()
+	}
+	return r
+}
+
+// InferShape infers the input size from the input shape and returns the output shape.
+func (r SimpleRNN) InferShape(input t.Shape) (Config, t.Shape, error) {
+	if input == nil {
+		if r.Input == 0 {
+			return nil, nil, &ConfigError{Name: fmt.Sprintf("simple rnn %q", r.Name), Reason: "input must be set as it cannot be inferred"}
+		}
+		return r, nil, nil
+	}
+	if len(input) != 3 || (r.Input != 0 && r.Input != input[2]) {
+		return nil, nil, &ShapeError{Name: fmt.Sprintf("simple rnn %q", r.Name), Expected: fmt.Sprintf("(batch, time, %d)", r.Input), Actual: input}
+	}
+	r.Input = input[2]
+	if r.Sequences {
+		return r, t.Shape{input[0], input[1], r.Output}, nil
+	}
+	return r, t.Shape{input[0], r.Output}, nil
+}
+
+// Compile the layer into the graph.
+func (r SimpleRNN) Compile(graph *g.ExprGraph, opts ...CompileOpt) (Layer, error) {
+	r = r.ApplyDefaults().(SimpleRNN)
+	rnn := newSimpleRNN(&r)
+	for _, opt := range opts {
+		if err := opt(rnn); err != nil {
+			return nil, err
+		}
+	}
+	recurrentName := fmt.Sprintf("%s-recurrent", r.Name)
+	biasName := fmt.Sprintf("%s-bias", r.Name)
+	if rnn.shared != nil {
+		rnn.weights = g.NewMatrix(graph, rnn.dtype, g.WithShape(r.Input, r.Output), g.WithName(r.Name), g.WithValue(rnn.shared.weights.Value()))
+		rnn.recurrent = g.NewMatrix(graph, rnn.dtype, g.WithShape(r.Output, r.Output), g.WithName(recurrentName), g.WithValue(rnn.shared.recurrent.Value()))
+		if !r.NoBias {
+			rnn.bias = g.NewMatrix(graph, rnn.dtype, g.WithShape(1, r.Output), g.WithName(biasName), g.WithValue(rnn.shared.bias.Value()))
+		}
+		return rnn, nil
+	}
+	rnn.weights = g.NewMatrix(graph, rnn.dtype, g.WithShape(r.Input, r.Output), g.WithInit(r.Init), g.WithName(r.Name))
+	rnn.recurrent = g.NewMatrix(graph, rnn.dtype, g.WithShape(r.Output, r.Output), g.WithInit(r.RecurrentInit), g.WithName(recurrentName))
+	if !r.NoBias {
+		rnn.bias = g.NewMatrix(graph, rnn.dtype, g.WithShape(1, r.Output), g.WithInit(r.BiasInit), g.WithName(biasName))
+	}
+	return rnn, nil
+}
+
+// WithNamePrefix returns the config with the prefix added to its name.
+func (r SimpleRNN) WithNamePrefix(prefix string) Config {
+	r.Name = prefixName(prefix, r.Name)
+	return r
+}
+
+// Clone the config.
+func (r SimpleRNN) Clone() Config {
+	var activation ActivationFn
+	if r.Activation != nil {
+		activation = r.Activation.Clone()
+	}
+	return SimpleRNN{
+		Input:         r.Input,
+		Output:        r.Output,
+		Name:          r.Name,
+		Activation:    activation,
+		Init:          r.Init,
+		RecurrentInit: r.RecurrentInit,
+		NoBias:        r.NoBias,
+		BiasInit:      r.BiasInit,
+		Sequences:     r.Sequences,
+	}
+}
+
+type simpleRNN struct {
+	*SimpleRNN
+
+	weights   *g.Node
+	recurrent *g.Node
+	bias      *g.Node
+	dtype     t.Dtype
+	shared    *simpleRNN
+	mask      *g.Node
+}
+
+func newSimpleRNN(config *SimpleRNN) *simpleRNN {
+	return &simpleRNN{
+		SimpleRNN: config,
+		dtype:     t.Float32,
+	}
+}
+
+// SetSharedLearnables sets the layer to share the learnables of another simple rnn layer.
+func (r *simpleRNN) SetSharedLearnables(shared Layer) error {
+	s, ok := shared.(*simpleRNN)
+	if !ok {
+		return &ConfigError{Name: fmt.Sprintf("simple rnn %q", r.Name), Reason: fmt.Sprintf("cannot share learnables with %T", shared)}
+	}
+	r.shared = s
+	return nil
+}
+
+// SetDType sets the data type of the layer.
+func (r *simpleRNN) SetDType(dtype t.Dtype) {
+	r.dtype = dtype
+}
+
+// SetMask sets the mask of the input, the state is kept over masked timesteps and the masked timesteps
+// of a sequence output are zeroed.
+func (r *simpleRNN) SetMask(mask *g.Node) {
+	r.mask = mask
+}
+
+// Mask of the output, which is the mask of the input if the output is a sequence.
+func (r *simpleRNN) Mask() *g.Node {
+	if !r.Sequences {
+		return nil
+	}
+	return r.mask
+}
+
+// Fwd is a forward pass through the layer.
+func (r *simpleRNN) Fwd(x *g.Node) (*g.Node, error) {
+	s := x.Shape().Clone()
+	if len(s) != 3 {
+		return nil, &ShapeError{Name: fmt.Sprintf("simple rnn %q", r.Name), Expected: fmt.Sprintf("(batch, time, %d)", r.Input), Actual: s}
+	}
+	batch, steps := s[0], s[1]
+
+	// the inputs of every timestep are projected at once and then split into timesteps.
+	n, err := g.Reshape(x, t.Shape{batch * steps, s[2]})
+	if err != nil {
+		return nil, err
+	}
+	n, err = g.Mul(n, r.weights)
+	if err != nil {
+		return nil, err
+	}
+	if r.bias != nil {
+		if batch*steps == 1 {
+			n, err = g.Add(n, r.bias)
+		} else {
+			n, err = g.BroadcastAdd(n, r.bias, nil, []byte{0})
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	inputs, err := splitSteps(n, batch, steps)
+	if err != nil {
+		return nil, err
+	}
+	var masks g.Nodes
+	if r.mask != nil {
+		m, err := g.Reshape(r.mask, t.Shape{batch * steps, 1})
+		if err != nil {
+			return nil, err
+		}
+		masks, err = splitSteps(m, batch, steps)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var h *g.Node
+	states := make(g.Nodes, steps)
+	for i, input := range inputs {
+		next := input
+		if h != nil {
+			// the recurrent projection is the first operand so that the timestep input is not added to in place.
+			next, err = g.Mul(h, r.recurrent)
+			if err != nil {
+				return nil, err
+			}
+			next, err = g.Add(next, input)
+			if err != nil {
+				return nil, err
+			}
+		}
+		next, err = r.Activation.Fwd(next)
+		if err != nil {
+			return nil, err
+		}
+		if masks != nil {
+			next, err = keepState(h, next, masks[i])
+			if err != nil {
+				return nil, err
+			}
+		}
+		h = next
+		states[i] = h
+	}
+	if !r.Sequences {
+		log.Debugf("simple rnn output shape: %v", h.Shape())
+		return h, nil
+	}
+
+	// the states are stacked time major and transposed to a sequence.
+	for i, state := range states {
+		states[i], err = g.Reshape(state, t.Shape{1, batch * r.Output})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if steps == 1 {
+		n = states[0]
+	} else {
+		n, err = g.Concat(0, states...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	n, err = g.Reshape(n, t.Shape{steps, batch, r.Output})
+	if err != nil {
+		return nil, err
+	}
+	n, err = g.Transpose(n, 1, 0, 2)
+	if err != nil {
+		return nil, err
+	}
+	n, err = g.Reshape(n, t.Shape{batch, steps, r.Output})
+	if err != nil {
+		return nil, err
+	}
+	if r.mask != nil {
+		n, err = ApplyMask(n, r.mask)
+		if err != nil {
+			return nil, err
+		}
+	}
+	log.Debugf("simple rnn output shape: %v", n.Shape())
+	return n, nil
+}
+
+// Learnables returns all learnable nodes within this layer.
+func (r *simpleRNN) Learnables() g.Nodes {
+	if r.bias != nil {
+		return g.Nodes{r.weights, r.recurrent, r.bias}
+	}
+	return g.Nodes{r.weights, r.recurrent}
+}
+
+// Clone the layer without any nodes. (nodes cannot be shared)
+func (r *simpleRNN) Clone() Layer {
+	configCloned := r.SimpleRNN.Clone().(SimpleRNN)
+	return &simpleRNN{
+		SimpleRNN: &configCloned,
+		dtype:     r.dtype,
+		shared:    r.shared,
+	}
+}
+
+// Graph returns the graph for this layer.
+func (r *simpleRNN) Graph() *g.ExprGraph {
+	if r.weights == nil {
+		return nil
+	}
+	return r.weights.Graph()
+}
+
+// splitSteps splits the batch major rows of shape (batch*time, size) into the rows of each timestep of
+// shape (batch, size).
+func splitSteps(x *g.Node, batch, steps int) (g.Nodes, error) {
+	size := x.Shape()[1]
+	if steps == 1 {
+		n, err := g.Reshape(x, t.Shape{batch, size})
+		if err != nil {
+			return nil, err
+		}
+		return g.Nodes{n}, nil
+	}
+	n, err := g.Reshape(x, t.Shape{batch, steps, size})
+	if err != nil {
+		return nil, err
+	}
+	n, err = g.Transpose(n, 1, 0, 2)
+	if err != nil {
+		return nil, err
+	}
+	n, err = g.Reshape(n, t.Shape{steps, batch * size})
+	if err != nil {
+		return nil, err
+	}
+	rows := make(g.Nodes, steps)
+	for i := range rows {
+		rows[i], err = g.Slice(n, g.S(i))
+		if err != nil {
+			return nil, err
+		}
+		rows[i], err = g.Reshape(rows[i], t.Shape{batch, size})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+// keepState returns the next state for the unmasked examples of the mask of shape (batch, 1) and the
+// previous state, zero if nil, for the masked examples.
+func keepState(previous, next, mask *g.Node) (*g.Node, error) {
+	var err error
+	diff := next
+	if previous != nil {
+		diff, err = g.Sub(next, previous)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if next.Shape()[1] == 1 {
+		diff, err = g.HadamardProd(diff, mask)
+	} else {
+		diff, err = g.BroadcastHadamardProd(diff, mask, nil, []byte{1})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if previous == nil {
+		return diff, nil
+	}
+	return g.Add(previous, diff)
+}
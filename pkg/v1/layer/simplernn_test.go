@@ -0,0 +1,116 @@
+package layer_test
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/aunum/goro/pkg/v1/layer"
+
+	"github.com/stretchr/testify/require"
+	g "gorgonia.org/gorgonia"
+	"gorgonia.org/tensor"
+)
+
+func TestSimpleRNN(tt *testing.T) {
+	input := []float32{1, 2, 3, 4, 5, 6, -1, 0, 0, 2, -3, 1}
+	for _, test := range []struct {
+		sequences bool
+		mask      []float32
+		shape     tensor.Shape
+	}{
+		{shape: tensor.Shape{2, 2}},
+		{sequences: true, shape: tensor.Shape{2, 3, 2}},
+		{mask: []float32{1, 0, 1, 0, 1, 1}, shape: tensor.Shape{2, 2}},
+		{sequences: true, mask: []float32{1, 0, 1, 0, 1, 1}, shape: tensor.Shape{2, 3, 2}},
+	} {
+		chain, err := NewChain(SimpleRNN{Output: 2, Name: "rnn", Init: g.ValuesOf(float32(0.1)), RecurrentInit: g.ValuesOf(float32(0.2)), BiasInit: g.ValuesOf(float32(0.3)), Sequences: test.sequences})
+		require.NoError(tt, err)
+		shape, err := chain.InferShapes(tensor.Shape{2, 3, 2})
+		require.NoError(tt, err)
+		require.Equal(tt, test.shape, shape)
+
+		graph := g.NewGraph()
+		require.NoError(tt, chain.Compile(graph, WithLayerOpts(AsBatch())))
+		x := g.NewTensor(graph, g.Float32, 3, g.WithShape(2, 3, 2), g.WithValue(tensor.New(tensor.WithShape(2, 3, 2), tensor.WithBacking(input))))
+		if test.mask != nil {
+			chain.SetMask(g.NewMatrix(graph, g.Float32, g.WithShape(2, 3), g.WithValue(tensor.New(tensor.WithShape(2, 3), tensor.WithBacking(test.mask)))))
+		}
+		y, err := chain.Fwd(x)
+		require.NoError(tt, err)
+		require.Equal(tt, shape, y.Shape())
+		require.Equal(tt, test.sequences && test.mask != nil, chain.Mask() != nil)
+		vm := g.NewTapeMachine(graph)
+		require.NoError(tt, vm.RunAll())
+		expected := simpleRNN(input, test.mask, 2, 3, 2, 2, 0.1, 0.2, 0.3, test.sequences)
+		require.InDeltaSlice(tt, expected, y.Value().Data(), 1e-5)
+	}
+}
+
+func TestBidirectionalSimpleRNN(tt *testing.T) {
+	input := []float32{1, 2, 3, 4, 5, 6, -1, 0, 0, 2, -3, 1}
+	reversed := []float32{5, 6, 3, 4, 1, 2, -3, 1, 0, 2, -1, 0}
+	rnn := SimpleRNN{Output: 2, Name: "rnn", Init: g.ValuesOf(float32(0.1)), RecurrentInit: g.ValuesOf(float32(0.2)), BiasInit: g.ValuesOf(float32(0.3))}
+	chain, err := NewChain(Bidirectional{Layer: rnn})
+	require.NoError(tt, err)
+	shape, err := chain.InferShapes(tensor.Shape{2, 3, 2})
+	require.NoError(tt, err)
+	require.Equal(tt, tensor.Shape{2, 4}, shape)
+
+	graph := g.NewGraph()
+	require.NoError(tt, chain.Compile(graph, WithLayerOpts(AsBatch())))
+	require.Len(tt, chain.Learnables(), 6)
+	x := g.NewTensor(graph, g.Float32, 3, g.WithShape(2, 3, 2), g.WithValue(tensor.New(tensor.WithShape(2, 3, 2), tensor.WithBacking(input))))
+	y, err := chain.Fwd(x)
+	require.NoError(tt, err)
+	vm := g.NewTapeMachine(graph)
+	require.NoError(tt, vm.RunAll())
+
+	// the backward layer starts with the same learnables, yet sees the timesteps in reverse.
+	forward := simpleRNN(input, nil, 2, 3, 2, 2, 0.1, 0.2, 0.3, false)
+	backward := simpleRNN(reversed, nil, 2, 3, 2, 2, 0.1, 0.2, 0.3, false)
+	data := y.Value().Data().([]float32)
+	for i := 0; i < 2; i++ {
+		require.InDeltaSlice(tt, forward[i*2:i*2+2], data[i*4:i*4+2], 1e-5)
+		require.InDeltaSlice(tt, backward[i*2:i*2+2], data[i*4+2:i*4+4], 1e-5)
+		require.NotEqual(tt, data[i*4:i*4+2], data[i*4+2:i*4+4])
+	}
+}
+
+// simpleRNN computes the output of a tanh simple rnn whose weights, recurrent weights and bias are all
+// of a single value.
+func simpleRNN(x, mask []float32, batch, steps, input, output int, w, u, b float64, sequences bool) []float64 {
+	out := []float64{}
+	for i := 0; i < batch; i++ {
+		h := make([]float64, output)
+		for s := 0; s < steps; s++ {
+			masked := mask != nil && mask[i*steps+s] == 0
+			if !masked {
+				var xw, hu float64
+				for _, v := range x[(i*steps+s)*input : (i*steps+s+1)*input] {
+					xw += float64(v) * w
+				}
+				for _, v := range h {
+					hu += v * u
+				}
+				next := make([]float64, output)
+				for j := range next {
+					next[j] = math.Tanh(xw + hu + b)
+				}
+				h = next
+			}
+			if !sequences {
+				continue
+			}
+			for _, v := range h {
+				if masked {
+					v = 0
+				}
+				out = append(out, v)
+			}
+		}
+		if !sequences {
+			out = append(out, h...)
+		}
+	}
+	return out
+}
@@ -0,0 +1,191 @@
+package layer
+
+import (
+	"fmt"
+
+	"github.com/aunum/log"
+
+	g "gorgonia.org/gorgonia"
+	t "gorgonia.org/tensor"
+)
+
+// TimeDistributed applies a layer independently to each timestep of a sequence of shape
+// (batch, time, x...), with the learnables of the layer shared across timesteps.
+type TimeDistributed struct {
+	// Layer to apply to each timestep.
+	// required
+	Layer Config `json:"-"`
+}
+
+// Validate the config.
+func (d TimeDistributed) Validate() error {
+	if d.Layer == nil {
+		return &ConfigError{Name: "time distributed", Reason: "layer must be set"}
+	}
+	if err := d.Layer.Validate(); err != nil {
+		return fmt.Errorf("time distributed: %w", err)
+	}
+	return nil
+}
+
+// ApplyDefaults to the config.
+func (d TimeDistributed) ApplyDefaults() Config {
+	if d.Layer != nil {
+		d.Layer = d.Layer.ApplyDefaults()
+	}
+	return d
+}
+
+// InferShape infers the input of the layer from the shape of a timestep and returns the output shape.
+func (d TimeDistributed) InferShape(input t.Shape) (Config, t.Shape, error) {
+	inferer, ok := d.Layer.(ShapeInferer)
+	if !ok {
+		return d, nil, nil
+	}
+	if input == nil {
+		config, _, err := inferer.InferShape(nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("time distributed: %w", err)
+		}
+		d.Layer = config
+		return d, nil, nil
+	}
+	if len(input) < 3 {
+		return nil, nil, &ShapeError{Name: "time distributed", Expected: "(batch, time, x...)", Actual: input}
+	}
+	config, output, err := inferer.InferShape(append(t.Shape{input[0] * input[1]}, input[2:]...))
+	if err != nil {
+		return nil, nil, fmt.Errorf("time distributed: %w", err)
+	}
+	d.Layer = config
+	if output == nil {
+		return d, nil, nil
+	}
+	return d, append(t.Shape{input[0], input[1]}, output[1:]...), nil
+}
+
+// Compile the layer into the graph.
+func (d TimeDistributed) Compile(graph *g.ExprGraph, opts ...CompileOpt) (Layer, error) {
+	td := newTimeDistributed(&d)
+	for _, opt := range opts {
+		if err := opt(td); err != nil {
+			return nil, err
+		}
+	}
+	// the timesteps of every example are a batch to the layer.
+	layerOpts := []CompileOpt{AsBatch(), AsType(td.dtype)}
+	if td.shared != nil {
+		layerOpts = append(layerOpts, WithSharedLearnables(td.shared.layer))
+	}
+	l, err := d.Layer.Compile(graph, layerOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("time distributed: %w", err)
+	}
+	td.layer = l
+	return td, nil
+}
+
+// WithNamePrefix returns the config with the prefix added to the name of its layer.
+func (d TimeDistributed) WithNamePrefix(prefix string) Config {
+	if prefixer, ok := d.Layer.(NamePrefixer); ok {
+		d.Layer = prefixer.WithNamePrefix(prefix)
+	}
+	return d
+}
+
+// Clone the config.
+func (d TimeDistributed) Clone() Config {
+	if d.Layer == nil {
+		return TimeDistributed{}
+	}
+	return TimeDistributed{Layer: d.Layer.Clone()}
+}
+
+type timeDistributed struct {
+	*TimeDistributed
+	layer  Layer
+	dtype  t.Dtype
+	shared *timeDistributed
+	mask   *g.Node
+}
+
+func newTimeDistributed(config *TimeDistributed) *timeDistributed {
+	return &timeDistributed{
+		TimeDistributed: config,
+		dtype:           t.Float32,
+	}
+}
+
+// SetSharedLearnables sets the layer to share the learnables of another time distributed layer.
+func (d *timeDistributed) SetSharedLearnables(shared Layer) error {
+	s, ok := shared.(*timeDistributed)
+	if !ok {
+		return &ConfigError{Name: "time distributed", Reason: fmt.Sprintf("cannot share learnables with %T", shared)}
+	}
+	d.shared = s
+	return nil
+}
+
+// SetDType sets the data type of the layer.
+func (d *timeDistributed) SetDType(dtype t.Dtype) {
+	d.dtype = dtype
+}
+
+// SetMask sets the mask of the input, the masked timesteps of the output are zeroed.
+func (d *timeDistributed) SetMask(mask *g.Node) {
+	d.mask = mask
+}
+
+// Mask of the output, which is the mask of the input as the timesteps are kept.
+func (d *timeDistributed) Mask() *g.Node {
+	return d.mask
+}
+
+// Fwd is a forward pass through the layer.
+func (d *timeDistributed) Fwd(x *g.Node) (*g.Node, error) {
+	s := x.Shape()
+	if len(s) < 3 {
+		return nil, &ShapeError{Name: "time distributed", Expected: "(batch, time, x...)", Actual: s}
+	}
+	steps, err := g.Reshape(x, append(t.Shape{s[0] * s[1]}, s[2:]...))
+	if err != nil {
+		return nil, err
+	}
+	n, err := d.layer.Fwd(steps)
+	if err != nil {
+		return nil, fmt.Errorf("time distributed: %w", err)
+	}
+	n, err = g.Reshape(n, append(t.Shape{s[0], s[1]}, n.Shape()[1:]...))
+	if err != nil {
+		return nil, err
+	}
+	if d.mask != nil {
+		n, err = ApplyMask(n, d.mask)
+		if err != nil {
+			return nil, err
+		}
+	}
+	log.Debugf("time distributed output shape: %v", n.Shape())
+	return n, nil
+}
+
+// Learnables returns all learnable nodes within this layer.
+func (d *timeDistributed) Learnables() g.Nodes {
+	return d.layer.Learnables()
+}
+
+// Clone the layer without any nodes. (nodes cannot be shared)
+func (d *timeDistributed) Clone() Layer {
+	configCloned := d.TimeDistributed.Clone().(TimeDistributed)
+	return &timeDistributed{
+		TimeDistributed: &configCloned,
+		layer:           d.layer.Clone(),
+		dtype:           d.dtype,
+		shared:          d.shared,
+	}
+}
+
+// Graph returns the graph for this layer.
+func (d *timeDistributed) Graph() *g.ExprGraph {
+	return d.layer.Graph()
+}
@@ -0,0 +1,159 @@
+package model
+
+import (
+	"fmt"
+
+	g "gorgonia.org/gorgonia"
+)
+
+// Accumulation is how accumulated gradients are reduced before an optimizer step.
+type Accumulation int
+
+const (
+	// AccumulateMean averages the accumulated gradients.
+	AccumulateMean Accumulation = iota
+
+	// AccumulateSum sums the accumulated gradients.
+	AccumulateSum
+)
+
+// gradAccumulator accumulates gradients over a number of steps.
+type gradAccumulator struct {
+	steps  int
+	reduce Accumulation
+	count  int
+	grads  []g.Value
+}
+
+func newGradAccumulator(steps int, reduce Accumulation) *gradAccumulator {
+	return &gradAccumulator{
+		steps:  steps,
+		reduce: reduce,
+	}
+}
+
+// add the gradients to the accumulation. If the accumulation is complete the reduced gradients are
+// written back to the given gradients and true is returned.
+func (a *gradAccumulator) add(grads []g.ValueGrad) (bool, error) {
+	if a.grads != nil && len(a.grads) != len(grads) {
+		return false, fmt.Errorf("cannot accumulate %d gradients into %d", len(grads), len(a.grads))
+	}
+	if a.grads == nil {
+		a.grads = make([]g.Value, len(grads))
+	}
+	for i, vg := range grads {
+		grad, err := vg.Grad()
+		if err != nil {
+			return false, err
+		}
+		if a.grads[i] == nil {
+			a.grads[i], err = g.CloneValue(grad)
+			if err != nil {
+				return false, err
+			}
+			continue
+		}
+		if a.count == 0 {
+			err = copyValue(a.grads[i], grad)
+		} else {
+			err = addValue(a.grads[i], grad)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	a.count++
+	if a.count < a.steps {
+		// the optimizer zeroes the gradients when it steps, without which the next backward pass
+		// would add to them.
+		for _, vg := range grads {
+			grad, err := vg.Grad()
+			if err != nil {
+				return false, err
+			}
+			err = zeroValue(grad)
+			if err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	}
+	for i, vg := range grads {
+		grad, err := vg.Grad()
+		if err != nil {
+			return false, err
+		}
+		err = copyValue(grad, a.grads[i])
+		if err != nil {
+			return false, err
+		}
+		if a.reduce == AccumulateMean {
+			err = scaleValue(grad, 1/float64(a.count))
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+	a.count = 0
+	return true, nil
+}
+
+func copyValue(dst, src g.Value) error {
+	switch d := dst.Data().(type) {
+	case []float32:
+		s, ok := src.Data().([]float32)
+		if !ok || len(s) != len(d) {
+			return fmt.Errorf("cannot copy %v %v into %v %v", src.Dtype(), src.Shape(), dst.Dtype(), dst.Shape())
+		}
+		copy(d, s)
+	case []float64:
+		s, ok := src.Data().([]float64)
+		if !ok || len(s) != len(d) {
+			return fmt.Errorf("cannot copy %v %v into %v %v", src.Dtype(), src.Shape(), dst.Dtype(), dst.Shape())
+		}
+		copy(d, s)
+	default:
+		return fmt.Errorf("unsupported gradient type %T", d)
+	}
+	return nil
+}
+
+func zeroValue(v g.Value) error {
+	switch data := v.Data().(type) {
+	case []float32:
+		for i := range data {
+			data[i] = 0
+		}
+	case []float64:
+		for i := range data {
+			data[i] = 0
+		}
+	default:
+		return fmt.Errorf("unsupported gradient type %T", data)
+	}
+	return nil
+}
+
+func addValue(dst, src g.Value) error {
+	switch d := dst.Data().(type) {
+	case []float32:
+		s, ok := src.Data().([]float32)
+		if !ok || len(s) != len(d) {
+			return fmt.Errorf("cannot add %v %v to %v %v", src.Dtype(), src.Shape(), dst.Dtype(), dst.Shape())
+		}
+		for i := range d {
+			d[i] += s[i]
+		}
+	case []float64:
+		s, ok := src.Data().([]float64)
+		if !ok || len(s) != len(d) {
+			return fmt.Errorf("cannot add %v %v to %v %v", src.Dtype(), src.Shape(), dst.Dtype(), dst.Shape())
+		}
+		for i := range d {
+			d[i] += s[i]
+		}
+	default:
+		return fmt.Errorf("unsupported gradient type %T", d)
+	}
+	return nil
+}
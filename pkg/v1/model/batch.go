@@ -0,0 +1,139 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/aunum/goro/pkg/v1/layer"
+	"github.com/aunum/log"
+
+	g "gorgonia.org/gorgonia"
+)
+
+// batchGraph is a graph compiled for a specific batch size.
+type batchGraph struct {
+	size    int
+	graph   *g.ExprGraph
+	chain   *layer.Chain
+	x       Inputs
+	xFwd    *Input
+	y       *Input
+	loss    Loss
+	lossVal g.Value
+	predVal g.Value
+	vm      g.VM
+}
+
+// trainBatch returns the train batch graph for the given size, compiling it if it does not exist.
+func (s *Sequential) trainBatch(size int) (*batchGraph, error) {
+	if b, ok := s.trainBatches[size]; ok {
+		return b, nil
+	}
+	err := s.checkBatchSize(size)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.buildTrainBatchGraph(size, true)
+	if err != nil {
+		return nil, err
+	}
+	s.trainBatches[size] = b
+	return b, nil
+}
+
+func (s *Sequential) checkBatchSize(size int) error {
+	if size < 1 || size > s.maxBatchSize {
+		return &ConfigError{Name: "batch size", Reason: fmt.Sprintf("%d must be between 1 and the max batch size %d", size, s.maxBatchSize)}
+	}
+	return nil
+}
+
+// buildTrainBatchGraph compiles a train batch graph, the learnables are shared with the train chain
+// unless the graph has its own copy.
+func (s *Sequential) buildTrainBatchGraph(size int, shared bool) (b *batchGraph, err error) {
+	log.Debugf("compiling train batch graph for batch size %d", size)
+	b = &batchGraph{
+		size:  size,
+		graph: g.NewGraph(),
+	}
+
+	b.loss = s.loss.CloneTo(b.graph, AsBatch(size))
+	for _, input := range s.x {
+		// TODO: need to validate input names for duplicates.
+		if i, err := b.loss.Inputs().Get(input.Name()); err == nil {
+			b.x = append(b.x, i)
+			continue
+		}
+		i, err := input.CloneTo(b.graph, AsBatch(size))
+		if err != nil {
+			return nil, err
+		}
+		b.x = append(b.x, i)
+	}
+
+	b.xFwd, err = b.x.Get(NameAsBatch(s.fwd.Name()))
+	if err != nil {
+		return nil, err
+	}
+
+	b.y = s.y.AsBatch(size)
+	_, err = b.y.Compile(b.graph)
+	if err != nil {
+		return nil, err
+	}
+
+	b.chain = s.Chain.Clone()
+	opts := []layer.ChainOpt{}
+	if shared {
+		opts = append(opts, layer.WithSharedChainLearnables(s.trainChain))
+	}
+	opts = append(opts, layer.WithLayerOpts(layer.AsBatch(), layer.AsType(s.dtype)))
+	err = b.chain.Compile(b.graph, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if s.mask != nil {
+		mask, err := b.x.Get(NameAsBatch(s.mask.Name()))
+		if err != nil {
+			return nil, err
+		}
+		b.chain.SetMask(mask.Node())
+	}
+
+	prediction, err := b.chain.Fwd(b.xFwd.Node())
+	if err != nil {
+		return nil, err
+	}
+	g.Read(prediction, &b.predVal)
+	loss, err := computeLoss(b.loss, prediction, b.y.Node(), b.chain.Mask())
+	if err != nil {
+		return nil, err
+	}
+	g.Read(loss, &b.lossVal)
+
+	_, err = g.Grad(loss, b.chain.Learnables()...)
+	if err != nil {
+		return nil, err
+	}
+
+	vmOpts := append([]g.VMOpt{}, s.vmOpts...)
+	vmOpts = append(vmOpts, g.BindDualValues(b.chain.Learnables()...))
+	b.vm = g.NewTapeMachine(b.graph, vmOpts...)
+	return b, nil
+}
+
+// sharedChains are all the compiled chains which share learnables with the train chain.
+func (s *Sequential) sharedChains() map[string]*layer.Chain {
+	shared := s.predictorChains()
+	for size, b := range s.trainBatches {
+		shared[fmt.Sprintf("trainBatch_%d", size)] = b.chain
+	}
+	return shared
+}
+
+// batchSizeOf returns the leading dimension of a value.
+func batchSizeOf(v g.Value) (int, error) {
+	if v == nil || len(v.Shape()) == 0 {
+		return 0, fmt.Errorf("cannot determine batch size of a scalar or nil value")
+	}
+	return v.Shape()[0], nil
+}
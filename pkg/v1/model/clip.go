@@ -0,0 +1,182 @@
+package model
+
+import (
+	"fmt"
+	"math"
+
+	g "gorgonia.org/gorgonia"
+)
+
+// GradClipper clips gradients before they are applied by the optimizer.
+type GradClipper interface {
+	// Clip the gradients in place, the global norm is the norm across all gradients prior to clipping.
+	Clip(grads []g.ValueGrad, globalNorm float64) error
+}
+
+// validateClipper checks the bound of the clippers in this package is greater than 0.
+func validateClipper(clipper GradClipper) error {
+	var name string
+	var bound float64
+	switch c := clipper.(type) {
+	case ClipByValue:
+		name, bound = "clip by value", c.Value
+	case ClipByNorm:
+		name, bound = "clip by norm", c.Max
+	case ClipByGlobalNorm:
+		name, bound = "clip by global norm", c.Max
+	default:
+		return nil
+	}
+	if bound <= 0 {
+		return &ConfigError{Name: name, Reason: fmt.Sprintf("bound must be greater than 0, got %v", bound)}
+	}
+	return nil
+}
+
+// ClipByValue clips each gradient element to the range [-Value, Value].
+type ClipByValue struct {
+	// Value to clip to.
+	// required
+	Value float64
+}
+
+// Clip the gradients.
+func (c ClipByValue) Clip(grads []g.ValueGrad, globalNorm float64) error {
+	for _, vg := range grads {
+		grad, err := vg.Grad()
+		if err != nil {
+			return err
+		}
+		err = clampValue(grad, -c.Value, c.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClipByNorm rescales each gradient tensor independently so that its L2 norm is at most Max.
+type ClipByNorm struct {
+	// Max norm of each gradient tensor.
+	// required
+	Max float64
+}
+
+// Clip the gradients.
+func (c ClipByNorm) Clip(grads []g.ValueGrad, globalNorm float64) error {
+	for _, vg := range grads {
+		grad, err := vg.Grad()
+		if err != nil {
+			return err
+		}
+		sq, err := sumSquares(grad)
+		if err != nil {
+			return err
+		}
+		norm := math.Sqrt(sq)
+		if norm <= c.Max {
+			continue
+		}
+		err = scaleValue(grad, c.Max/norm)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClipByGlobalNorm rescales all gradients by the same factor so that the L2 norm across all
+// gradients is at most Max.
+type ClipByGlobalNorm struct {
+	// Max global norm.
+	// required
+	Max float64
+}
+
+// Clip the gradients.
+func (c ClipByGlobalNorm) Clip(grads []g.ValueGrad, globalNorm float64) error {
+	if globalNorm <= c.Max {
+		return nil
+	}
+	for _, vg := range grads {
+		grad, err := vg.Grad()
+		if err != nil {
+			return err
+		}
+		err = scaleValue(grad, c.Max/globalNorm)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GlobalNorm computes the L2 norm across all the gradients.
+func GlobalNorm(grads []g.ValueGrad) (float64, error) {
+	total := 0.0
+	for _, vg := range grads {
+		grad, err := vg.Grad()
+		if err != nil {
+			return 0, err
+		}
+		sq, err := sumSquares(grad)
+		if err != nil {
+			return 0, err
+		}
+		total += sq
+	}
+	return math.Sqrt(total), nil
+}
+
+func sumSquares(v g.Value) (float64, error) {
+	sum := 0.0
+	switch data := v.Data().(type) {
+	case []float32:
+		for _, d := range data {
+			sum += float64(d) * float64(d)
+		}
+	case []float64:
+		for _, d := range data {
+			sum += d * d
+		}
+	case float32:
+		sum = float64(data) * float64(data)
+	case float64:
+		sum = data * data
+	default:
+		return 0, fmt.Errorf("unsupported gradient type %T", data)
+	}
+	return sum, nil
+}
+
+func scaleValue(v g.Value, scale float64) error {
+	switch data := v.Data().(type) {
+	case []float32:
+		for i := range data {
+			data[i] *= float32(scale)
+		}
+	case []float64:
+		for i := range data {
+			data[i] *= scale
+		}
+	default:
+		return fmt.Errorf("unsupported gradient type %T", data)
+	}
+	return nil
+}
+
+func clampValue(v g.Value, min, max float64) error {
+	switch data := v.Data().(type) {
+	case []float32:
+		for i, d := range data {
+			data[i] = float32(math.Max(min, math.Min(max, float64(d))))
+		}
+	case []float64:
+		for i, d := range data {
+			data[i] = math.Max(min, math.Min(max, d))
+		}
+	default:
+		return fmt.Errorf("unsupported gradient type %T", data)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package model_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aunum/goro/pkg/v1/layer"
+	. "github.com/aunum/goro/pkg/v1/model"
+
+	"github.com/stretchr/testify/require"
+	g "gorgonia.org/gorgonia"
+	"gorgonia.org/tensor"
+)
+
+type valueGrad struct {
+	value, grad g.Value
+}
+
+func (v *valueGrad) Value() g.Value         { return v.value }
+func (v *valueGrad) Grad() (g.Value, error) { return v.grad, nil }
+
+func newValueGrads(grads ...[]float32) []g.ValueGrad {
+	vgs := []g.ValueGrad{}
+	for _, grad := range grads {
+		vgs = append(vgs, &valueGrad{
+			value: tensor.New(tensor.WithShape(len(grad)), tensor.Of(tensor.Float32)),
+			grad:  tensor.New(tensor.WithShape(len(grad)), tensor.WithBacking(grad)),
+		})
+	}
+	return vgs
+}
+
+func TestClip(t *testing.T) {
+	grads := newValueGrads([]float32{3, 4}, []float32{0, 0})
+	norm, err := GlobalNorm(grads)
+	require.NoError(t, err)
+	require.InDelta(t, 5, norm, 1e-6)
+
+	err = ClipByGlobalNorm{Max: 1}.Clip(grads, norm)
+	require.NoError(t, err)
+	grad, _ := grads[0].Grad()
+	require.InDeltaSlice(t, []float32{0.6, 0.8}, grad.Data(), 1e-6)
+
+	grads = newValueGrads([]float32{3, 4}, []float32{0.1, -0.2})
+	err = ClipByNorm{Max: 1}.Clip(grads, 0)
+	require.NoError(t, err)
+	grad, _ = grads[1].Grad()
+	require.InDeltaSlice(t, []float32{0.1, -0.2}, grad.Data(), 1e-6)
+
+	grads = newValueGrads([]float32{3, -4, 0.5})
+	err = ClipByValue{Value: 1}.Clip(grads, 0)
+	require.NoError(t, err)
+	grad, _ = grads[0].Grad()
+	require.InDeltaSlice(t, []float32{1, -1, 0.5}, grad.Data(), 1e-6)
+}
+
+func TestGradClipBound(t *testing.T) {
+	for _, clipper := range []GradClipper{ClipByValue{}, ClipByNorm{Max: -1}, ClipByGlobalNorm{}} {
+		model, err := NewSequential("clip")
+		require.NoError(t, err)
+		err = model.AddLayers(
+			layer.FC{Input: 3, Output: 2, Activation: layer.Linear, Name: "w0"},
+		)
+		require.NoError(t, err)
+		err = model.Compile(NewInput("x", []int{1, 3}), NewInput("y", []int{1, 2}),
+			WithGradClip(clipper),
+			WithoutTracker(),
+		)
+		var configErr *ConfigError
+		require.True(t, errors.As(err, &configErr), "%T", clipper)
+	}
+}
@@ -0,0 +1,203 @@
+package model_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/aunum/goro/pkg/v1/layer"
+	. "github.com/aunum/goro/pkg/v1/model"
+
+	"github.com/stretchr/testify/require"
+	g "gorgonia.org/gorgonia"
+	"gorgonia.org/tensor"
+)
+
+func TestCustomLayer(t *testing.T) {
+	require.NoError(t, layer.RegisterConfig("scale", scale{}))
+
+	model, err := NewSequential("custom")
+	require.NoError(t, err)
+	err = model.AddLayers(
+		scale{Size: 4},
+		layer.FC{Output: 2, Activation: layer.Linear},
+	)
+	require.NoError(t, err)
+	err = model.Compile(NewInput("x", []int{1, 4}), NewInput("y", []int{1, 2}),
+		WithBatchSize(2),
+		WithDType(tensor.Float64),
+		WithoutTracker(),
+	)
+	require.NoError(t, err)
+	require.Len(t, model.Learnables(), 3)
+
+	// the custom layer is compiled with the data type of the model.
+	require.Equal(t, tensor.Float64, model.Learnables()[0].Dtype())
+
+	x := tensor.New(tensor.WithShape(2, 4), tensor.WithBacking([]float64{1, 2, 3, 4, 4, 3, 2, 1}))
+	y := tensor.New(tensor.WithShape(2, 2), tensor.WithBacking([]float64{1, 0, 0, 1}))
+	loaded := requireTrainedShared(t, model, x, y, true)
+
+	// the registered custom layer is saved and loaded by its type.
+	require.Equal(t, scale{Size: 4}, loaded.Chain.Layers[0])
+}
+
+// requireTrainedShared fits the model on the batch requiring every learnable to change, then that the
+// online graph predicts the first instance of the batch with the trained learnables. If the model saves,
+// it requires the loaded model to predict the same and returns it, otherwise it requires saving to fail.
+func requireTrainedShared(t *testing.T, model *Sequential, x, y *tensor.Dense, saves bool) *Sequential {
+	before := []tensor.Tensor{}
+	for _, learnable := range model.Learnables() {
+		before = append(before, learnable.Value().(tensor.Tensor).Clone().(tensor.Tensor))
+	}
+	require.NoError(t, model.FitBatch(x, y))
+	for i, learnable := range model.Learnables() {
+		require.NotEqual(t, before[i].Data(), learnable.Value().Data(), "learnable %q was not trained", learnable.Name())
+	}
+
+	expected, err := model.PredictBatch(x)
+	require.NoError(t, err)
+	prediction, err := model.Predict(first(t, x))
+	require.NoError(t, err)
+	require.InDeltaSlice(t, first(t, expected.(*tensor.Dense)).Data(), prediction.Data(), 1e-5)
+
+	buf := &bytes.Buffer{}
+	if !saves {
+		require.Error(t, model.Save(buf))
+		return nil
+	}
+	require.NoError(t, model.Save(buf))
+	loaded, err := Load(buf, WithoutTracker())
+	require.NoError(t, err)
+	loadedPrediction, err := loaded.PredictBatch(x)
+	require.NoError(t, err)
+	require.Equal(t, expected.Data(), loadedPrediction.Data())
+	return loaded
+}
+
+// first returns the first instance of a batch with a batch dimension of one.
+func first(t *testing.T, batch *tensor.Dense) *tensor.Dense {
+	v, err := batch.Slice(g.S(0))
+	require.NoError(t, err)
+	instance := v.(*tensor.Dense).Materialize().(*tensor.Dense)
+	require.NoError(t, instance.Reshape(append([]int{1}, batch.Shape()[1:]...)...))
+	return instance
+}
+
+// scale is a custom layer config which scales its input by a learnable vector.
+type scale struct {
+	Size int `json:"size"`
+}
+
+func (s scale) Compile(graph *g.ExprGraph, opts ...layer.CompileOpt) (layer.Layer, error) {
+	l := &scaleLayer{scale: s, dtype: g.Float32}
+	for _, opt := range opts {
+		if err := opt(l); err != nil {
+			return nil, err
+		}
+	}
+	if l.shared != nil {
+		l.weights = g.NewMatrix(graph, l.dtype, g.WithShape(1, s.Size), g.WithName("scale"), g.WithValue(l.shared.weights.Value()))
+		return l, nil
+	}
+	l.weights = g.NewMatrix(graph, l.dtype, g.WithShape(1, s.Size), g.WithInit(g.Ones()), g.WithName("scale"))
+	return l, nil
+}
+
+func (s scale) InferShape(input tensor.Shape) (layer.Config, tensor.Shape, error) {
+	return s, input, nil
+}
+
+func (s scale) ApplyDefaults() layer.Config { return s }
+
+func (s scale) Validate() error { return nil }
+
+func (s scale) Clone() layer.Config { return s }
+
+type scaleLayer struct {
+	scale
+	weights   *g.Node
+	dtype     tensor.Dtype
+	isBatched bool
+	shared    *scaleLayer
+}
+
+func (s *scaleLayer) SetSharedLearnables(shared layer.Layer) error {
+	l, ok := shared.(*scaleLayer)
+	if !ok {
+		return fmt.Errorf("cannot share learnables with %T", shared)
+	}
+	s.shared = l
+	return nil
+}
+
+func (s *scaleLayer) SetBatched(batched bool) { s.isBatched = batched }
+
+func (s *scaleLayer) SetDType(dtype tensor.Dtype) { s.dtype = dtype }
+
+func (s *scaleLayer) Fwd(x *g.Node) (*g.Node, error) {
+	if s.isBatched {
+		return g.BroadcastHadamardProd(x, s.weights, nil, []byte{0})
+	}
+	return g.HadamardProd(x, s.weights)
+}
+
+func (s *scaleLayer) Learnables() g.Nodes { return g.Nodes{s.weights} }
+
+func (s *scaleLayer) Clone() layer.Layer {
+	return &scaleLayer{scale: s.scale, dtype: s.dtype, isBatched: s.isBatched, shared: s.shared}
+}
+
+func (s *scaleLayer) Graph() *g.ExprGraph { return s.weights.Graph() }
+
+func TestLambda(t *testing.T) {
+	model, err := NewSequential("lambda")
+	require.NoError(t, err)
+	err = model.AddLayers(
+		layer.Lambda{Name: "half", Fn: func(x *g.Node) (*g.Node, error) {
+			return g.Mul(x, g.NewConstant(float32(0.5)))
+		}},
+		layer.Lambda{
+			Name:       "scale",
+			Learnables: []layer.Learnable{{Name: "weights", Shape: tensor.Shape{1, 4}, Init: g.Ones()}},
+			LearnableFn: func(x *g.Node, learnables g.Nodes) (*g.Node, error) {
+				return g.BroadcastHadamardProd(x, learnables[0], nil, []byte{0})
+			},
+		},
+		layer.FC{Output: 2, Activation: layer.Linear},
+	)
+	require.NoError(t, err)
+	err = model.Compile(NewInput("x", []int{1, 4}), NewInput("y", []int{1, 2}),
+		WithBatchSize(2),
+		WithoutTracker(),
+	)
+	require.NoError(t, err)
+	require.Len(t, model.Learnables(), 3)
+	require.Equal(t, "scale-weights", model.Learnables()[0].Name())
+
+	x := tensor.New(tensor.WithShape(2, 4), tensor.WithBacking([]float32{1, 2, 3, 4, 4, 3, 2, 1}))
+	y := tensor.New(tensor.WithShape(2, 2), tensor.WithBacking([]float32{1, 0, 0, 1}))
+	requireTrainedShared(t, model, x, y, false)
+
+	// the prediction is the input halved, scaled by the lambda learnables then through the fc layer.
+	learnables := model.Learnables()
+	factors := learnables[0].Value().Data().([]float32)
+	weights := learnables[1].Value().Data().([]float32)
+	bias := learnables[2].Value().Data().([]float32)
+	expected := []float32{}
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 2; j++ {
+			v := bias[j]
+			for k := 0; k < 4; k++ {
+				v += x.Data().([]float32)[i*4+k] * 0.5 * factors[k] * weights[k*2+j]
+			}
+			expected = append(expected, v)
+		}
+	}
+	prediction, err := model.PredictBatch(x)
+	require.NoError(t, err)
+	require.InDeltaSlice(t, expected, prediction.Data(), 1e-5)
+
+	_, err = layer.NewChain(layer.Lambda{})
+	require.Error(t, err)
+}
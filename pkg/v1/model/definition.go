@@ -0,0 +1,674 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/aunum/goro/pkg/v1/layer"
+
+	"gopkg.in/yaml.v3"
+	g "gorgonia.org/gorgonia"
+)
+
+// Definition is a declarative definition of a sequential model, which can be written as YAML or JSON.
+//
+//	name: mnist
+//	x:
+//	- name: x
+//	  shape: [1, 784]
+//	y:
+//	  name: y
+//	  shape: [1, 10]
+//	layers:
+//	- type: fc
+//	  config: {output: 32, activation: relu}
+//	- type: fc
+//	  config: {output: 10, activation: softmax}
+//	loss: cross_entropy
+//	optimizer: {type: adam, learnRate: 0.001}
+//	batchSize: 100
+//
+// Layers and activations are decoded by the names they are registered with in the layer package.
+type Definition struct {
+	// Name of the model.
+	// required
+	Name string `json:"name"`
+
+	// DType of the model e.g. float32.
+	// Defaults to the data type of the forward input.
+	DType string `json:"dtype,omitempty"`
+
+	// X inputs of the model.
+	// required
+	X []InputDefinition `json:"x"`
+
+	// Y input of the model.
+	// required
+	Y InputDefinition `json:"y"`
+
+	// Fwd is the name of the x input to pass forward through the layers.
+	// Defaults to the first x input.
+	Fwd string `json:"fwd,omitempty"`
+
+	// Mask is the name of the x input which masks the timesteps of the forward input.
+	Mask string `json:"mask,omitempty"`
+
+	// Layers of the model.
+	Layers []*layer.Encoded `json:"layers"`
+
+	// Loss of the model.
+	// Defaults to mse.
+	Loss *EncodedLoss `json:"loss,omitempty"`
+
+	// Optimizer of the model.
+	// Defaults to adam.
+	Optimizer *OptimizerConfig `json:"optimizer,omitempty"`
+
+	// GradClip clips the gradients before each optimizer step.
+	GradClip *GradClipConfig `json:"gradClip,omitempty"`
+
+	// GradAccumulation accumulates the gradients over a number of steps.
+	GradAccumulation *GradAccumulationConfig `json:"gradAccumulation,omitempty"`
+
+	// Replicas to split each batch across when training, as with WithDataParallel.
+	// Defaults to 1
+	Replicas int `json:"replicas,omitempty"`
+
+	// BatchSize of the model.
+	// Defaults to 32.
+	BatchSize int `json:"batchSize,omitempty"`
+
+	// MaxBatchSize of the model.
+	// Defaults to the batch size.
+	MaxBatchSize int `json:"maxBatchSize,omitempty"`
+
+	// Epochs to train for, which is used by tools that train a model from its definition.
+	Epochs int `json:"epochs,omitempty"`
+}
+
+// InputDefinition is the definition of an input.
+type InputDefinition struct {
+	// Name of the input.
+	// required
+	Name string `json:"name"`
+
+	// Shape of the input.
+	// required
+	Shape []int `json:"shape"`
+
+	// DType of the input, if set the input is typed as with AsType.
+	DType string `json:"dtype,omitempty"`
+}
+
+// OptimizerConfig is the config of an optimizer.
+type OptimizerConfig struct {
+	// Type of the optimizer: adam, rmsprop, sgd or momentum.
+	// required
+	Type string `json:"type"`
+
+	// LearnRate of the optimizer.
+	// Defaults to the default of the optimizer.
+	LearnRate float64 `json:"learnRate,omitempty"`
+
+	// Beta1 of adam.
+	Beta1 float64 `json:"beta1,omitempty"`
+
+	// Beta2 of adam.
+	Beta2 float64 `json:"beta2,omitempty"`
+
+	// Epsilon of adam or rmsprop.
+	Epsilon float64 `json:"epsilon,omitempty"`
+
+	// Rho of rmsprop.
+	Rho float64 `json:"rho,omitempty"`
+
+	// Momentum of momentum.
+	Momentum float64 `json:"momentum,omitempty"`
+
+	// L1Reg is the L1 regularization of adam, sgd or momentum.
+	L1Reg float64 `json:"l1Reg,omitempty"`
+
+	// L2Reg is the L2 regularization of adam, rmsprop, sgd or momentum.
+	L2Reg float64 `json:"l2Reg,omitempty"`
+
+	// Clip of the gradients by the optimizer.
+	Clip float64 `json:"clip,omitempty"`
+
+	// Schedule of the learning rate, which sets the learning rate before each step.
+	Schedule *ScheduleConfig `json:"schedule,omitempty"`
+
+	// PerEpoch advances the schedule by epoch rather than by step.
+	PerEpoch bool `json:"perEpoch,omitempty"`
+}
+
+// Solver returns the optimizer of the config.
+func (o OptimizerConfig) Solver() (g.Solver, error) {
+	opts := []g.SolverOpt{}
+	if o.LearnRate != 0 {
+		opts = append(opts, g.WithLearnRate(o.LearnRate))
+	}
+	if o.Beta1 != 0 {
+		opts = append(opts, g.WithBeta1(o.Beta1))
+	}
+	if o.Beta2 != 0 {
+		opts = append(opts, g.WithBeta2(o.Beta2))
+	}
+	if o.Epsilon != 0 {
+		opts = append(opts, g.WithEps(o.Epsilon))
+	}
+	if o.Rho != 0 {
+		opts = append(opts, g.WithRho(o.Rho))
+	}
+	if o.Momentum != 0 {
+		opts = append(opts, g.WithMomentum(o.Momentum))
+	}
+	if o.L1Reg != 0 {
+		opts = append(opts, g.WithL1Reg(o.L1Reg))
+	}
+	if o.L2Reg != 0 {
+		opts = append(opts, g.WithL2Reg(o.L2Reg))
+	}
+	if o.Clip != 0 {
+		opts = append(opts, g.WithClip(o.Clip))
+	}
+	var solver g.Solver
+	switch o.Type {
+	case "adam":
+		solver = g.NewAdamSolver(opts...)
+	case "rmsprop":
+		solver = g.NewRMSPropSolver(opts...)
+	case "sgd":
+		solver = g.NewVanillaSolver(opts...)
+	case "momentum":
+		solver = g.NewMomentum(opts...)
+	default:
+		return nil, &ConfigError{Name: fmt.Sprintf("optimizer %q", o.Type), Reason: "unknown optimizer type"}
+	}
+	if o.Schedule == nil {
+		return solver, nil
+	}
+	schedule, err := o.Schedule.LearnRateSchedule()
+	if err != nil {
+		return nil, err
+	}
+	scheduleOpts := []ScheduledOptimizerOpt{}
+	if o.PerEpoch {
+		scheduleOpts = append(scheduleOpts, PerEpoch())
+	}
+	return NewScheduledOptimizer(solver, schedule, scheduleOpts...)
+}
+
+// ScheduleConfig is the config of a learning rate schedule. A ReduceOnPlateau schedule monitors a
+// metric at runtime, so it cannot be configured.
+type ScheduleConfig struct {
+	// Type of the schedule: step_decay, exponential_decay, cosine_annealing or one_cycle.
+	// required
+	Type string `json:"type"`
+
+	// Initial learning rate of step or exponential decay.
+	Initial float64 `json:"initial,omitempty"`
+
+	// Max learning rate of cosine annealing or one cycle.
+	Max float64 `json:"max,omitempty"`
+
+	// Min learning rate of cosine annealing.
+	Min float64 `json:"min,omitempty"`
+
+	// Factor of step decay.
+	Factor float64 `json:"factor,omitempty"`
+
+	// Every is the number of steps or epochs between the drops of step decay.
+	Every int `json:"every,omitempty"`
+
+	// Rate of exponential decay.
+	Rate float64 `json:"rate,omitempty"`
+
+	// Steps over which the rate of exponential decay is applied.
+	Steps int `json:"steps,omitempty"`
+
+	// Staircase decays the learning rate of exponential decay at discrete intervals.
+	Staircase bool `json:"staircase,omitempty"`
+
+	// Period of cosine annealing.
+	Period int `json:"period,omitempty"`
+
+	// Mult is the factor the period of cosine annealing grows by after each restart.
+	Mult float64 `json:"mult,omitempty"`
+
+	// Total number of steps or epochs of one cycle.
+	Total int `json:"total,omitempty"`
+
+	// PctStart is the percentage of one cycle spent increasing the learning rate.
+	PctStart float64 `json:"pctStart,omitempty"`
+
+	// DivFactor determines the initial learning rate of one cycle.
+	DivFactor float64 `json:"divFactor,omitempty"`
+
+	// FinalDivFactor determines the final learning rate of one cycle.
+	FinalDivFactor float64 `json:"finalDivFactor,omitempty"`
+
+	// Warmup is the number of steps or epochs to linearly warm up to the schedule over.
+	Warmup int `json:"warmup,omitempty"`
+}
+
+// LearnRateSchedule returns the schedule of the config.
+func (c ScheduleConfig) LearnRateSchedule() (Schedule, error) {
+	var schedule Schedule
+	switch c.Type {
+	case "step_decay":
+		schedule = StepDecay{Initial: c.Initial, Factor: c.Factor, Every: c.Every}
+	case "exponential_decay":
+		schedule = ExponentialDecay{Initial: c.Initial, Rate: c.Rate, Steps: c.Steps, Staircase: c.Staircase}
+	case "cosine_annealing":
+		schedule = CosineAnnealing{Max: c.Max, Min: c.Min, Period: c.Period, Mult: c.Mult}
+	case "one_cycle":
+		schedule = OneCycle{Max: c.Max, Total: c.Total, PctStart: c.PctStart, DivFactor: c.DivFactor, FinalDivFactor: c.FinalDivFactor}
+	default:
+		return nil, &ConfigError{Name: fmt.Sprintf("schedule %q", c.Type), Reason: "unknown schedule type"}
+	}
+	if c.Warmup > 0 {
+		schedule = LinearWarmup{Steps: c.Warmup, Schedule: schedule}
+	}
+	return schedule, nil
+}
+
+// GradClipConfig is the config of a gradient clipper.
+type GradClipConfig struct {
+	// Type of the clipping: value, norm or global_norm.
+	// required
+	Type string `json:"type"`
+
+	// Value to clip to by value.
+	Value float64 `json:"value,omitempty"`
+
+	// Max norm to clip to by norm or global norm.
+	Max float64 `json:"max,omitempty"`
+}
+
+// Clipper returns the gradient clipper of the config.
+func (c GradClipConfig) Clipper() (GradClipper, error) {
+	switch c.Type {
+	case "value":
+		return ClipByValue{Value: c.Value}, nil
+	case "norm":
+		return ClipByNorm{Max: c.Max}, nil
+	case "global_norm":
+		return ClipByGlobalNorm{Max: c.Max}, nil
+	default:
+		return nil, &ConfigError{Name: fmt.Sprintf("gradient clipping %q", c.Type), Reason: "unknown gradient clipping type"}
+	}
+}
+
+// GradAccumulationConfig is the config of gradient accumulation.
+type GradAccumulationConfig struct {
+	// Steps to accumulate.
+	// required
+	Steps int `json:"steps"`
+
+	// Reduce the accumulated gradients by mean or sum.
+	// Defaults to mean.
+	Reduce string `json:"reduce,omitempty"`
+}
+
+// Build the model of the definition, returning it compiled. Any options are applied after those of
+// the definition.
+func (d *Definition) Build(opts ...Opt) (*Sequential, error) {
+	model, err := NewSequential(d.Name)
+	if err != nil {
+		return nil, err
+	}
+	for i, encoded := range d.Layers {
+		if encoded == nil {
+			return nil, &ConfigError{Name: fmt.Sprintf("model %q layer %d", d.Name, i), Reason: "must not be empty"}
+		}
+		config, err := layer.Decode(encoded)
+		if err != nil {
+			return nil, err
+		}
+		err = model.AddLayer(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+	x := Inputs{}
+	for _, id := range d.X {
+		input, err := id.input()
+		if err != nil {
+			return nil, err
+		}
+		x = append(x, input)
+	}
+	if d.Fwd != "" {
+		fwd, err := x.Get(d.Fwd)
+		if err != nil {
+			return nil, err
+		}
+		model.Fwd(fwd)
+	}
+	if d.Mask != "" {
+		mask, err := x.Get(d.Mask)
+		if err != nil {
+			return nil, err
+		}
+		model.Mask(mask)
+	}
+	y, err := d.Y.input()
+	if err != nil {
+		return nil, err
+	}
+
+	compileOpts := []Opt{}
+	if d.DType != "" {
+		dtype, err := parseDType(d.DType)
+		if err != nil {
+			return nil, err
+		}
+		compileOpts = append(compileOpts, WithDType(dtype))
+	}
+	if d.BatchSize != 0 {
+		compileOpts = append(compileOpts, WithBatchSize(d.BatchSize))
+	}
+	if d.MaxBatchSize != 0 {
+		compileOpts = append(compileOpts, WithMaxBatchSize(d.MaxBatchSize))
+	}
+	if d.Loss != nil {
+		loss, err := loadLoss(d.Loss)
+		if err != nil {
+			return nil, err
+		}
+		compileOpts = append(compileOpts, WithLoss(loss))
+	}
+	if d.Optimizer != nil {
+		compileOpts = append(compileOpts, WithOptimizerConfig(*d.Optimizer))
+	}
+	if d.GradClip != nil {
+		clipper, err := d.GradClip.Clipper()
+		if err != nil {
+			return nil, err
+		}
+		compileOpts = append(compileOpts, WithGradClip(clipper))
+	}
+	if d.GradAccumulation != nil {
+		reduce := AccumulateMean
+		switch d.GradAccumulation.Reduce {
+		case "", "mean":
+		case "sum":
+			reduce = AccumulateSum
+		default:
+			return nil, &ConfigError{Name: fmt.Sprintf("gradient accumulation reduction %q", d.GradAccumulation.Reduce), Reason: "must be mean or sum"}
+		}
+		compileOpts = append(compileOpts, WithGradAccumulation(d.GradAccumulation.Steps, reduce))
+	}
+	if d.Replicas != 0 {
+		compileOpts = append(compileOpts, WithDataParallel(d.Replicas))
+	}
+	err = model.Compile(x, y, append(compileOpts, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+func (i InputDefinition) input() (*Input, error) {
+	opts := []InputOpt{}
+	if i.DType != "" {
+		dtype, err := parseDType(i.DType)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, AsType(dtype))
+	}
+	return NewInput(i.Name, i.Shape, opts...), nil
+}
+
+func defineInput(i *Input) InputDefinition {
+	def := InputDefinition{Name: i.Name(), Shape: i.Shape().Clone()}
+	if i.typed {
+		def.DType = i.DType().String()
+	}
+	return def
+}
+
+// Definition returns the definition of the compiled model. The loss, optimizer and gradient clipping
+// must be those of this package, and the optimizer must be the default or set with WithOptimizerConfig,
+// as the parameters of other optimizers are unknown.
+func (s *Sequential) Definition() (*Definition, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	err := s.checkCompiled()
+	if err != nil {
+		return nil, err
+	}
+	return s.define(true)
+}
+
+// define returns the definition of the model. If strict, a loss, optimizer or gradient clipper which
+// cannot be defined is an error, otherwise it is left out of the definition.
+func (s *Sequential) define(strict bool) (*Definition, error) {
+	def := &Definition{
+		Name:         s.name,
+		DType:        s.dtype.String(),
+		Y:            defineInput(s.y),
+		Fwd:          s.fwd.Name(),
+		Loss:         saveLoss(s.loss),
+		BatchSize:    s.batchSize,
+		MaxBatchSize: s.maxBatchSize,
+	}
+	if def.Loss == nil && strict {
+		return nil, &ConfigError{Name: fmt.Sprintf("loss %T", s.loss), Reason: "cannot be defined, only the losses of this package can"}
+	}
+	if s.mask != nil {
+		def.Mask = s.mask.Name()
+	}
+	for _, x := range s.x {
+		def.X = append(def.X, defineInput(x))
+	}
+	for _, config := range s.Chain.Layers {
+		encoded, err := layer.Encode(config)
+		if err != nil {
+			return nil, err
+		}
+		def.Layers = append(def.Layers, encoded)
+	}
+	if s.optimizerConfig != nil {
+		config := *s.optimizerConfig
+		if config.Schedule != nil {
+			schedule := *config.Schedule
+			config.Schedule = &schedule
+		}
+		def.Optimizer = &config
+	} else if strict {
+		return nil, &ConfigError{Name: fmt.Sprintf("optimizer %T", s.optimizer), Reason: "cannot be defined as its parameters are unknown, set it with WithOptimizerConfig"}
+	}
+	switch c := s.gradClipper.(type) {
+	case nil:
+	case ClipByValue:
+		def.GradClip = &GradClipConfig{Type: "value", Value: c.Value}
+	case ClipByNorm:
+		def.GradClip = &GradClipConfig{Type: "norm", Max: c.Max}
+	case ClipByGlobalNorm:
+		def.GradClip = &GradClipConfig{Type: "global_norm", Max: c.Max}
+	default:
+		if strict {
+			return nil, &ConfigError{Name: fmt.Sprintf("gradient clipper %T", c), Reason: "cannot be defined, only the gradient clippers of this package can"}
+		}
+	}
+	if s.accumulator != nil {
+		reduce := "mean"
+		if s.accumulator.reduce == AccumulateSum {
+			reduce = "sum"
+		}
+		def.GradAccumulation = &GradAccumulationConfig{Steps: s.accumulator.steps, Reduce: reduce}
+	}
+	if s.replicas > 1 {
+		def.Replicas = s.replicas
+	}
+	return def, nil
+}
+
+// ParseDefinition parses a YAML or JSON model definition, unknown fields are an error.
+func ParseDefinition(b []byte) (*Definition, error) {
+	var v interface{}
+	err := yaml.Unmarshal(b, &v)
+	if err != nil {
+		return nil, err
+	}
+	b, err = json.Marshal(jsonValue(v))
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	def := &Definition{}
+	err = dec.Decode(def)
+	if err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+// ReadDefinition reads a YAML or JSON model definition.
+func ReadDefinition(r io.Reader) (*Definition, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDefinition(b)
+}
+
+// ReadDefinitionFile reads a YAML or JSON model definition from the file at the path.
+func ReadDefinitionFile(path string) (*Definition, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDefinition(b)
+}
+
+// JSON encodes the definition as indented JSON.
+func (d *Definition) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// YAML encodes the definition as YAML, with the fields in the same order as in JSON.
+func (d *Definition) YAML() ([]byte, error) {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	node, err := yamlNode(dec)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	err = enc.Encode(node)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), enc.Close()
+}
+
+// WriteFile writes the definition to the file at the path, as JSON if it has a .json extension and
+// YAML otherwise.
+func (d *Definition) WriteFile(path string) error {
+	var b []byte
+	var err error
+	if filepath.Ext(path) == ".json" {
+		b, err = d.JSON()
+	} else {
+		b, err = d.YAML()
+	}
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// jsonValue converts a decoded YAML value to one which can be encoded as JSON.
+func jsonValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, v := range val {
+			val[k] = jsonValue(v)
+		}
+		return val
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, v := range val {
+			m[fmt.Sprint(k)] = jsonValue(v)
+		}
+		return m
+	case []interface{}:
+		for i, v := range val {
+			val[i] = jsonValue(v)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// yamlNode decodes the next JSON value as a YAML node, keeping the order of object fields.
+func yamlNode(dec *json.Decoder) (*yaml.Node, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok := token.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		if tok == '{' {
+			node.Kind = yaml.MappingNode
+		}
+		for dec.More() {
+			if node.Kind == yaml.MappingNode {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key.(string)})
+			}
+			v, err := yamlNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, v)
+		}
+		if node.Kind == yaml.SequenceNode && scalars(node.Content) {
+			node.Style = yaml.FlowStyle
+		}
+		_, err = dec.Token()
+		return node, err
+	case json.Number:
+		node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: tok.String()}
+		if _, err := tok.Int64(); err == nil {
+			node.Tag = "!!int"
+		}
+		return node, nil
+	case string:
+		node := &yaml.Node{}
+		return node, node.Encode(tok)
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(tok)}, nil
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+}
+
+// scalars returns whether the nodes are all scalars, such as in shapes which are written inline.
+func scalars(nodes []*yaml.Node) bool {
+	for _, n := range nodes {
+		if n.Kind != yaml.ScalarNode {
+			return false
+		}
+	}
+	return true
+}
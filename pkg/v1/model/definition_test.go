@@ -0,0 +1,101 @@
+package model_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aunum/goro/pkg/v1/layer"
+	. "github.com/aunum/goro/pkg/v1/model"
+
+	"github.com/stretchr/testify/require"
+	g "gorgonia.org/gorgonia"
+	"gorgonia.org/tensor"
+)
+
+const definition = `
+name: xor
+x:
+- name: x
+  shape: [1, 2]
+y:
+  name: y
+  shape: [1, 2]
+layers:
+- type: fc
+  config:
+    output: 4
+    activation: tanh
+- type: fc
+  config:
+    output: 2
+    activation: {type: leaky_relu, alpha: 0.1}
+loss: mse
+optimizer:
+  type: momentum
+  learnRate: 0.1
+  momentum: 0.9
+gradClip: {type: global_norm, max: 5}
+batchSize: 4
+epochs: 10
+`
+
+func TestDefinition(t *testing.T) {
+	def, err := ParseDefinition([]byte(definition))
+	require.NoError(t, err)
+	require.Equal(t, 10, def.Epochs)
+	model, err := def.Build(WithoutTracker())
+	require.NoError(t, err)
+	require.Equal(t, 4, model.BatchSize())
+	require.Equal(t, layer.NewLeakyReLU(0.1), model.Chain.Layers[1].(layer.FC).Activation)
+
+	x := tensor.New(tensor.WithShape(4, 2), tensor.WithBacking([]float32{0, 0, 0, 1, 1, 0, 1, 1}))
+	y := tensor.New(tensor.WithShape(4, 2), tensor.WithBacking([]float32{1, 0, 0, 1, 0, 1, 1, 0}))
+	require.NoError(t, model.FitBatch(x, y))
+
+	defined, err := model.Definition()
+	require.NoError(t, err)
+	require.Equal(t, &OptimizerConfig{Type: "momentum", LearnRate: 0.1, Momentum: 0.9}, defined.Optimizer)
+	require.Equal(t, &GradClipConfig{Type: "global_norm", Max: 5}, defined.GradClip)
+	dir, err := ioutil.TempDir("", "definition")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for _, path := range []string{"xor.yaml", "xor.json"} {
+		path = filepath.Join(dir, path)
+		require.NoError(t, defined.WriteFile(path))
+		read, err := ReadDefinitionFile(path)
+		require.NoError(t, err)
+		expected, err := defined.JSON()
+		require.NoError(t, err)
+		actual, err := read.JSON()
+		require.NoError(t, err)
+		require.JSONEq(t, string(expected), string(actual))
+		rebuilt, err := read.Build(WithoutTracker())
+		require.NoError(t, err)
+		require.Equal(t, model.OutputShape(), rebuilt.OutputShape())
+		require.Len(t, rebuilt.Learnables(), len(model.Learnables()))
+	}
+
+	// schedules and replicas are defined, optimizers with unknown parameters are not.
+	def.Optimizer = &OptimizerConfig{Type: "sgd", Schedule: &ScheduleConfig{Type: "step_decay", Initial: 0.1, Every: 2, Warmup: 1}, PerEpoch: true}
+	def.Replicas = 2
+	scheduled, err := def.Build(WithoutTracker())
+	require.NoError(t, err)
+	defined, err = scheduled.Definition()
+	require.NoError(t, err)
+	require.Equal(t, def.Optimizer, defined.Optimizer)
+	require.Equal(t, 2, defined.Replicas)
+	custom, err := def.Build(WithoutTracker(), WithOptimizer(g.NewAdamSolver(g.WithLearnRate(0.01))))
+	require.NoError(t, err)
+	_, err = custom.Definition()
+	var configErr *ConfigError
+	require.True(t, errors.As(err, &configErr))
+
+	_, err = ParseDefinition([]byte("name: typo\nlayer: []"))
+	require.Error(t, err)
+	def.Layers[0].Type = "unknown"
+	_, err = def.Build(WithoutTracker())
+	require.Error(t, err)
+}
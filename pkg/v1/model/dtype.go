@@ -0,0 +1,52 @@
+package model
+
+import (
+	"fmt"
+
+	t "gorgonia.org/tensor"
+)
+
+// SupportedDTypes are the data types a model can be compiled with.
+var SupportedDTypes = []t.Dtype{t.Float32, t.Float64}
+
+// resolveDType determines the data type of the model and propagates it to clones of the inputs.
+func (s *Sequential) resolveDType() error {
+	if s.dtype.Type == nil {
+		s.dtype = s.fwd.DType()
+	}
+	supported := false
+	for _, dtype := range SupportedDTypes {
+		if s.dtype == dtype {
+			supported = true
+		}
+	}
+	if !supported {
+		return &ConfigError{Name: fmt.Sprintf("model %q", s.name), Reason: fmt.Sprintf("data type %v is not supported, supported types are %v", s.dtype, SupportedDTypes)}
+	}
+	// the inputs are cloned so that the data type is not set on the inputs of the caller.
+	s.x = s.x.Clone()
+	s.fwd = s.fwd.Clone()
+	s.y = s.y.Clone()
+	if s.mask != nil {
+		s.mask = s.mask.Clone()
+	}
+	inputs := append(Inputs{s.fwd, s.y}, s.x...)
+	for _, input := range inputs {
+		if input.DType() == s.dtype {
+			continue
+		}
+		if !input.typed {
+			input.dtype = s.dtype
+			continue
+		}
+		if input.Name() == s.fwd.Name() || input.Name() == s.y.Name() || (s.mask != nil && input.Name() == s.mask.Name()) {
+			return &DTypeError{Name: fmt.Sprintf("input %q", input.Name()), Expected: s.dtype, Actual: input.DType()}
+		}
+	}
+	return nil
+}
+
+// DType is the data type of the model.
+func (s *Sequential) DType() t.Dtype {
+	return s.dtype
+}
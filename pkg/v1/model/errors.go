@@ -0,0 +1,24 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/aunum/goro/pkg/v1/layer"
+)
+
+// ShapeError is returned when a shape does not match what is expected.
+type ShapeError = layer.ShapeError
+
+// DTypeError is returned when a data type does not match what is expected.
+type DTypeError = layer.DTypeError
+
+// ConfigError is returned when a configuration is invalid.
+type ConfigError = layer.ConfigError
+
+// checkCompiled returns an error if the model has not been compiled.
+func (s *Sequential) checkCompiled() error {
+	if s.trainChain == nil || s.predictors == nil {
+		return &ConfigError{Name: fmt.Sprintf("model %q", s.name), Reason: "model must be compiled before use"}
+	}
+	return nil
+}
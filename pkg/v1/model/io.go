@@ -24,6 +24,7 @@ type Input struct {
 	name  string
 	shape t.Shape
 	dtype t.Dtype
+	typed bool
 	node  *g.Node
 }
 
@@ -31,10 +32,11 @@ type Input struct {
 type InputOpt func(*Input)
 
 // AsType explicitly sets the type of the input.
-// Defaults to Float32.
+// Defaults to the data type of the model it is compiled in, which defaults to Float32.
 func AsType(dtype t.Dtype) func(*Input) {
 	return func(i *Input) {
 		i.dtype = dtype
+		i.typed = true
 	}
 }
 
@@ -62,9 +64,9 @@ func (i *Input) Inputs() Inputs {
 }
 
 // Compile an input into a graph.
-func (i *Input) Compile(graph *g.ExprGraph, opts ...InputOpt) *g.Node {
+func (i *Input) Compile(graph *g.ExprGraph, opts ...InputOpt) (*g.Node, error) {
 	if i.node != nil {
-		panic(fmt.Sprintf("trying ot compile input %q that is already compiled", i.name))
+		return nil, &ConfigError{Name: fmt.Sprintf("input %q", i.name), Reason: "input is already compiled"}
 	}
 	for _, opt := range opts {
 		opt(i)
@@ -76,7 +78,7 @@ func (i *Input) Compile(graph *g.ExprGraph, opts ...InputOpt) *g.Node {
 		n = g.NewTensor(graph, i.dtype, len(i.shape), g.WithShape(i.shape...), g.WithName(i.name))
 	}
 	i.node = n
-	return n
+	return n, nil
 }
 
 // Shape is the shape of the input.
@@ -115,6 +117,7 @@ func (i *Input) Clone(opts ...CloneOpt) *Input {
 		name:  i.name,
 		shape: i.shape.Clone(),
 		dtype: i.dtype,
+		typed: i.typed,
 	}
 	for _, opt := range opts {
 		opt(ret)
@@ -123,31 +126,37 @@ func (i *Input) Clone(opts ...CloneOpt) *Input {
 }
 
 // CloneTo clones an input with the node value (if present) to another graph.
-func (i *Input) CloneTo(graph *g.ExprGraph, opts ...CloneOpt) *Input {
+func (i *Input) CloneTo(graph *g.ExprGraph, opts ...CloneOpt) (*Input, error) {
 	ret := i.Clone(opts...)
 	if ret.node != nil {
 		ret.node = i.node.CloneTo(graph)
-	} else {
-		ret.Compile(graph)
+		return ret, nil
 	}
-	return ret
+	_, err := ret.Compile(graph)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
 }
 
 // Check that the dimensions and type of the given value are congruent with the
 // expected input.
 func (i *Input) Check(value g.Value) error {
+	if value == nil {
+		return &ShapeError{Name: fmt.Sprintf("input %q", i.name), Expected: fmt.Sprintf("%v", i.shape)}
+	}
 	vShape := value.Shape()
 	if len(vShape) != len(i.Shape()) {
-		return fmt.Errorf("shape mismatch: input %v expects %v got %v", i.name, i.shape, vShape)
+		return &ShapeError{Name: fmt.Sprintf("input %q", i.name), Expected: fmt.Sprintf("%v", i.shape), Actual: vShape}
 	}
 
 	for index, s := range i.Shape() {
 		if vShape[index] != s {
-			return fmt.Errorf("shape mismatch: input %v expects %v got %v", i.name, i.shape, vShape)
+			return &ShapeError{Name: fmt.Sprintf("input %q", i.name), Expected: fmt.Sprintf("%v", i.shape), Actual: vShape}
 		}
 	}
 	if i.dtype != value.Dtype() {
-		return fmt.Errorf("data type mismatch: input %v expects %v got %v", i.name, i.dtype, value.Dtype())
+		return &DTypeError{Name: fmt.Sprintf("input %q", i.name), Expected: i.dtype, Actual: value.Dtype()}
 	}
 	return nil
 }
@@ -158,17 +167,18 @@ func (i *Input) Set(value g.Value) error {
 	if err != nil {
 		return err
 	}
+	if i.node == nil {
+		return &ConfigError{Name: fmt.Sprintf("input %q", i.name), Reason: "input must be compiled before it is set"}
+	}
 	return g.Let(i.node, value)
 }
 
 // AsBatch converts an input to a batched representation.
+// The returned input is uncompiled, a vector or scalar input gains a leading batch dimension.
 func (i *Input) AsBatch(size int) *Input {
 	ret := i.Clone()
-	if len(ret.Shape()) == 1 {
-		err := ret.OneOfMany()
-		if err != nil {
-			panic(err)
-		}
+	if len(ret.Shape()) <= 1 {
+		ret.shape = append(t.Shape{1}, ret.shape...)
 	}
 	ret.shape[0] = size
 	ret.name = NameAsBatch(ret.Name())
@@ -208,10 +218,10 @@ func (i *Input) EnsureBatch() *Input {
 // Validate the input.
 func (i *Input) Validate() error {
 	if len(i.shape) == 0 {
-		return fmt.Errorf("no input shape provided")
+		return &ConfigError{Name: fmt.Sprintf("input %q", i.name), Reason: "no input shape provided"}
 	}
 	if i.shape[0] != 1 {
-		return fmt.Errorf("input shape %q %v must be a scalar or have the first dimension 1 e.g. [1, 4]", i.name, i.shape)
+		return &ShapeError{Name: fmt.Sprintf("input %q", i.name), Expected: "a scalar or the first dimension 1 e.g. [1, 4]", Actual: i.shape}
 	}
 	return nil
 }
@@ -248,13 +258,16 @@ func (i Inputs) Get(name string) (*Input, error) {
 }
 
 // Compile all inputs into the given graph.
-func (i Inputs) Compile(graph *g.ExprGraph, opts ...InputOpt) g.Nodes {
+func (i Inputs) Compile(graph *g.ExprGraph, opts ...InputOpt) (g.Nodes, error) {
 	nodes := g.Nodes{}
 	for _, input := range i {
-		n := input.Compile(graph, opts...)
+		n, err := input.Compile(graph, opts...)
+		if err != nil {
+			return nil, err
+		}
 		nodes = append(nodes, n)
 	}
-	return nodes
+	return nodes, nil
 }
 
 // Clone the inputs.
@@ -268,6 +281,9 @@ func (i Inputs) Clone() Inputs {
 
 // Set the values to the inputs.
 func (i Inputs) Set(values Values) error {
+	if len(values) > len(i) {
+		return &ConfigError{Name: "inputs", Reason: fmt.Sprintf("got %d values for %d inputs", len(values), len(i))}
+	}
 	for index, value := range values {
 		err := i[index].Set(value)
 		if err != nil {
@@ -300,11 +316,12 @@ func (i *Input) AsLayer() l.Layer {
 }
 
 // Compile the layer.
-func (i *InputLayer) Compile(graph *g.ExprGraph, opts ...l.CompileOpt) {
+func (i *InputLayer) Compile(graph *g.ExprGraph, opts ...l.CompileOpt) error {
 	if i.Graph() != nil {
-		return
+		return nil
 	}
-	i.input.Compile(graph)
+	_, err := i.input.Compile(graph)
+	return err
 }
 
 // Fwd is a forward pass through the layer.
@@ -347,14 +364,15 @@ type Values []g.Value
 type ValueOr interface{}
 
 // ValuesFrom returns the value as an array of gorgonia values.
-func ValuesFrom(v ValueOr) Values {
+func ValuesFrom(v ValueOr) (Values, error) {
 	switch val := v.(type) {
 	case g.Value:
-		return []g.Value{val}
+		return []g.Value{val}, nil
 	case []g.Value:
-		return val
+		return val, nil
+	case Values:
+		return val, nil
 	default:
-		log.Fatalf("value type %v is not supported; gorgonia.Value and []gorgonia.Values are currently supported.", val)
+		return nil, &ConfigError{Name: "value", Reason: fmt.Sprintf("value type %T is not supported; gorgonia.Value and []gorgonia.Value are currently supported", val)}
 	}
-	return nil
 }
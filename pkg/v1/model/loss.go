@@ -1,7 +1,9 @@
 package model
 
 import (
-	"github.com/aunum/log"
+	"fmt"
+
+	"github.com/aunum/goro/pkg/v1/layer"
 
 	g "gorgonia.org/gorgonia"
 )
@@ -18,6 +20,53 @@ type Loss interface {
 	Inputs() Inputs
 }
 
+// MaskedLoss is a loss which can ignore the masked timesteps of sequences.
+type MaskedLoss interface {
+	// ComputeMasked computes the loss of the timesteps of sequences of shape (batch, time, x...) which
+	// are not masked, the mask is of shape (batch, time).
+	ComputeMasked(yHat, y, mask *g.Node) (loss *g.Node, err error)
+}
+
+// computeLoss computes the loss, ignoring masked timesteps if the prediction is masked.
+func computeLoss(loss Loss, yHat, y, mask *g.Node) (*g.Node, error) {
+	if mask == nil {
+		return loss.Compute(yHat, y)
+	}
+	masked, ok := loss.(MaskedLoss)
+	if !ok {
+		return nil, &ConfigError{Name: fmt.Sprintf("loss %T", loss), Reason: "loss cannot ignore masked timesteps, it is not a MaskedLoss"}
+	}
+	return masked.ComputeMasked(yHat, y, mask)
+}
+
+// maskedMean is the mean of the elementwise loss of a sequence over the timesteps which are not masked.
+func maskedMean(loss, mask *g.Node) (*g.Node, error) {
+	size := 1
+	for _, d := range loss.Shape()[2:] {
+		size *= d
+	}
+	loss, err := layer.ApplyMask(loss, mask)
+	if err != nil {
+		return nil, err
+	}
+	loss, err = g.Sum(loss)
+	if err != nil {
+		return nil, err
+	}
+	count, err := g.Sum(mask)
+	if err != nil {
+		return nil, err
+	}
+	loss, err = g.Div(loss, count)
+	if err != nil {
+		return nil, err
+	}
+	if size == 1 {
+		return loss, nil
+	}
+	return g.Div(loss, scalar(loss, float64(size)))
+}
+
 // MSE is standard mean squared error loss.
 var MSE = &MSELoss{}
 
@@ -41,6 +90,19 @@ func (m *MSELoss) Compute(yHat, y *g.Node) (loss *g.Node, err error) {
 	return
 }
 
+// ComputeMasked computes the loss of the timesteps which are not masked.
+func (m *MSELoss) ComputeMasked(yHat, y, mask *g.Node) (loss *g.Node, err error) {
+	loss, err = g.Sub(yHat, y)
+	if err != nil {
+		return nil, err
+	}
+	loss, err = g.Square(loss)
+	if err != nil {
+		return nil, err
+	}
+	return maskedMean(loss, mask)
+}
+
 // CloneTo another graph.
 func (m *MSELoss) CloneTo(graph *g.ExprGraph, opts ...CloneOpt) Loss {
 	return &MSELoss{}
@@ -78,6 +140,37 @@ func (c *CrossEntropyLoss) Compute(yHat, y *g.Node) (loss *g.Node, err error) {
 	return
 }
 
+// ComputeMasked computes the loss of the timesteps which are not masked.
+func (c *CrossEntropyLoss) ComputeMasked(yHat, y, mask *g.Node) (loss *g.Node, err error) {
+	// masked predictions are set to one as they may be zero.
+	one := scalar(yHat, 1.0)
+	loss, err = g.Sub(yHat, one)
+	if err != nil {
+		return nil, err
+	}
+	loss, err = layer.ApplyMask(loss, mask)
+	if err != nil {
+		return nil, err
+	}
+	loss, err = g.Add(loss, one)
+	if err != nil {
+		return nil, err
+	}
+	loss, err = g.Log(loss)
+	if err != nil {
+		return nil, err
+	}
+	loss, err = g.HadamardProd(y, loss)
+	if err != nil {
+		return nil, err
+	}
+	loss, err = g.Neg(loss)
+	if err != nil {
+		return nil, err
+	}
+	return maskedMean(loss, mask)
+}
+
 // CloneTo another graph.
 func (c *CrossEntropyLoss) CloneTo(graph *g.ExprGraph, opts ...CloneOpt) Loss {
 	return &CrossEntropyLoss{}
@@ -116,7 +209,9 @@ func (h *PseudoHuberLoss) Compute(yHat, y *g.Node) (loss *g.Node, err error) {
 	if err != nil {
 		return nil, err
 	}
-	loss, err = g.Div(loss, g.NewScalar(yHat.Graph(), g.Float32, g.WithValue(float32(h.Delta))))
+	delta := scalar(yHat, float64(h.Delta))
+	one := scalar(yHat, 1.0)
+	loss, err = g.Div(loss, delta)
 	if err != nil {
 		return nil, err
 	}
@@ -124,7 +219,7 @@ func (h *PseudoHuberLoss) Compute(yHat, y *g.Node) (loss *g.Node, err error) {
 	if err != nil {
 		return nil, err
 	}
-	loss, err = g.Add(g.NewScalar(yHat.Graph(), g.Float32, g.WithValue(float32(1.0))), loss)
+	loss, err = g.Add(one, loss)
 	if err != nil {
 		return nil, err
 	}
@@ -132,11 +227,11 @@ func (h *PseudoHuberLoss) Compute(yHat, y *g.Node) (loss *g.Node, err error) {
 	if err != nil {
 		return nil, err
 	}
-	loss, err = g.Sub(loss, g.NewScalar(yHat.Graph(), g.Float32, g.WithValue(float32(1.0))))
+	loss, err = g.Sub(loss, one)
 	if err != nil {
 		return nil, err
 	}
-	deltaSquare, err := g.Square(g.NewScalar(yHat.Graph(), g.Float32, g.WithValue(float32(h.Delta))))
+	deltaSquare, err := g.Square(delta)
 	if err != nil {
 		return nil, err
 	}
@@ -172,15 +267,15 @@ type PseudoCrossEntropyLoss struct{}
 func (c *PseudoCrossEntropyLoss) Compute(yHat, y *g.Node) (loss *g.Node, err error) {
 	loss, err = g.HadamardProd(yHat, y)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	loss, err = g.Mean(loss)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	loss, err = g.Neg(loss)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	return
 }
@@ -194,3 +289,11 @@ func (c *PseudoCrossEntropyLoss) CloneTo(graph *g.ExprGraph, opts ...CloneOpt) L
 func (c *PseudoCrossEntropyLoss) Inputs() Inputs {
 	return Inputs{}
 }
+
+// scalar returns a constant scalar node of the value with the data type of the given node.
+func scalar(like *g.Node, value float64) *g.Node {
+	if like.Dtype() == g.Float64 {
+		return g.NewConstant(value)
+	}
+	return g.NewConstant(float32(value))
+}
@@ -0,0 +1,102 @@
+package model_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/aunum/goro/pkg/v1/layer"
+	. "github.com/aunum/goro/pkg/v1/model"
+
+	"github.com/stretchr/testify/require"
+	"gorgonia.org/tensor"
+)
+
+func TestMaskInput(t *testing.T) {
+	model, err := NewSequential("mask")
+	require.NoError(t, err)
+	err = model.AddLayers(
+		layer.TimeDistributed{Layer: layer.FC{Name: "steps", Output: 4, Activation: layer.Tanh}},
+		layer.GlobalAveragePooling1D{},
+		layer.FC{Name: "out", Output: 2, Activation: layer.Linear},
+	)
+	require.NoError(t, err)
+	x := NewInput("x", []int{1, 3, 2})
+	mask := NewInput("mask", []int{1, 3})
+	model.Mask(mask)
+	err = model.Compile(Inputs{x, mask}, NewInput("y", []int{1, 2}),
+		WithBatchSize(2),
+		WithoutTracker(),
+	)
+	require.NoError(t, err)
+
+	// the values of masked timesteps do not change the prediction.
+	m := tensor.New(tensor.WithShape(1, 3), tensor.WithBacking([]float32{1, 1, 0}))
+	expected, err := model.Predict(Values{tensor.New(tensor.WithShape(1, 3, 2), tensor.WithBacking([]float32{1, 2, 3, 4, 0, 0})), m})
+	require.NoError(t, err)
+	prediction, err := model.Predict(Values{tensor.New(tensor.WithShape(1, 3, 2), tensor.WithBacking([]float32{1, 2, 3, 4, 9, 9})), m})
+	require.NoError(t, err)
+	require.Equal(t, expected.Data(), prediction.Data())
+
+	xBatch := tensor.New(tensor.WithShape(2, 3, 2), tensor.WithBacking([]float32{1, 2, 3, 4, 9, 9, 4, 3, 9, 9, 9, 9}))
+	mBatch := tensor.New(tensor.WithShape(2, 3), tensor.WithBacking([]float32{1, 1, 0, 1, 0, 0}))
+	y := tensor.New(tensor.WithShape(2, 2), tensor.WithBacking([]float32{1, 0, 0, 1}))
+	require.NoError(t, model.FitBatch(Values{xBatch, mBatch}, y))
+	batchPrediction, err := model.PredictBatch(Values{xBatch, mBatch})
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, model.Save(buf))
+	loaded, err := Load(buf, WithoutTracker())
+	require.NoError(t, err)
+	require.Equal(t, "mask", loaded.MaskInput().Name())
+	loadedPrediction, err := loaded.PredictBatch(Values{xBatch, mBatch})
+	require.NoError(t, err)
+	require.Equal(t, batchPrediction.Data(), loadedPrediction.Data())
+
+	invalid, err := NewSequential("invalid")
+	require.NoError(t, err)
+	require.NoError(t, invalid.AddLayers(layer.GlobalAveragePooling1D{}))
+	invalid.Mask(NewInput("mask", []int{1, 2}))
+	err = invalid.Compile(Inputs{NewInput("x", []int{1, 3, 2}), NewInput("mask", []int{1, 2})}, NewInput("y", []int{1, 2}), WithoutTracker())
+	var shapeErr *ShapeError
+	require.True(t, errors.As(err, &shapeErr))
+}
+
+func TestMaskedLoss(t *testing.T) {
+	model, err := NewSequential("masked_loss")
+	require.NoError(t, err)
+	err = model.AddLayers(
+		layer.Masking{},
+		layer.TimeDistributed{Layer: layer.FC{Name: "steps", Output: 2, Activation: layer.Sigmoid}},
+	)
+	require.NoError(t, err)
+	err = model.Compile(NewInput("x", []int{1, 3, 2}), NewInput("y", []int{1, 3, 2}),
+		WithBatchSize(2),
+		WithLoss(CrossEntropy),
+		WithoutTracker(),
+	)
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	require.NoError(t, model.Save(buf))
+	saved := buf.Bytes()
+
+	// the targets of padded timesteps do not contribute to the gradients.
+	x := tensor.New(tensor.WithShape(2, 3, 2), tensor.WithBacking([]float32{1, 2, 3, 4, 0, 0, 4, 3, 0, 0, 0, 0}))
+	learnables := []interface{}{}
+	for _, padding := range []float32{0, 1} {
+		m, err := Load(bytes.NewReader(saved), WithoutTracker())
+		require.NoError(t, err)
+		y := tensor.New(tensor.WithShape(2, 3, 2), tensor.WithBacking([]float32{1, 0, 0, 1, padding, padding, 0, 1, padding, padding, padding, padding}))
+		require.NoError(t, m.FitBatch(x, y))
+		learnables = append(learnables, m.Learnables()[0].Value().Data())
+	}
+	require.Equal(t, learnables[0], learnables[1])
+
+	huber, err := NewSequential("huber_loss")
+	require.NoError(t, err)
+	require.NoError(t, huber.AddLayers(layer.Masking{}))
+	err = huber.Compile(NewInput("x", []int{1, 3, 2}), NewInput("y", []int{1, 3, 2}), WithLoss(PseudoHuber), WithoutTracker())
+	var configErr *ConfigError
+	require.True(t, errors.As(err, &configErr))
+}
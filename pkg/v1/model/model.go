@@ -4,13 +4,15 @@ package model
 import (
 	"fmt"
 	golog "log"
+	"runtime"
+	"sync"
 
 	"github.com/aunum/gold/pkg/v1/track"
-	cgraph "github.com/aunum/goro/pkg/v1/common/graph"
 	"github.com/aunum/goro/pkg/v1/layer"
 	"github.com/aunum/log"
 
 	g "gorgonia.org/gorgonia"
+	t "gorgonia.org/tensor"
 )
 
 // Model is a prediction model.
@@ -19,7 +21,7 @@ type Model interface {
 	Compile(x InputOr, y *Input, opts ...Opt) error
 
 	// Predict x.
-	Predict(x g.Value) (prediction g.Value, err error)
+	Predict(x ValueOr) (prediction g.Value, err error)
 
 	// Fit x to y.
 	Fit(x ValueOr, y g.Value) error
@@ -28,13 +30,13 @@ type Model interface {
 	FitBatch(x ValueOr, y g.Value) error
 
 	// PredictBatch predicts x as a batch
-	PredictBatch(x g.Value) (prediction g.Value, err error)
+	PredictBatch(x ValueOr) (prediction g.Value, err error)
 
 	// ResizeBatch resizes the batch graphs.
 	ResizeBatch(n int) error
 
 	// Visualize the model by graph name.
-	Visualize(name string)
+	Visualize(name string) error
 
 	// Graph returns the expression graph for the model.
 	Graphs() map[string]*g.ExprGraph
@@ -61,54 +63,76 @@ type Sequential struct {
 
 	name string
 
-	x   Inputs
-	y   *Input
-	fwd *Input
+	x    Inputs
+	y    *Input
+	fwd  *Input
+	mask *Input
 
-	trainChain, trainBatchChain   *layer.Chain
-	onlineChain, onlineBatchChain *layer.Chain
-	backwardChain                 *layer.Chain
+	mu sync.RWMutex
 
-	trainGraph, trainBatchGraph   *g.ExprGraph
-	onlineGraph, onlineBatchGraph *g.ExprGraph
-	backwardGraph                 *g.ExprGraph
+	trainChain    *layer.Chain
+	backwardChain *layer.Chain
 
-	xTrain, xTrainBatch         Inputs
-	xTrainFwd, xTrainBatchFwd   *Input
-	xOnline, xOnlineBatch       Inputs
-	xOnlineFwd, xOnlineBatchFwd *Input
+	trainGraph    *g.ExprGraph
+	backwardGraph *g.ExprGraph
 
-	yTrain, yTrainBatch *Input
+	xTrain    Inputs
+	xTrainFwd *Input
 
-	trainPredVal, trainBatchPredVal   g.Value
-	onlinePredVal, onlineBatchPredVal g.Value
+	yTrain *Input
 
-	loss                      Loss
-	trainLoss, trainBatchLoss Loss
+	trainPredVal g.Value
+	outputShape  t.Shape
 
-	metrics Metrics
+	trainBatches   map[int]*batchGraph
+	replicaBatches map[int][]*batchGraph
+	replicas       int
+	predictors     *predictorPool
+	maxPredictors  int
 
-	batchSize int
-	optimizer g.Solver
+	loss      Loss
+	trainLoss Loss
 
-	trainVM, trainBatchVM   g.VM
-	onlineVM, onlineBatchVM g.VM
-	backwardVM              g.VM
-	vmOpts                  []g.VMOpt
+	metrics   Metrics
+	batchLoss *track.TrackedScalarValue
+
+	dtype           t.Dtype
+	batchSize       int
+	maxBatchSize    int
+	optimizer       g.Solver
+	optimizerConfig *OptimizerConfig
+	gradClipper     GradClipper
+	gradNorm        *track.TrackedScalarValue
+	accumulator     *gradAccumulator
+
+	trainVM    g.VM
+	backwardVM g.VM
+	vmOpts     []g.VMOpt
 }
 
 // NewSequential returns a new sequential model.
 func NewSequential(name string) (*Sequential, error) {
+	chain, err := layer.NewChain()
+	if err != nil {
+		return nil, err
+	}
 	return &Sequential{
-		Chain:     layer.NewChain(),
-		name:      name,
-		batchSize: 32,
-		metrics:   AllMetrics,
+		Chain:         chain,
+		name:          name,
+		batchSize:     32,
+		replicas:      1,
+		maxPredictors: runtime.GOMAXPROCS(0),
+		metrics:       AllMetrics,
 	}, nil
 }
 
 // Opt is a model option.
-type Opt func(Model)
+type Opt func(Model) error
+
+// errUnknownModel is returned by options applied to a model type they do not support.
+func errUnknownModel(m Model) error {
+	return &ConfigError{Name: "model option", Reason: fmt.Sprintf("unknown model type %T", m)}
+}
 
 // Metric tracked by the model.
 type Metric string
@@ -119,6 +143,12 @@ const (
 
 	// TrainBatchLossMetric is the metric for batch training loss.
 	TrainBatchLossMetric Metric = "train_batch_loss"
+
+	// LearnRateMetric is the metric for the learning rate of a scheduled optimizer.
+	LearnRateMetric Metric = "learn_rate"
+
+	// GradNormMetric is the metric for the global norm of the gradients prior to any clipping.
+	GradNormMetric Metric = "grad_norm"
 )
 
 // Metrics is a set of metric.
@@ -135,119 +165,243 @@ func (m Metrics) Contains(metric Metric) bool {
 }
 
 // AllMetrics are all metrics.
-var AllMetrics = Metrics{TrainLossMetric, TrainBatchLossMetric}
+var AllMetrics = Metrics{TrainLossMetric, TrainBatchLossMetric, LearnRateMetric, GradNormMetric}
 
 // WithMetrics sets the metrics that the model should track.
 // Defaults to AllMetrics.
-func WithMetrics(metrics ...Metric) func(Model) {
-	return func(m Model) {
+func WithMetrics(metrics ...Metric) func(Model) error {
+	return func(m Model) error {
 		switch t := m.(type) {
 		case *Sequential:
 			t.metrics = metrics
 		default:
-			log.Fatal("unknown model type")
+			return errUnknownModel(m)
 		}
+		return nil
 	}
 }
 
 // WithLoss uses a specific loss function with the model.
 // Defaults to MSE.
-func WithLoss(loss Loss) func(Model) {
-	return func(m Model) {
+func WithLoss(loss Loss) func(Model) error {
+	return func(m Model) error {
 		switch t := m.(type) {
 		case *Sequential:
+			if loss == nil {
+				return &ConfigError{Name: "loss", Reason: "must not be nil"}
+			}
 			t.loss = loss
 		default:
-			log.Fatal("unknown model type")
+			return errUnknownModel(m)
 		}
+		return nil
 	}
 }
 
-// WithOptimizer uses a specific optimizer function.
+// WithOptimizer uses a specific optimizer function, use a ScheduledOptimizer to vary the learning rate.
 // Defaults to Adam.
-func WithOptimizer(optimizer g.Solver) func(Model) {
-	return func(m Model) {
+func WithOptimizer(optimizer g.Solver) func(Model) error {
+	return func(m Model) error {
 		switch t := m.(type) {
 		case *Sequential:
+			if optimizer == nil {
+				return &ConfigError{Name: "optimizer", Reason: "must not be nil"}
+			}
 			t.optimizer = optimizer
+			t.optimizerConfig = nil
+		default:
+			return errUnknownModel(m)
+		}
+		return nil
+	}
+}
+
+// WithOptimizerConfig uses the optimizer of the config, which is kept in the definition of the model.
+func WithOptimizerConfig(config OptimizerConfig) func(Model) error {
+	return func(m Model) error {
+		switch t := m.(type) {
+		case *Sequential:
+			optimizer, err := config.Solver()
+			if err != nil {
+				return err
+			}
+			t.optimizer = optimizer
+			t.optimizerConfig = &config
+		default:
+			return errUnknownModel(m)
+		}
+		return nil
+	}
+}
+
+// WithGradClip clips the gradients before each optimizer step, the bound of the clippers in this package must be greater than 0.
+func WithGradClip(clipper GradClipper) func(Model) error {
+	return func(m Model) error {
+		switch t := m.(type) {
+		case *Sequential:
+			err := validateClipper(clipper)
+			if err != nil {
+				return err
+			}
+			t.gradClipper = clipper
+		default:
+			return errUnknownModel(m)
+		}
+		return nil
+	}
+}
+
+// WithGradAccumulation accumulates the gradients of a number of consecutive Fit or FitBatch calls,
+// reducing them and stepping the optimizer once. This gives an effective batch size of steps*batchSize.
+func WithGradAccumulation(steps int, reduce Accumulation) func(Model) error {
+	return func(m Model) error {
+		switch t := m.(type) {
+		case *Sequential:
+			if steps < 1 {
+				return &ConfigError{Name: "gradient accumulation", Reason: fmt.Sprintf("steps must be at least 1, got %d", steps)}
+			}
+			t.accumulator = nil
+			if steps > 1 {
+				t.accumulator = newGradAccumulator(steps, reduce)
+			}
 		default:
-			log.Fatal("unknown model type")
+			return errUnknownModel(m)
 		}
+		return nil
 	}
 }
 
 // WithTracker adds a tracker to the model, if not provided one will be created.
-func WithTracker(tracker *track.Tracker) func(Model) {
-	return func(m Model) {
+func WithTracker(tracker *track.Tracker) func(Model) error {
+	return func(m Model) error {
 		switch t := m.(type) {
 		case *Sequential:
 			t.Tracker = tracker
 		default:
-			log.Fatal("unknown model type")
+			return errUnknownModel(m)
 		}
+		return nil
 	}
 }
 
 // WithoutTracker uses no tracking with the model.
-func WithoutTracker() func(Model) {
-	return func(m Model) {
+func WithoutTracker() func(Model) error {
+	return func(m Model) error {
 		switch t := m.(type) {
 		case *Sequential:
 			t.noTracker = true
 		default:
-			log.Fatal("unknown model type")
+			return errUnknownModel(m)
 		}
+		return nil
 	}
 }
 
 // WithBatchSize sets the batch size for the model.
 // Defaults to 32.
-func WithBatchSize(size int) func(Model) {
-	return func(m Model) {
+func WithBatchSize(size int) func(Model) error {
+	return func(m Model) error {
 		switch t := m.(type) {
 		case *Sequential:
+			if size < 1 {
+				return &ConfigError{Name: "batch size", Reason: fmt.Sprintf("must be at least 1, got %d", size)}
+			}
 			t.batchSize = size
 		default:
-			log.Fatal("unknown model type")
+			return errUnknownModel(m)
 		}
+		return nil
+	}
+}
+
+// WithMaxBatchSize sets the largest batch size the batch graphs will accept. Graphs are compiled
+// and cached for each batch size as it is encountered, allowing FitBatch and PredictBatch to take
+// any batch size up to the max.
+// Defaults to the batch size.
+func WithMaxBatchSize(size int) func(Model) error {
+	return func(m Model) error {
+		switch t := m.(type) {
+		case *Sequential:
+			if size < 1 {
+				return &ConfigError{Name: "max batch size", Reason: fmt.Sprintf("must be at least 1, got %d", size)}
+			}
+			t.maxBatchSize = size
+		default:
+			return errUnknownModel(m)
+		}
+		return nil
+	}
+}
+
+// WithMaxPredictors sets the max number of online graphs compiled for each batch size to predict
+// concurrently, further concurrent predictions of the batch size wait for a graph to be free.
+// Defaults to GOMAXPROCS.
+func WithMaxPredictors(max int) func(Model) error {
+	return func(m Model) error {
+		switch t := m.(type) {
+		case *Sequential:
+			if max < 1 {
+				return &ConfigError{Name: "max predictors", Reason: fmt.Sprintf("must be at least 1, got %d", max)}
+			}
+			t.maxPredictors = max
+		default:
+			return errUnknownModel(m)
+		}
+		return nil
+	}
+}
+
+// WithDType sets the data type of the model, which is propagated to the inputs, layers and loss.
+// Inputs explicitly typed with AsType must match the model data type if they are the forward input
+// or the expected output.
+// Defaults to the data type of the forward input.
+func WithDType(dtype t.Dtype) func(Model) error {
+	return func(m Model) error {
+		switch t := m.(type) {
+		case *Sequential:
+			t.dtype = dtype
+		default:
+			return errUnknownModel(m)
+		}
+		return nil
 	}
 }
 
 // WithGraphLogger adds a logger to the model which will print out the graph operations
 // as they occur.
-func WithGraphLogger(log *golog.Logger) func(Model) {
-	return func(m Model) {
+func WithGraphLogger(logger *golog.Logger) func(Model) error {
+	return func(m Model) error {
 		switch t := m.(type) {
 		case *Sequential:
-			t.vmOpts = append(t.vmOpts, g.WithLogger(log))
+			t.vmOpts = append(t.vmOpts, g.WithLogger(logger))
 		default:
-			log.Fatal("unknown model type")
+			return errUnknownModel(m)
 		}
+		return nil
 	}
 }
 
 // WithLogger adds a logger to the model.
-func WithLogger(logger *log.Logger) func(Model) {
-	return func(m Model) {
+func WithLogger(logger *log.Logger) func(Model) error {
+	return func(m Model) error {
 		switch t := m.(type) {
 		case *Sequential:
 			t.logger = logger
 		default:
-			log.Fatal("unknown model type")
+			return errUnknownModel(m)
 		}
+		return nil
 	}
 }
 
 // AddLayer adds a layer.
-func (s *Sequential) AddLayer(layer layer.Config) {
-	s.Chain.Add(layer)
+func (s *Sequential) AddLayer(layer layer.Config) error {
+	return s.Chain.Add(layer)
 }
 
-// AddLayers adds a number of layer.
-func (s *Sequential) AddLayers(layers ...layer.Config) {
-	for _, layer := range layers {
-		s.Chain.Add(layer)
-	}
+// AddLayers adds a number of layer, if any layer is invalid none are added.
+func (s *Sequential) AddLayers(layers ...layer.Config) error {
+	return s.Chain.Add(layers...)
 }
 
 // Fwd tells the model which input should be sent through the layer.
@@ -256,8 +410,41 @@ func (s *Sequential) Fwd(x *Input) {
 	s.fwd = x
 }
 
+// Mask tells the model which input masks the timesteps of the forward input, a sequence of shape
+// (batch, time, x...). The mask is of shape (batch, time) with ones for the timesteps to use and zeros
+// for those to ignore, such as padding. Masked timesteps do not contribute to the loss.
+func (s *Sequential) Mask(mask *Input) {
+	s.mask = mask
+}
+
+// validateMask validates the mask is an x input of shape (batch, time) of the forward input.
+func (s *Sequential) validateMask() error {
+	if s.mask == nil {
+		return nil
+	}
+	if !s.x.Contains(s.mask.Name()) {
+		return &ConfigError{Name: fmt.Sprintf("mask %q", s.mask.Name()), Reason: "mask must be one of the x inputs"}
+	}
+	if s.mask.Name() == s.fwd.Name() {
+		return &ConfigError{Name: fmt.Sprintf("mask %q", s.mask.Name()), Reason: "mask cannot be the forward input"}
+	}
+	fwd := s.fwd.Shape()
+	if len(fwd) < 3 || !s.mask.Shape().Eq(fwd[:2]) {
+		return &ShapeError{Name: fmt.Sprintf("mask %q", s.mask.Name()), Expected: fmt.Sprintf("(batch, time) of the forward input of shape %v", fwd), Actual: s.mask.Shape()}
+	}
+	return nil
+}
+
 // Compile the model.
 func (s *Sequential) Compile(x InputOr, y *Input, opts ...Opt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if x == nil || len(x.Inputs()) == 0 {
+		return &ConfigError{Name: fmt.Sprintf("model %q", s.name), Reason: "no x inputs provided"}
+	}
+	if y == nil {
+		return &ConfigError{Name: fmt.Sprintf("model %q", s.name), Reason: "no y input provided"}
+	}
 	s.x = x.Inputs()
 	err := y.Validate()
 	if err != nil {
@@ -266,7 +453,10 @@ func (s *Sequential) Compile(x InputOr, y *Input, opts ...Opt) error {
 	s.y = y
 
 	for _, opt := range opts {
-		opt(s)
+		err = opt(s)
+		if err != nil {
+			return err
+		}
 	}
 	if s.logger == nil {
 		s.logger = log.DefaultLogger
@@ -276,6 +466,7 @@ func (s *Sequential) Compile(x InputOr, y *Input, opts ...Opt) error {
 	}
 	if s.optimizer == nil {
 		s.optimizer = g.NewAdamSolver()
+		s.optimizerConfig = &OptimizerConfig{Type: "adam"}
 	}
 	if s.Tracker == nil && !s.noTracker {
 		tracker, err := track.NewTracker(track.WithLogger(s.logger))
@@ -284,27 +475,54 @@ func (s *Sequential) Compile(x InputOr, y *Input, opts ...Opt) error {
 		}
 		s.Tracker = tracker
 	}
+	if so, ok := s.optimizer.(*ScheduledOptimizer); ok && s.Tracker != nil && s.metrics.Contains(LearnRateMetric) {
+		so.track(s.Tracker, s.name)
+	}
+	if s.Tracker != nil && s.metrics.Contains(TrainBatchLossMetric) {
+		s.batchLoss = s.Tracker.TrackValue(string(TrainBatchLossMetric), 0.0, track.WithNamespace(s.name)).(*track.TrackedScalarValue)
+	}
+	if s.Tracker != nil && s.metrics.Contains(GradNormMetric) {
+		s.gradNorm = s.Tracker.TrackValue(string(GradNormMetric), 0.0, track.WithNamespace(s.name)).(*track.TrackedScalarValue)
+	}
 	if s.fwd == nil {
 		s.fwd = x.Inputs()[0]
-		err = s.fwd.Validate()
-		if err != nil {
-			return err
-		}
 		s.logger.Infof("setting forward for layers to input %q", s.fwd.Name())
 	}
+	err = s.fwd.Validate()
+	if err != nil {
+		return err
+	}
+	err = s.validateMask()
+	if err != nil {
+		return err
+	}
+	err = s.resolveDType()
+	if err != nil {
+		return err
+	}
+	_, err = s.Chain.InferShapes(s.fwd.Shape())
+	if err != nil {
+		return err
+	}
 	err = s.buildTrainGraph(s.x, s.y)
 	if err != nil {
 		return err
 	}
-	err = s.buildTrainBatchGraph(s.x, s.y)
+	if s.maxBatchSize < s.batchSize {
+		s.maxBatchSize = s.batchSize
+	}
+	s.trainBatches = map[int]*batchGraph{}
+	s.replicaBatches = nil
+	_, err = s.trainBatch(s.batchSize)
 	if err != nil {
 		return err
 	}
-	err = s.buildOnlineGraph(s.x)
+	s.predictors = newPredictorPool(s.maxPredictors)
+	err = s.ensurePredictor(onlineSize)
 	if err != nil {
 		return err
 	}
-	err = s.buildOnlineBatchGraph(s.x)
+	err = s.ensurePredictor(s.batchSize)
 	if err != nil {
 		return err
 	}
@@ -320,7 +538,10 @@ func (s *Sequential) buildTrainGraph(x Inputs, y *Input) (err error) {
 			s.xTrain = append(s.xTrain, i)
 			continue
 		}
-		i := input.CloneTo(s.trainGraph)
+		i, err := input.CloneTo(s.trainGraph)
+		if err != nil {
+			return err
+		}
 		s.xTrain = append(s.xTrain, i)
 	}
 
@@ -332,18 +553,35 @@ func (s *Sequential) buildTrainGraph(x Inputs, y *Input) (err error) {
 	s.trainLoss = s.loss.CloneTo(s.trainGraph)
 
 	s.yTrain = y.Clone()
-	s.yTrain.Compile(s.trainGraph)
+	_, err = s.yTrain.Compile(s.trainGraph)
+	if err != nil {
+		return err
+	}
 
 	s.trainChain = s.Chain.Clone()
-	s.trainChain.Compile(s.trainGraph)
+	err = s.trainChain.Compile(s.trainGraph, layer.WithLayerOpts(layer.AsType(s.dtype)))
+	if err != nil {
+		return err
+	}
+	if s.mask != nil {
+		mask, err := s.xTrain.Get(s.mask.Name())
+		if err != nil {
+			return err
+		}
+		s.trainChain.SetMask(mask.Node())
+	}
 
 	prediction, err := s.trainChain.Fwd(s.xTrainFwd.Node())
 	if err != nil {
 		return err
 	}
 	g.Read(prediction, &s.trainPredVal)
+	s.outputShape = prediction.Shape().Clone()
+	if prediction.Dtype() != s.yTrain.DType() {
+		return &DTypeError{Name: fmt.Sprintf("prediction of y %q", s.y.Name()), Expected: s.y.DType(), Actual: prediction.Dtype()}
+	}
 
-	loss, err := s.trainLoss.Compute(prediction, s.yTrain.Node())
+	loss, err := computeLoss(s.trainLoss, prediction, s.yTrain.Node(), s.trainChain.Mask())
 	if err != nil {
 		return err
 	}
@@ -359,180 +597,99 @@ func (s *Sequential) buildTrainGraph(x Inputs, y *Input) (err error) {
 		return err
 	}
 
-	vmOpts := []g.VMOpt{}
-	copy(vmOpts, s.vmOpts)
+	vmOpts := append([]g.VMOpt{}, s.vmOpts...)
 	vmOpts = append(vmOpts, g.BindDualValues(s.trainChain.Learnables()...))
 	s.trainVM = g.NewTapeMachine(s.trainGraph, vmOpts...)
 	return nil
 }
 
-func (s *Sequential) buildTrainBatchGraph(x Inputs, y *Input) (err error) {
-	s.trainBatchGraph = g.NewGraph()
-
-	s.trainBatchLoss = s.loss.CloneTo(s.trainBatchGraph, AsBatch(s.batchSize))
-	for _, input := range x {
-		// TODO: need to validate input names for duplicates.
-		if i, err := s.trainBatchLoss.Inputs().Get(input.Name()); err == nil {
-			s.xTrainBatch = append(s.xTrainBatch, i)
-			continue
-		}
-		i := input.CloneTo(s.trainBatchGraph, AsBatch(s.batchSize))
-		s.xTrainBatch = append(s.xTrainBatch, i)
-	}
-
-	s.xTrainBatchFwd, err = s.xTrainBatch.Get(NameAsBatch(s.fwd.Name()))
+// ResizeBatch sets the default batch size of the model, compiling the batch graphs for the size
+// if they have not been compiled. The max batch size is increased to n if needed.
+// Note: compiling a graph is expensive, graphs are cached for each batch size.
+func (s *Sequential) ResizeBatch(n int) (err error) {
+	log.Debugf("resizing batch graphs to %d", n)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = s.checkCompiled()
 	if err != nil {
 		return err
 	}
-
-	s.yTrainBatch = s.y.AsBatch(s.batchSize)
-	s.yTrainBatch.Compile(s.trainBatchGraph)
-
-	s.trainBatchChain = s.Chain.Clone()
-	s.trainBatchChain.Compile(s.trainBatchGraph, layer.WithSharedChainLearnables(s.trainChain), layer.WithLayerOpts(layer.AsBatch()))
-
-	prediction, err := s.trainBatchChain.Fwd(s.xTrainBatchFwd.Node())
-	if err != nil {
-		return err
+	if n > s.maxBatchSize {
+		s.maxBatchSize = n
 	}
-	g.Read(prediction, &s.trainBatchPredVal)
-	loss, err := s.trainBatchLoss.Compute(prediction, s.yTrainBatch.Node())
+	_, err = s.trainBatch(n)
 	if err != nil {
 		return err
 	}
-
-	if s.metrics.Contains(TrainBatchLossMetric) {
-		if s.Tracker != nil {
-			s.Tracker.TrackValue("train_batch_loss", loss, track.WithNamespace(s.name))
-		}
-	}
-
-	_, err = g.Grad(loss, s.trainBatchChain.Learnables()...)
+	err = s.ensurePredictor(n)
 	if err != nil {
 		return err
 	}
-
-	vmOpts := []g.VMOpt{}
-	copy(vmOpts, s.vmOpts)
-	vmOpts = append(vmOpts, g.BindDualValues(s.trainBatchChain.Learnables()...))
-	s.trainBatchVM = g.NewTapeMachine(s.trainBatchGraph, vmOpts...)
-
+	s.batchSize = n
 	return nil
 }
 
-func (s *Sequential) buildOnlineGraph(x Inputs) (err error) {
-	s.onlineGraph = g.NewGraph()
-
-	s.xOnline = s.x.Clone()
-	s.xOnline.Compile(s.onlineGraph)
-
-	s.xOnlineFwd, err = s.xOnline.Get(s.fwd.Name())
+// Predict x, which is the value of the forward input or the values of all x inputs in order, such as
+// with a mask. Predictions are safe to make concurrently and the returned value is owned
+// by the caller.
+func (s *Sequential) Predict(x ValueOr) (prediction g.Value, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	err = s.checkCompiled()
 	if err != nil {
-		return err
+		return prediction, err
 	}
-
-	s.onlineChain = s.Chain.Clone()
-	s.onlineChain.Compile(s.onlineGraph, layer.WithSharedChainLearnables(s.trainChain))
-
-	prediction, err := s.onlineChain.Fwd(s.xOnlineFwd.Node())
+	xVals, err := ValuesFrom(x)
 	if err != nil {
-		return err
+		return prediction, err
 	}
-	g.Read(prediction, &s.onlinePredVal)
-
-	vmOpts := []g.VMOpt{}
-	copy(vmOpts, s.vmOpts)
-	s.onlineVM = g.NewTapeMachine(s.onlineGraph, vmOpts...)
-	return nil
+	return s.predict(onlineSize, xVals)
 }
 
-func (s *Sequential) buildOnlineBatchGraph(x Inputs) (err error) {
-	s.onlineBatchGraph = g.NewGraph()
-
-	for _, input := range x {
-		if input.Name() == s.fwd.Name() {
-			s.xOnlineBatchFwd = input.AsBatch(s.batchSize)
-			s.xOnlineBatchFwd.Compile(s.onlineBatchGraph)
-			s.xOnlineBatch = append(s.xOnlineBatch, s.xOnlineBatchFwd)
-			continue
-		}
-		i := input.CloneTo(s.onlineBatchGraph)
-		s.xOnlineBatch = append(s.xOnlineBatch, i)
-	}
-
-	s.xOnlineBatchFwd, err = s.xOnlineBatch.Get(NameAsBatch(s.fwd.Name()))
+// PredictBatch predicts x as a batch, the batch may be any size up to the max batch size. X is the value
+// of the forward input or the values of all x inputs in order, such as with a mask. Predictions are safe to make concurrently and the returned value is owned by the caller.
+func (s *Sequential) PredictBatch(x ValueOr) (prediction g.Value, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	err = s.checkCompiled()
 	if err != nil {
-		return err
+		return prediction, err
 	}
-
-	s.onlineBatchChain = s.Chain.Clone()
-	s.onlineBatchChain.Compile(s.onlineBatchGraph, layer.WithSharedChainLearnables(s.trainChain), layer.WithLayerOpts(layer.AsBatch()))
-
-	prediction, err := s.onlineBatchChain.Fwd(s.xOnlineBatchFwd.Node())
+	xVals, err := ValuesFrom(x)
 	if err != nil {
-		return err
+		return prediction, err
 	}
-	g.Read(prediction, &s.onlineBatchPredVal)
-
-	vmOpts := []g.VMOpt{}
-	copy(vmOpts, s.vmOpts)
-	s.onlineBatchVM = g.NewTapeMachine(s.onlineBatchGraph, vmOpts...)
-	return nil
-}
-
-// ResizeBatch will resize the batch graph.
-// Note: this is expensive as it recompiles the graph.
-func (s *Sequential) ResizeBatch(n int) (err error) {
-	log.Debugf("resizing batch graphs to %d", n)
-	s.batchSize = n
-	s.xTrainBatch = Inputs{}
-	s.xTrainBatchFwd = nil
-	err = s.buildTrainBatchGraph(s.x, s.y)
+	fwd, err := s.fwdValue(xVals)
 	if err != nil {
-		return
+		return prediction, err
 	}
-	s.xOnlineBatch = Inputs{}
-	s.xOnlineBatchFwd = nil
-	return s.buildOnlineBatchGraph(s.x)
-}
-
-// Predict x.
-func (s *Sequential) Predict(x g.Value) (prediction g.Value, err error) {
-	err = s.xOnlineFwd.Set(x)
+	size, err := batchSizeOf(fwd)
 	if err != nil {
 		return prediction, err
 	}
-	err = s.onlineVM.RunAll()
+	err = s.checkBatchSize(size)
 	if err != nil {
 		return prediction, err
 	}
-	prediction = s.onlinePredVal
-	s.onlineVM.Reset()
-	return
+	return s.predict(size, xVals)
 }
 
-// PredictBatch predicts x as a batch.
-func (s *Sequential) PredictBatch(x g.Value) (prediction g.Value, err error) {
-	err = s.xOnlineBatchFwd.Set(x)
+// Fit x to y. Fitting blocks any concurrent predictions.
+func (s *Sequential) Fit(x ValueOr, y g.Value) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.checkCompiled()
 	if err != nil {
-		return prediction, err
+		return err
 	}
-	err = s.onlineBatchVM.RunAll()
+	err = s.yTrain.Set(y)
 	if err != nil {
-		return prediction, err
+		return err
 	}
-	prediction = s.onlineBatchPredVal
-	s.onlineBatchVM.Reset()
-	return
-}
-
-// Fit x to y.
-func (s *Sequential) Fit(x ValueOr, y g.Value) error {
-	err := s.yTrain.Set(y)
+	xVals, err := ValuesFrom(x)
 	if err != nil {
 		return err
 	}
-	xVals := ValuesFrom(x)
 	err = s.xTrain.Set(xVals)
 	if err != nil {
 		return err
@@ -542,49 +699,125 @@ func (s *Sequential) Fit(x ValueOr, y g.Value) error {
 	if err != nil {
 		return err
 	}
-	grads := g.NodesToValueGrads(s.trainChain.Learnables())
-	s.optimizer.Step(grads)
+	err = s.step(s.trainChain.Learnables())
+	if err != nil {
+		return err
+	}
 	s.trainVM.Reset()
 	return nil
 }
 
-// FitBatch fits x to y as a batch.
+// NextEpoch advances a scheduled optimizer to the next epoch, training loops call this at the end of each epoch.
+func (s *Sequential) NextEpoch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if so, ok := s.optimizer.(*ScheduledOptimizer); ok {
+		so.NextEpoch()
+	}
+}
+
+// FitBatch fits x to y as a batch, the batch may be any size up to the max batch size.
+// Fitting blocks any concurrent predictions.
 func (s *Sequential) FitBatch(x ValueOr, y g.Value) error {
-	err := s.yTrainBatch.Set(y)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.checkCompiled()
 	if err != nil {
 		return err
 	}
-
-	xVals := ValuesFrom(x)
-	err = s.xTrainBatch.Set(xVals)
+	size, err := batchSizeOf(y)
+	if err != nil {
+		return err
+	}
+	xVals, err := ValuesFrom(x)
+	if err != nil {
+		return err
+	}
+	if s.replicas > 1 && size > 1 {
+		err = s.checkBatchSize(size)
+		if err != nil {
+			return err
+		}
+		return s.fitParallel(size, xVals, y)
+	}
+	b, err := s.trainBatch(size)
+	if err != nil {
+		return err
+	}
+	err = b.y.Set(y)
+	if err != nil {
+		return err
+	}
+	err = b.x.Set(xVals)
 	if err != nil {
 		return err
 	}
 
-	err = s.trainBatchVM.RunAll()
+	err = b.vm.RunAll()
 	if err != nil {
 		return err
 	}
-	// log.Infovb("pred val", s.trainBatchPredVal)
-	grads := g.NodesToValueGrads(s.trainBatchChain.Learnables())
-	s.optimizer.Step(grads)
-	s.trainBatchVM.Reset()
+	if s.batchLoss != nil {
+		s.batchLoss.Set(b.lossVal.Data())
+	}
+	err = s.step(b.chain.Learnables())
+	if err != nil {
+		return err
+	}
+	b.vm.Reset()
 	return nil
 }
 
-// Visualize the model by graph name.
-func (s *Sequential) Visualize(name string) {
-	cgraph.Visualize(s.Graphs()[name])
+// step the optimizer with the gradients of the given learnables, accumulating and clipping them if configured.
+func (s *Sequential) step(learnables g.Nodes) error {
+	grads := g.NodesToValueGrads(learnables)
+	if s.accumulator != nil {
+		ready, err := s.accumulator.add(grads)
+		if err != nil {
+			return err
+		}
+		if !ready {
+			return nil
+		}
+	}
+	if s.gradClipper != nil || s.gradNorm != nil {
+		norm, err := GlobalNorm(grads)
+		if err != nil {
+			return err
+		}
+		if s.gradNorm != nil {
+			s.gradNorm.Set(norm)
+		}
+		if s.gradClipper != nil {
+			err = s.gradClipper.Clip(grads, norm)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return s.optimizer.Step(grads)
 }
 
 // Graphs returns the expression graphs for the model.
 func (s *Sequential) Graphs() map[string]*g.ExprGraph {
-	return map[string]*g.ExprGraph{
-		"train":       s.trainGraph,
-		"trainBatch":  s.trainBatchGraph,
-		"online":      s.onlineGraph,
-		"onlineBatch": s.onlineBatchGraph,
+	graphs := map[string]*g.ExprGraph{
+		"train": s.trainGraph,
+	}
+	if b, ok := s.trainBatches[s.batchSize]; ok {
+		graphs["trainBatch"] = b.graph
 	}
+	if s.predictors == nil {
+		return graphs
+	}
+	s.predictors.mu.Lock()
+	defer s.predictors.mu.Unlock()
+	if b, ok := s.predictors.primary[onlineSize]; ok {
+		graphs["online"] = b.graph
+	}
+	if b, ok := s.predictors.primary[s.batchSize]; ok {
+		graphs["onlineBatch"] = b.graph
+	}
+	return graphs
 }
 
 // X is is the input to the model.
@@ -597,67 +830,102 @@ func (s *Sequential) Y() *Input {
 	return s.y
 }
 
-// Learnables are the model learnables.
+// MaskInput is the input which masks the forward input, nil if it is not masked.
+func (s *Sequential) MaskInput() *Input {
+	return s.mask
+}
+
+// FwdInput is the input which is sent through the layers.
+func (s *Sequential) FwdInput() *Input {
+	return s.fwd
+}
+
+// OutputShape is the shape of a single prediction, nil if the model has not been compiled.
+func (s *Sequential) OutputShape() t.Shape {
+	return s.outputShape
+}
+
+// Name of the model.
+func (s *Sequential) Name() string {
+	return s.name
+}
+
+// BatchSize is the default batch size of the model.
+func (s *Sequential) BatchSize() int {
+	return s.batchSize
+}
+
+// MaxBatchSize is the largest batch size the model will accept.
+func (s *Sequential) MaxBatchSize() int {
+	return s.maxBatchSize
+}
+
+// Learnables are the model learnables, nil if the model has not been compiled.
 func (s *Sequential) Learnables() g.Nodes {
+	if s.trainChain == nil {
+		return nil
+	}
 	return s.trainChain.Learnables()
 }
 
 // CloneLearnablesTo another model.
 func (s *Sequential) CloneLearnablesTo(to *Sequential) error {
-	desired := s.trainChain.Learnables()
-	destination := to.trainChain.Learnables()
-	if len(desired) != len(destination) {
-		return fmt.Errorf("models must be identical to clone learnables")
-	}
-	for i, learnable := range destination {
-		c := desired[i].Clone()
-		err := g.Let(learnable, c.(*g.Node).Value())
-		if err != nil {
-			return err
-		}
+	if to == s {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	to.mu.Lock()
+	defer to.mu.Unlock()
+	if err := s.checkCompiled(); err != nil {
+		return err
 	}
-	new := to.trainChain.Learnables()
-	shared := map[string]*layer.Chain{
-		"trainBatch":  to.trainBatchChain,
-		"online":      to.onlineChain,
-		"onlineBatch": to.onlineBatchChain,
+	if err := to.checkCompiled(); err != nil {
+		return err
 	}
-	for name, chain := range shared {
-		s.logger.Debugv("chain", name)
-		for i, learnable := range chain.Learnables() {
-			err := g.Let(learnable, new[i].Value())
-			if err != nil {
-				return err
-			}
-			s.logger.Debugvb(learnable.Name(), learnable.Value())
-		}
+	values, err := learnableValues(s.trainChain.Learnables())
+	if err != nil {
+		return err
 	}
-	return nil
+	return to.letLearnables(values)
 }
 
 // SetLearnables sets learnables to model
 func (s *Sequential) SetLearnables(desired g.Nodes) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkCompiled(); err != nil {
+		return err
+	}
+	values, err := learnableValues(desired)
+	if err != nil {
+		return err
+	}
+	return s.letLearnables(values)
+}
+
+// letLearnables sets the values of the learnables, propagating them to every chain which shares them.
+func (s *Sequential) letLearnables(values []g.Value) error {
 	destination := s.trainChain.Learnables()
-	if len(desired) != len(destination) {
-		return fmt.Errorf("cannot set learnables: number of desired nodes not equal to number of nodes in model")
+	if len(values) != len(destination) {
+		return &ConfigError{Name: fmt.Sprintf("model %q", s.name), Reason: fmt.Sprintf("cannot set %d learnables on a model with %d learnables", len(values), len(destination))}
 	}
 	for i, learnable := range destination {
-		c := desired[i].Clone()
-		err := g.Let(learnable, c.(*g.Node).Value())
+		if !learnable.Shape().Eq(values[i].Shape()) {
+			return &ShapeError{Name: fmt.Sprintf("learnable %q", learnable.Name()), Expected: fmt.Sprintf("%v", learnable.Shape()), Actual: values[i].Shape()}
+		}
+		if learnable.Dtype() != values[i].Dtype() {
+			return &DTypeError{Name: fmt.Sprintf("learnable %q", learnable.Name()), Expected: learnable.Dtype(), Actual: values[i].Dtype()}
+		}
+		err := g.Let(learnable, values[i])
 		if err != nil {
 			return err
 		}
 	}
-	new := s.trainChain.Learnables()
-	shared := map[string]*layer.Chain{
-		"trainBatch":  s.trainBatchChain,
-		"online":      s.onlineChain,
-		"onlineBatch": s.onlineBatchChain,
-	}
-	for name, chain := range shared {
+	for name, chain := range s.sharedChains() {
 		s.logger.Debugv("chain", name)
 		for i, learnable := range chain.Learnables() {
-			err := g.Let(learnable, new[i].Value())
+			err := g.Let(learnable, destination[i].Value())
 			if err != nil {
 				return err
 			}
@@ -667,6 +935,19 @@ func (s *Sequential) SetLearnables(desired g.Nodes) error {
 	return nil
 }
 
+// learnableValues returns copies of the values of the learnables.
+func learnableValues(learnables g.Nodes) ([]g.Value, error) {
+	values := []g.Value{}
+	for _, learnable := range learnables {
+		v, err := g.CloneValue(learnable.Value())
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
 // Opts are optsion for a model
 type Opts struct {
 	opts []Opt
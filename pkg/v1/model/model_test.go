@@ -1,6 +1,7 @@
 package model_test
 
 import (
+	"errors"
 	"fmt"
 	golog "log"
 	"os"
@@ -49,11 +50,12 @@ func TestSequential(t *testing.T) {
 
 	model, err := NewSequential("test")
 	require.NoError(t, err)
-	model.AddLayers(
+	err = model.AddLayers(
 		layer.FC{Input: 5, Output: 24, Activation: layer.Sigmoid, Name: "w0"},
 		layer.FC{Input: 24, Output: 24, Activation: layer.Sigmoid, Name: "w1"},
 		layer.FC{Input: 24, Output: 2, Activation: layer.Linear, Name: "w2"},
 	)
+	require.NoError(t, err)
 
 	optimizer := g.NewAdamSolver()
 	model.Fwd(xi)
@@ -92,3 +94,196 @@ func TestSequential(t *testing.T) {
 	log.Infov("y0", y0)
 	log.Infov("final single prediction", prediction)
 }
+
+func TestGradAccumulation(t *testing.T) {
+	batchSize, lr := 4, 0.1
+	x1 := tensor.New(tensor.WithShape(batchSize, 3), tensor.WithBacking(tensor.Range(tensor.Float32, 0, 12)))
+	y1 := tensor.New(tensor.WithShape(batchSize, 2), tensor.WithBacking(tensor.Range(tensor.Float32, 0, 8)))
+	x2 := tensor.New(tensor.WithShape(batchSize, 3), tensor.WithBacking([]float32{1, -1, 0, 2, 0, -2, 0.5, 1, 1, -1, 3, 0}))
+	y2 := tensor.New(tensor.WithShape(batchSize, 2), tensor.WithBacking([]float32{1, 0, 0, 1, 1, 1, -1, 2}))
+
+	for _, reduce := range []Accumulation{AccumulateMean, AccumulateSum} {
+		model, err := NewSequential("accumulate")
+		require.NoError(t, err)
+		err = model.AddLayers(
+			layer.FC{Input: 3, Output: 2, Activation: layer.Linear, Name: "w0", BiasInit: g.GlorotN(1)},
+		)
+		require.NoError(t, err)
+		err = model.Compile(NewInput("x", []int{1, 3}), NewInput("y", []int{1, 2}),
+			WithOptimizer(g.NewVanillaSolver(g.WithLearnRate(lr))),
+			WithBatchSize(batchSize),
+			WithGradAccumulation(2, reduce),
+			WithoutTracker(),
+		)
+		require.NoError(t, err)
+
+		w := float64s(model.Learnables()[0].Value().Data())
+		b := float64s(model.Learnables()[1].Value().Data())
+		dw1, db1 := linearMSEGrads(float64s(x1.Data()), float64s(y1.Data()), w, b)
+		dw2, db2 := linearMSEGrads(float64s(x2.Data()), float64s(y2.Data()), w, b)
+		scale := 1.0
+		if reduce == AccumulateMean {
+			scale = 0.5
+		}
+		expectedW, expectedB := []float64{}, []float64{}
+		for i := range w {
+			expectedW = append(expectedW, w[i]-lr*scale*(dw1[i]+dw2[i]))
+		}
+		for i := range b {
+			expectedB = append(expectedB, b[i]-lr*scale*(db1[i]+db2[i]))
+		}
+
+		// the first step only accumulates the gradients.
+		err = model.FitBatch(x1, y1)
+		require.NoError(t, err)
+		require.InDeltaSlice(t, w, float64s(model.Learnables()[0].Value().Data()), 1e-6)
+
+		err = model.FitBatch(x2, y2)
+		require.NoError(t, err)
+		require.InDeltaSlice(t, expectedW, float64s(model.Learnables()[0].Value().Data()), 1e-4)
+		require.InDeltaSlice(t, expectedB, float64s(model.Learnables()[1].Value().Data()), 1e-4)
+	}
+}
+
+// linearMSEGrads returns the gradients of the weights of shape (3, 2) and the bias of shape (1, 2) of
+// the mean squared error of x*w + b to y.
+func linearMSEGrads(x, y, w, b []float64) (dw, db []float64) {
+	in, out := 3, 2
+	rows := len(y) / out
+	dw, db = make([]float64, len(w)), make([]float64, len(b))
+	for r := 0; r < rows; r++ {
+		for o := 0; o < out; o++ {
+			pred := b[o]
+			for i := 0; i < in; i++ {
+				pred += x[r*in+i] * w[i*out+o]
+			}
+			d := 2 * (pred - y[r*out+o]) / float64(rows*out)
+			for i := 0; i < in; i++ {
+				dw[i*out+o] += d * x[r*in+i]
+			}
+			db[o] += d
+		}
+	}
+	return dw, db
+}
+
+func float64s(data interface{}) []float64 {
+	ret := []float64{}
+	for _, v := range data.([]float32) {
+		ret = append(ret, float64(v))
+	}
+	return ret
+}
+
+func TestDynamicBatch(t *testing.T) {
+	model, err := NewSequential("dynamic")
+	require.NoError(t, err)
+	err = model.AddLayers(
+		layer.FC{Input: 3, Output: 2, Activation: layer.Linear, Name: "w0"},
+	)
+	require.NoError(t, err)
+	err = model.Compile(NewInput("x", []int{1, 3}), NewInput("y", []int{1, 2}),
+		WithBatchSize(4),
+		WithMaxBatchSize(6),
+		WithoutTracker(),
+	)
+	require.NoError(t, err)
+
+	for _, size := range []int{4, 3, 6, 1} {
+		x := tensor.New(tensor.WithShape(size, 3), tensor.WithBacking(tensor.Range(tensor.Float32, 0, size*3)))
+		y := tensor.New(tensor.WithShape(size, 2), tensor.WithBacking(tensor.Range(tensor.Float32, 0, size*2)))
+		err = model.FitBatch(x, y)
+		require.NoError(t, err)
+
+		prediction, err := model.PredictBatch(x)
+		require.NoError(t, err)
+		require.Equal(t, []int{size, 2}, []int(prediction.Shape()))
+	}
+
+	x := tensor.New(tensor.WithShape(7, 3), tensor.Of(tensor.Float32))
+	_, err = model.PredictBatch(x)
+	require.Error(t, err)
+
+	err = model.ResizeBatch(8)
+	require.NoError(t, err)
+	_, err = model.PredictBatch(x)
+	require.NoError(t, err)
+}
+
+func TestFloat64(t *testing.T) {
+	model, err := NewSequential("float64")
+	require.NoError(t, err)
+	err = model.AddLayers(
+		layer.FC{Input: 3, Output: 4, Activation: layer.LeakyReLU, Name: "w0"},
+		layer.Dropout{Probability: 0.1},
+		layer.FC{Input: 4, Output: 2, Activation: layer.Softmax, Name: "w1"},
+	)
+	require.NoError(t, err)
+	xi := NewInput("x", []int{1, 3})
+	yi := NewInput("y", []int{1, 2})
+	err = model.Compile(xi, yi,
+		WithDType(tensor.Float64),
+		WithLoss(CrossEntropy),
+		WithBatchSize(4),
+		WithoutTracker(),
+	)
+	require.NoError(t, err)
+	require.Equal(t, tensor.Float64, model.DType())
+	require.Equal(t, tensor.Float64, model.X().Inputs()[0].DType())
+	require.Equal(t, tensor.Float64, model.Y().DType())
+
+	// the inputs of the caller are left as they were.
+	require.Equal(t, tensor.Float32, xi.DType())
+	require.Equal(t, tensor.Float32, yi.DType())
+
+	x := tensor.New(tensor.WithShape(4, 3), tensor.WithBacking(tensor.Range(tensor.Float64, 0, 12)))
+	y := tensor.New(tensor.WithShape(4, 2), tensor.WithBacking(tensor.Range(tensor.Float64, 0, 8)))
+	err = model.FitBatch(x, y)
+	require.NoError(t, err)
+	prediction, err := model.PredictBatch(x)
+	require.NoError(t, err)
+	require.Equal(t, tensor.Float64, prediction.Dtype())
+
+	mismatched, err := NewSequential("mismatched")
+	require.NoError(t, err)
+	err = mismatched.AddLayers(layer.FC{Input: 3, Output: 2})
+	require.NoError(t, err)
+	err = mismatched.Compile(NewInput("x", []int{1, 3}, AsType(tensor.Float32)), NewInput("y", []int{1, 2}),
+		WithDType(tensor.Float64),
+		WithoutTracker(),
+	)
+	require.Error(t, err)
+}
+
+func TestErrors(t *testing.T) {
+	model, err := NewSequential("errors")
+	require.NoError(t, err)
+
+	var configErr *ConfigError
+	err = model.AddLayers(layer.FC{Input: 3, Output: 2}, layer.FC{Input: 2})
+	require.True(t, errors.As(err, &configErr))
+	require.Empty(t, model.Chain.Layers)
+
+	x := tensor.New(tensor.WithShape(1, 3), tensor.WithBacking([]float32{0, 1, 2}))
+	_, err = model.Predict(x)
+	require.True(t, errors.As(err, &configErr))
+
+	err = model.Compile(NewInput("x", []int{1, 3}), NewInput("y", []int{1, 2}), WithBatchSize(0))
+	require.True(t, errors.As(err, &configErr))
+
+	err = model.AddLayers(layer.FC{Output: 2, Activation: layer.Linear})
+	require.NoError(t, err)
+	err = model.Compile(NewInput("x", []int{1, 3}), NewInput("y", []int{1, 2}), WithoutTracker())
+	require.NoError(t, err)
+
+	var shapeErr *ShapeError
+	_, err = model.Predict(tensor.New(tensor.WithShape(1, 4), tensor.WithBacking([]float32{0, 1, 2, 3})))
+	require.True(t, errors.As(err, &shapeErr))
+
+	var dtypeErr *DTypeError
+	_, err = model.Predict(tensor.New(tensor.WithShape(1, 3), tensor.WithBacking([]float64{0, 1, 2})))
+	require.True(t, errors.As(err, &dtypeErr))
+
+	err = model.Fit("x", tensor.New(tensor.WithShape(1, 2), tensor.WithBacking([]float32{0, 1})))
+	require.True(t, errors.As(err, &configErr))
+}
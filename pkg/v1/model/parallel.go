@@ -0,0 +1,225 @@
+package model
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/aunum/gold/pkg/v1/dense"
+
+	g "gorgonia.org/gorgonia"
+	t "gorgonia.org/tensor"
+)
+
+// WithDataParallel splits each batch given to FitBatch across a number of replicas of the train batch
+// graph, which are synced with the learnables of the model and run on concurrent goroutines. The
+// gradients of the replicas are averaged, weighted by the size of their split, and the optimizer steps
+// once. Batches smaller than the number of replicas use one replica per example.
+// Defaults to 1, which trains on a single goroutine.
+func WithDataParallel(replicas int) func(Model) error {
+	return func(m Model) error {
+		switch t := m.(type) {
+		case *Sequential:
+			if replicas < 1 {
+				return &ConfigError{Name: "data parallel", Reason: fmt.Sprintf("replicas must be at least 1, got %d", replicas)}
+			}
+			t.replicas = replicas
+		default:
+			return errUnknownModel(m)
+		}
+		return nil
+	}
+}
+
+// replicaBatch returns the train batch graph of the size for the replica, compiling it if it does not
+// exist. The first replica is the train batch graph, the others have their own copy of the learnables
+// as the backward pass transposes them in place, which is not safe while other replicas run.
+func (s *Sequential) replicaBatch(size, replica int) (*batchGraph, error) {
+	if replica == 0 {
+		return s.trainBatch(size)
+	}
+	if s.replicaBatches == nil {
+		s.replicaBatches = map[int][]*batchGraph{}
+	}
+	for len(s.replicaBatches[size]) < replica {
+		b, err := s.buildTrainBatchGraph(size, false)
+		if err != nil {
+			return nil, err
+		}
+		s.replicaBatches[size] = append(s.replicaBatches[size], b)
+	}
+	return s.replicaBatches[size][replica-1], nil
+}
+
+// split is a contiguous part of a batch run on a replica.
+type split struct {
+	start, end int
+	batch      *batchGraph
+}
+
+// splits divides a batch of the size as evenly as possible across the replicas.
+func (s *Sequential) splits(size int) ([]split, error) {
+	n := s.replicas
+	if n > size {
+		n = size
+	}
+	splits := []split{}
+	start := 0
+	for i := 0; i < n; i++ {
+		splitSize := size / n
+		if i < size%n {
+			splitSize++
+		}
+		// graphs must be compiled up front, compiling them while others run is not safe.
+		b, err := s.replicaBatch(splitSize, i)
+		if err != nil {
+			return nil, err
+		}
+		splits = append(splits, split{start: start, end: start + splitSize, batch: b})
+		start += splitSize
+	}
+	return splits, nil
+}
+
+// fitParallel fits x to y with the batch split across replicas.
+func (s *Sequential) fitParallel(size int, x Values, y g.Value) error {
+	splits, err := s.splits(size)
+	if err != nil {
+		return err
+	}
+	learnables := s.trainChain.Learnables()
+	for i, sp := range splits {
+		for j, vg := range g.NodesToValueGrads(sp.batch.chain.Learnables()) {
+			// gradients are added to on each run until the optimizer zeroes them, which it only does
+			// for the replica it steps.
+			grad, err := vg.Grad()
+			if err != nil {
+				return err
+			}
+			if grad != nil {
+				err = scaleValue(grad, 0)
+				if err != nil {
+					return err
+				}
+			}
+			if i > 0 {
+				err = copyValue(vg.Value(), learnables[j].Value())
+				if err != nil {
+					return err
+				}
+			}
+		}
+		yi, err := sliceBatch(y, sp.start, sp.end)
+		if err != nil {
+			return err
+		}
+		err = sp.batch.y.Set(yi)
+		if err != nil {
+			return err
+		}
+		xi := Values{}
+		for _, v := range x {
+			v, err = sliceBatch(v, sp.start, sp.end)
+			if err != nil {
+				return err
+			}
+			xi = append(xi, v)
+		}
+		err = sp.batch.x.Set(xi)
+		if err != nil {
+			return err
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(splits))
+	for i, sp := range splits {
+		wg.Add(1)
+		go func(i int, vm g.VM) {
+			defer wg.Done()
+			errs[i] = vm.RunAll()
+		}(i, sp.batch.vm)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("replica %d: %w", i, err)
+		}
+	}
+
+	// reduce the gradients of the replicas into the first, the losses average over their split so
+	// each is weighted by its share of the batch.
+	grads := g.NodesToValueGrads(splits[0].batch.chain.Learnables())
+	var loss float64
+	for i, sp := range splits {
+		weight := float64(sp.end-sp.start) / float64(size)
+		loss += weight * scalarOf(sp.batch.lossVal)
+		for j, vg := range g.NodesToValueGrads(sp.batch.chain.Learnables()) {
+			grad, err := vg.Grad()
+			if err != nil {
+				return err
+			}
+			err = scaleValue(grad, weight)
+			if err != nil {
+				return err
+			}
+			if i == 0 {
+				continue
+			}
+			total, err := grads[j].Grad()
+			if err != nil {
+				return err
+			}
+			err = addValue(total, grad)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if s.batchLoss != nil {
+		s.batchLoss.Set(loss)
+	}
+	err = s.step(splits[0].batch.chain.Learnables())
+	if err != nil {
+		return err
+	}
+	for _, sp := range splits {
+		sp.batch.vm.Reset()
+	}
+	return nil
+}
+
+// sliceBatch returns a copy of the rows from start to end of a batch value, keeping the batch
+// dimension.
+func sliceBatch(v g.Value, start, end int) (g.Value, error) {
+	d, ok := v.(*t.Dense)
+	if !ok {
+		return nil, fmt.Errorf("cannot split a batch of type %T", v)
+	}
+	view, err := d.Slice(dense.MakeRangedSlice(start, end))
+	if err != nil {
+		return nil, err
+	}
+	shape := append(t.Shape{end - start}, d.Shape()[1:]...)
+	if view.IsScalar() {
+		ret := t.New(t.Of(d.Dtype()), t.WithShape(shape...))
+		ret.Set(0, view.Data())
+		return ret, nil
+	}
+	ret := view.Materialize().(*t.Dense).Clone().(*t.Dense)
+	err = ret.Reshape(shape...)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// scalarOf returns the value of a scalar as a float64.
+func scalarOf(v g.Value) float64 {
+	switch d := v.Data().(type) {
+	case float32:
+		return float64(d)
+	case float64:
+		return d
+	}
+	return 0
+}
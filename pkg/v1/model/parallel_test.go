@@ -0,0 +1,58 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/aunum/goro/pkg/v1/layer"
+	. "github.com/aunum/goro/pkg/v1/model"
+
+	"github.com/stretchr/testify/require"
+	g "gorgonia.org/gorgonia"
+	"gorgonia.org/tensor"
+)
+
+// TestDataParallel should be run with the race detector e.g. go test -race.
+func TestDataParallel(t *testing.T) {
+	newModel := func(opts ...Opt) *Sequential {
+		model, err := NewSequential("parallel")
+		require.NoError(t, err)
+		err = model.AddLayers(
+			layer.FC{Output: 4, Activation: layer.Sigmoid, Name: "w0"},
+			layer.FC{Output: 2, Activation: layer.Linear, Name: "w1"},
+		)
+		require.NoError(t, err)
+		opts = append(opts, WithBatchSize(8), WithOptimizer(g.NewVanillaSolver(g.WithLearnRate(0.1))), WithoutTracker())
+		err = model.Compile(NewInput("x", []int{1, 3}), NewInput("y", []int{1, 2}), opts...)
+		require.NoError(t, err)
+		return model
+	}
+	serial := newModel()
+	parallel := newModel(WithDataParallel(3))
+	require.NoError(t, serial.CloneLearnablesTo(parallel))
+
+	x := tensor.New(tensor.WithShape(8, 3), tensor.WithBacking(tensor.Range(tensor.Float32, 0, 24)))
+	y := tensor.New(tensor.WithShape(8, 2), tensor.WithBacking(tensor.Range(tensor.Float32, 0, 16)))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, serial.FitBatch(x, y))
+		require.NoError(t, parallel.FitBatch(x, y))
+	}
+	expected := serial.Learnables()
+	for i, learnable := range parallel.Learnables() {
+		require.InDeltaSlice(t, expected[i].Value().Data(), learnable.Value().Data(), 1e-4)
+	}
+
+	// the split replicas share the learnables with the predictors.
+	xi := tensor.New(tensor.WithShape(1, 3), tensor.WithBacking([]float32{1, 2, 3}))
+	expectedPrediction, err := serial.Predict(xi)
+	require.NoError(t, err)
+	prediction, err := parallel.Predict(xi)
+	require.NoError(t, err)
+	require.InDeltaSlice(t, expectedPrediction.Data(), prediction.Data(), 1e-4)
+
+	// batches smaller than the number of replicas.
+	xs := tensor.New(tensor.WithShape(2, 3), tensor.WithBacking(tensor.Range(tensor.Float32, 0, 6)))
+	ys := tensor.New(tensor.WithShape(2, 2), tensor.WithBacking(tensor.Range(tensor.Float32, 0, 4)))
+	require.NoError(t, parallel.FitBatch(xs, ys))
+
+	require.Error(t, WithDataParallel(0)(parallel))
+}
@@ -0,0 +1,224 @@
+package model
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/aunum/goro/pkg/v1/layer"
+	"github.com/aunum/log"
+
+	g "gorgonia.org/gorgonia"
+)
+
+// onlineSize is the pool key for the online graph which predicts a single x.
+const onlineSize = 0
+
+// predictorPool is a pool of online graphs which share learnables with the train graph, allowing
+// predictions to run concurrently. Graphs are keyed by batch size and compiled as demand requires,
+// up to a max for each batch size after which predictions wait for a free graph.
+type predictorPool struct {
+	// build is held exclusively while compiling a graph as compiling a tape machine sets the engine of
+	// the shared learnables, predictions hold it for reading while they run.
+	build sync.RWMutex
+
+	mu        sync.Mutex
+	available *sync.Cond
+	max       int
+	counts    map[int]int
+	free      map[int][]*batchGraph
+	primary   map[int]*batchGraph
+	all       []*batchGraph
+}
+
+func newPredictorPool(max int) *predictorPool {
+	pool := &predictorPool{
+		max:     max,
+		counts:  map[int]int{},
+		free:    map[int][]*batchGraph{},
+		primary: map[int]*batchGraph{},
+	}
+	pool.available = sync.NewCond(&pool.mu)
+	return pool
+}
+
+// acquire a predictor for the given batch size, compiling a new one if none are free and the max has
+// not been reached, otherwise waiting for one to be released.
+func (s *Sequential) acquire(size int) (*batchGraph, error) {
+	pool := s.predictors
+	pool.mu.Lock()
+	for len(pool.free[size]) == 0 && pool.counts[size] >= pool.max {
+		pool.available.Wait()
+	}
+	if free := pool.free[size]; len(free) > 0 {
+		p := free[len(free)-1]
+		pool.free[size] = free[:len(free)-1]
+		pool.mu.Unlock()
+		return p, nil
+	}
+	pool.counts[size]++
+	pool.mu.Unlock()
+
+	pool.build.Lock()
+	p, err := s.buildPredictor(size)
+	pool.build.Unlock()
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if err != nil {
+		pool.counts[size]--
+		pool.available.Broadcast()
+		return nil, err
+	}
+	if _, ok := pool.primary[size]; !ok {
+		pool.primary[size] = p
+	}
+	pool.all = append(pool.all, p)
+	return p, nil
+}
+
+// release a predictor back to the pool.
+func (s *Sequential) release(p *batchGraph) {
+	pool := s.predictors
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	pool.free[p.size] = append(pool.free[p.size], p)
+	pool.available.Broadcast()
+}
+
+// ensurePredictor compiles a predictor for the given batch size if one does not exist.
+func (s *Sequential) ensurePredictor(size int) error {
+	s.predictors.mu.Lock()
+	_, ok := s.predictors.primary[size]
+	s.predictors.mu.Unlock()
+	if ok {
+		return nil
+	}
+	p, err := s.acquire(size)
+	if err != nil {
+		return err
+	}
+	s.release(p)
+	return nil
+}
+
+// fwdValue returns the value of the forward input from the values of x, which is either the value of the
+// forward input or the values of all x inputs.
+func (s *Sequential) fwdValue(x Values) (g.Value, error) {
+	if len(x) == 1 {
+		return x[0], nil
+	}
+	if len(x) != len(s.x) {
+		return nil, &ConfigError{Name: "inputs", Reason: fmt.Sprintf("got %d values for %d inputs", len(x), len(s.x))}
+	}
+	for i, input := range s.x {
+		if input.Name() == s.fwd.Name() {
+			return x[i], nil
+		}
+	}
+	return nil, fmt.Errorf("could not find input %s", s.fwd.Name())
+}
+
+// predict runs x through a predictor from the pool, returning a copy of the prediction which is
+// owned by the caller.
+func (s *Sequential) predict(size int, x Values) (g.Value, error) {
+	p, err := s.acquire(size)
+	if err != nil {
+		return nil, err
+	}
+	defer s.release(p)
+	s.predictors.build.RLock()
+	defer s.predictors.build.RUnlock()
+	defer p.vm.Reset()
+
+	if len(x) == 1 {
+		err = p.xFwd.Set(x[0])
+	} else {
+		err = p.x.Set(x)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = p.vm.RunAll()
+	if err != nil {
+		return nil, err
+	}
+	return g.CloneValue(p.predVal)
+}
+
+// buildPredictor compiles an online graph for the given batch size which shares learnables with the
+// train graph. A size of zero compiles the online graph for a single x.
+func (s *Sequential) buildPredictor(size int) (b *batchGraph, err error) {
+	log.Debugf("compiling online graph for batch size %d", size)
+	b = &batchGraph{
+		size:  size,
+		graph: g.NewGraph(),
+	}
+
+	fwdName := s.fwd.Name()
+	layerOpts := []layer.CompileOpt{layer.AsType(s.dtype)}
+	if size != onlineSize {
+		fwdName = NameAsBatch(fwdName)
+		layerOpts = append(layerOpts, layer.AsBatch())
+	}
+	for _, input := range s.x {
+		batched := input.Name() == s.fwd.Name() || (s.mask != nil && input.Name() == s.mask.Name())
+		if batched && size != onlineSize {
+			i := input.AsBatch(size)
+			_, err = i.Compile(b.graph)
+			if err != nil {
+				return nil, err
+			}
+			b.x = append(b.x, i)
+			continue
+		}
+		i, err := input.CloneTo(b.graph)
+		if err != nil {
+			return nil, err
+		}
+		b.x = append(b.x, i)
+	}
+
+	b.xFwd, err = b.x.Get(fwdName)
+	if err != nil {
+		return nil, err
+	}
+
+	b.chain = s.Chain.Clone()
+	err = b.chain.Compile(b.graph, layer.WithSharedChainLearnables(s.trainChain), layer.WithLayerOpts(layerOpts...))
+	if err != nil {
+		return nil, err
+	}
+	if s.mask != nil {
+		name := s.mask.Name()
+		if size != onlineSize {
+			name = NameAsBatch(name)
+		}
+		mask, err := b.x.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		b.chain.SetMask(mask.Node())
+	}
+
+	prediction, err := b.chain.Fwd(b.xFwd.Node())
+	if err != nil {
+		return nil, err
+	}
+	g.Read(prediction, &b.predVal)
+
+	b.vm = g.NewTapeMachine(b.graph, s.vmOpts...)
+	return b, nil
+}
+
+// predictorChains are the chains of all compiled predictors.
+func (s *Sequential) predictorChains() map[string]*layer.Chain {
+	chains := map[string]*layer.Chain{}
+	if s.predictors == nil {
+		return chains
+	}
+	s.predictors.mu.Lock()
+	defer s.predictors.mu.Unlock()
+	for i, p := range s.predictors.all {
+		chains[fmt.Sprintf("online_%d_%d", p.size, i)] = p.chain
+	}
+	return chains
+}
@@ -0,0 +1,131 @@
+package model_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/aunum/goro/pkg/v1/layer"
+	. "github.com/aunum/goro/pkg/v1/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	g "gorgonia.org/gorgonia"
+	"gorgonia.org/tensor"
+)
+
+// TestConcurrentPredict should be run with the race detector e.g. go test -race.
+func TestConcurrentPredict(t *testing.T) {
+	model, err := NewSequential("concurrent")
+	require.NoError(t, err)
+	err = model.AddLayers(
+		layer.FC{Output: 8, Activation: layer.Sigmoid, Name: "w0"},
+		layer.FC{Output: 2, Activation: layer.Linear, Name: "w1"},
+	)
+	require.NoError(t, err)
+	err = model.Compile(NewInput("x", []int{1, 3}), NewInput("y", []int{1, 2}),
+		WithMaxPredictors(0),
+		WithoutTracker(),
+	)
+	var configErr *ConfigError
+	require.True(t, errors.As(err, &configErr))
+
+	// more workers than predictors must wait for a free one.
+	model, err = NewSequential("concurrent")
+	require.NoError(t, err)
+	err = model.AddLayers(
+		layer.FC{Output: 8, Activation: layer.Sigmoid, Name: "w0"},
+		layer.FC{Output: 2, Activation: layer.Linear, Name: "w1"},
+	)
+	require.NoError(t, err)
+	err = model.Compile(NewInput("x", []int{1, 3}), NewInput("y", []int{1, 2}),
+		WithBatchSize(4),
+		WithMaxPredictors(2),
+		WithoutTracker(),
+	)
+	require.NoError(t, err)
+
+	numInputs := 8
+	xs := []g.Value{}
+	expected := []g.Value{}
+	for i := 0; i < numInputs; i++ {
+		x := tensor.New(tensor.WithShape(1, 3), tensor.WithBacking(tensor.Range(tensor.Float32, i, i+3)))
+		prediction, err := model.Predict(x)
+		require.NoError(t, err)
+		xs = append(xs, x)
+		expected = append(expected, prediction)
+	}
+	xBatch := tensor.New(tensor.WithShape(4, 3), tensor.WithBacking(tensor.Range(tensor.Float32, 0, 12)))
+	expectedBatch, err := model.PredictBatch(xBatch)
+	require.NoError(t, err)
+
+	// returned predictions must not alias graph memory.
+	_, err = model.Predict(xs[1])
+	require.NoError(t, err)
+	first, err := model.Predict(xs[0])
+	require.NoError(t, err)
+	require.Equal(t, expected[0].Data(), first.Data())
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for w := 0; w < 16; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				index := (w + i) % numInputs
+				prediction, err := model.Predict(xs[index])
+				if err != nil {
+					errs <- err
+					return
+				}
+				if !assert.Equal(t, expected[index].Data(), prediction.Data()) {
+					return
+				}
+				if i%5 != 0 {
+					continue
+				}
+				prediction, err = model.PredictBatch(xBatch)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if !assert.Equal(t, expectedBatch.Data(), prediction.Data()) {
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// fitting while predicting must also be safe.
+	y := tensor.New(tensor.WithShape(4, 2), tensor.WithBacking(tensor.Range(tensor.Float32, 0, 8)))
+	errs = make(chan error, 64)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				var err error
+				if w == 0 {
+					err = model.FitBatch(xBatch, y)
+				} else {
+					_, err = model.Predict(xs[i%numInputs])
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
@@ -0,0 +1,206 @@
+package model
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	g "gorgonia.org/gorgonia"
+	t "gorgonia.org/tensor"
+)
+
+// savedModel is the serialized representation of a sequential model, its definition along with the
+// values of its learnables.
+type savedModel struct {
+	*Definition
+	Learnables []savedTensor `json:"learnables"`
+}
+
+// EncodedLoss is a loss encoded with its type and parameters.
+type EncodedLoss struct {
+	// Type of the loss: mse, cross_entropy, pseudo_huber or pseudo_cross_entropy.
+	Type string `json:"type"`
+
+	// Delta of a pseudo huber loss.
+	Delta float32 `json:"delta,omitempty"`
+}
+
+// UnmarshalJSON decodes the loss from JSON, which may also be just the name of its type e.g. "mse".
+func (e *EncodedLoss) UnmarshalJSON(b []byte) error {
+	var typ string
+	if err := json.Unmarshal(b, &typ); err == nil {
+		*e = EncodedLoss{Type: typ}
+		return nil
+	}
+	type encoded EncodedLoss
+	return json.Unmarshal(b, (*encoded)(e))
+}
+
+type savedTensor struct {
+	Name  string `json:"name"`
+	Shape []int  `json:"shape"`
+	DType string `json:"dtype"`
+	// Data is the little endian encoded backing data.
+	Data []byte `json:"data"`
+}
+
+// Save the model definition and learnables as JSON to the writer.
+// Note: the optimizer state is not saved, and a loss, optimizer or gradient clipper which cannot be
+// defined is left out.
+func (s *Sequential) Save(w io.Writer) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	err := s.checkCompiled()
+	if err != nil {
+		return err
+	}
+	def, err := s.define(false)
+	if err != nil {
+		return err
+	}
+	saved := savedModel{Definition: def}
+	for _, learnable := range s.trainChain.Learnables() {
+		st, err := saveTensor(learnable.Name(), learnable.Value())
+		if err != nil {
+			return err
+		}
+		saved.Learnables = append(saved.Learnables, st)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(saved)
+}
+
+// SaveFile saves the model to the file at the given path.
+func (s *Sequential) SaveFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = s.Save(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load a model saved with Save, returning it compiled with the saved learnables. Any options are
+// applied after those of the saved definition.
+func Load(r io.Reader, opts ...Opt) (*Sequential, error) {
+	saved := savedModel{}
+	err := json.NewDecoder(r).Decode(&saved)
+	if err != nil {
+		return nil, err
+	}
+	if saved.Definition == nil {
+		return nil, &ConfigError{Name: "saved model", Reason: "definition is missing"}
+	}
+	model, err := saved.Definition.Build(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []g.Value{}
+	for _, st := range saved.Learnables {
+		v, err := loadTensor(st)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	model.mu.Lock()
+	defer model.mu.Unlock()
+	err = model.letLearnables(values)
+	if err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// LoadFile loads a model from the file at the given path.
+func LoadFile(path string, opts ...Opt) (*Sequential, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f, opts...)
+}
+
+func saveLoss(loss Loss) *EncodedLoss {
+	switch l := loss.(type) {
+	case *MSELoss:
+		return &EncodedLoss{Type: "mse"}
+	case *CrossEntropyLoss:
+		return &EncodedLoss{Type: "cross_entropy"}
+	case *PseudoHuberLoss:
+		return &EncodedLoss{Type: "pseudo_huber", Delta: l.Delta}
+	case *PseudoCrossEntropyLoss:
+		return &EncodedLoss{Type: "pseudo_cross_entropy"}
+	default:
+		return nil
+	}
+}
+
+func loadLoss(sl *EncodedLoss) (Loss, error) {
+	switch sl.Type {
+	case "mse":
+		return MSE, nil
+	case "cross_entropy":
+		return CrossEntropy, nil
+	case "pseudo_huber":
+		return NewPseudoHuberLoss(sl.Delta), nil
+	case "pseudo_cross_entropy":
+		return PseudoCrossEntropy, nil
+	default:
+		return nil, &ConfigError{Name: fmt.Sprintf("loss %q", sl.Type), Reason: "unknown loss type"}
+	}
+}
+
+func saveTensor(name string, v g.Value) (savedTensor, error) {
+	buf := &bytes.Buffer{}
+	err := binary.Write(buf, binary.LittleEndian, v.Data())
+	if err != nil {
+		return savedTensor{}, fmt.Errorf("could not encode learnable %q: %w", name, err)
+	}
+	return savedTensor{
+		Name:  name,
+		Shape: v.Shape().Clone(),
+		DType: v.Dtype().String(),
+		Data:  buf.Bytes(),
+	}, nil
+}
+
+func loadTensor(st savedTensor) (g.Value, error) {
+	dtype, err := parseDType(st.DType)
+	if err != nil {
+		return nil, err
+	}
+	size := t.Shape(st.Shape).TotalSize()
+	var backing interface{}
+	switch dtype {
+	case t.Float64:
+		backing = make([]float64, size)
+	default:
+		backing = make([]float32, size)
+	}
+	err = binary.Read(bytes.NewReader(st.Data), binary.LittleEndian, backing)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode learnable %q: %w", st.Name, err)
+	}
+	return t.New(t.WithShape(st.Shape...), t.WithBacking(backing)), nil
+}
+
+// parseDType parses the name of one of the supported data types.
+func parseDType(name string) (t.Dtype, error) {
+	for _, dtype := range SupportedDTypes {
+		if dtype.String() == name {
+			return dtype, nil
+		}
+	}
+	return t.Dtype{}, &ConfigError{Name: fmt.Sprintf("data type %q", name), Reason: fmt.Sprintf("supported types are %v", SupportedDTypes)}
+}
@@ -0,0 +1,63 @@
+package model_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/aunum/goro/pkg/v1/layer"
+	. "github.com/aunum/goro/pkg/v1/model"
+
+	"github.com/stretchr/testify/require"
+	"gorgonia.org/tensor"
+)
+
+func TestSaveLoad(t *testing.T) {
+	model, err := NewSequential("save")
+	require.NoError(t, err)
+	err = model.AddLayers(
+		layer.Reshape{To: []int{1, 2, 2}},
+		layer.Conv2D{Output: 2, Height: 2, Width: 2, Activation: layer.Tanh, Bias: true},
+		layer.Flatten{},
+		layer.FC{Output: 3, Activation: layer.NewLeakyReLU(0.1)},
+		layer.FC{Output: 2, Activation: layer.Linear},
+	)
+	require.NoError(t, err)
+	err = model.Compile(NewInput("x", []int{1, 4}), NewInput("y", []int{1, 2}),
+		WithBatchSize(4),
+		WithLoss(CrossEntropy),
+		WithoutTracker(),
+	)
+	require.NoError(t, err)
+
+	x := tensor.New(tensor.WithShape(4, 4), tensor.WithBacking(tensor.Range(tensor.Float32, 0, 16)))
+	y := tensor.New(tensor.WithShape(4, 2), tensor.WithBacking([]float32{1, 0, 0, 1, 1, 0, 0, 1}))
+	err = model.FitBatch(x, y)
+	require.NoError(t, err)
+	expected, err := model.PredictBatch(x)
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	err = model.Save(buf)
+	require.NoError(t, err)
+
+	loaded, err := Load(buf, WithoutTracker())
+	require.NoError(t, err)
+	require.Equal(t, model.Name(), loaded.Name())
+	require.Equal(t, model.OutputShape(), loaded.OutputShape())
+	require.Equal(t, 4, loaded.BatchSize())
+	prediction, err := loaded.PredictBatch(x)
+	require.NoError(t, err)
+	require.Equal(t, expected.Data(), prediction.Data())
+
+	// the model is saved with its definition.
+	def, err := model.Definition()
+	require.NoError(t, err)
+	loadedDef, err := loaded.Definition()
+	require.NoError(t, err)
+	require.Equal(t, def, loadedDef)
+
+	_, err = Load(bytes.NewBufferString(`{"dtype": "int"}`))
+	var configErr *ConfigError
+	require.True(t, errors.As(err, &configErr))
+}
@@ -0,0 +1,375 @@
+package model
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/aunum/gold/pkg/v1/track"
+
+	g "gorgonia.org/gorgonia"
+)
+
+// Schedule determines the learning rate at a point in training.
+type Schedule interface {
+	// LearnRate returns the learning rate for the given step or epoch.
+	LearnRate(t int) float64
+}
+
+// Monitor is a metric which can be monitored by a schedule, a tracked value satisfies this interface.
+type Monitor interface {
+	// Scalar value of the metric.
+	Scalar() float64
+}
+
+// ScheduledOptimizer is a solver which adjusts the learning rate of an underlying solver according to a schedule.
+type ScheduledOptimizer struct {
+	solver   g.Solver
+	schedule Schedule
+	perEpoch bool
+
+	step      int
+	epoch     int
+	learnRate float64
+	tracked   *track.TrackedScalarValue
+}
+
+// ScheduledOptimizerOpt is an option for a scheduled optimizer.
+type ScheduledOptimizerOpt func(*ScheduledOptimizer)
+
+// PerEpoch will advance the schedule by epoch rather than by step.
+// Epochs are advanced by calling NextEpoch on the optimizer or the model at the end of each epoch.
+func PerEpoch() func(*ScheduledOptimizer) {
+	return func(s *ScheduledOptimizer) {
+		s.perEpoch = true
+	}
+}
+
+// NewScheduledOptimizer returns a new scheduled optimizer wrapping the given solver.
+func NewScheduledOptimizer(solver g.Solver, schedule Schedule, opts ...ScheduledOptimizerOpt) (*ScheduledOptimizer, error) {
+	switch solver.(type) {
+	case *g.RMSPropSolver, *g.AdamSolver, *g.VanillaSolver, *g.BarzilaiBorweinSolver, *g.Momentum:
+	default:
+		return nil, fmt.Errorf("solver %T does not support setting a learning rate", solver)
+	}
+	if schedule == nil {
+		return nil, fmt.Errorf("schedule must be set")
+	}
+	s := &ScheduledOptimizer{
+		solver:   solver,
+		schedule: schedule,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.learnRate = s.schedule.LearnRate(0)
+	return s, nil
+}
+
+// Step sets the learning rate for the current step or epoch and steps the underlying solver.
+func (s *ScheduledOptimizer) Step(model []g.ValueGrad) error {
+	t := s.step
+	if s.perEpoch {
+		t = s.epoch
+	}
+	s.learnRate = s.schedule.LearnRate(t)
+	g.WithLearnRate(s.learnRate)(s.solver)
+	if s.tracked != nil {
+		s.tracked.Set(s.learnRate)
+	}
+	s.step++
+	return s.solver.Step(model)
+}
+
+// NextEpoch advances the optimizer to the next epoch.
+func (s *ScheduledOptimizer) NextEpoch() {
+	s.epoch++
+}
+
+// LearnRate is the current learning rate.
+func (s *ScheduledOptimizer) LearnRate() float64 {
+	return s.learnRate
+}
+
+// Steps is the number of steps taken.
+func (s *ScheduledOptimizer) Steps() int {
+	return s.step
+}
+
+// Epoch is the current epoch.
+func (s *ScheduledOptimizer) Epoch() int {
+	return s.epoch
+}
+
+// Solver is the underlying solver.
+func (s *ScheduledOptimizer) Solver() g.Solver {
+	return s.solver
+}
+
+// track the learning rate with the given tracker.
+func (s *ScheduledOptimizer) track(tracker *track.Tracker, namespace string) {
+	tv := tracker.TrackValue(string(LearnRateMetric), s.learnRate, track.WithNamespace(namespace))
+	s.tracked = tv.(*track.TrackedScalarValue)
+}
+
+// StepDecay drops the learning rate by a factor every number of steps or epochs.
+type StepDecay struct {
+	// Initial learning rate.
+	// required
+	Initial float64
+
+	// Factor to drop the learning rate by.
+	// Defaults to 0.5
+	Factor float64
+
+	// Every is the number of steps or epochs between drops.
+	// Defaults to 10
+	Every int
+}
+
+// LearnRate returns the learning rate at t.
+func (s StepDecay) LearnRate(t int) float64 {
+	if s.Factor == 0 {
+		s.Factor = 0.5
+	}
+	if s.Every == 0 {
+		s.Every = 10
+	}
+	return s.Initial * math.Pow(s.Factor, float64(t/s.Every))
+}
+
+// ExponentialDecay continuously decays the learning rate by a rate over a number of steps or epochs.
+type ExponentialDecay struct {
+	// Initial learning rate.
+	// required
+	Initial float64
+
+	// Rate of decay.
+	// Defaults to 0.96
+	Rate float64
+
+	// Steps over which the rate is applied.
+	// Defaults to 1000
+	Steps int
+
+	// Staircase decays the learning rate at discrete intervals.
+	Staircase bool
+}
+
+// LearnRate returns the learning rate at t.
+func (e ExponentialDecay) LearnRate(t int) float64 {
+	if e.Rate == 0 {
+		e.Rate = 0.96
+	}
+	if e.Steps == 0 {
+		e.Steps = 1000
+	}
+	p := float64(t) / float64(e.Steps)
+	if e.Staircase {
+		p = math.Floor(p)
+	}
+	return e.Initial * math.Pow(e.Rate, p)
+}
+
+// CosineAnnealing anneals the learning rate from max to min following a cosine curve, restarting at the
+// end of every period.
+// Paper: https://arxiv.org/abs/1608.03983
+type CosineAnnealing struct {
+	// Max learning rate.
+	// required
+	Max float64
+
+	// Min learning rate.
+	Min float64
+
+	// Period is the number of steps or epochs before the first restart.
+	// required
+	Period int
+
+	// Mult is the factor the period grows by after each restart.
+	// Defaults to 1
+	Mult float64
+}
+
+// LearnRate returns the learning rate at t.
+func (c CosineAnnealing) LearnRate(t int) float64 {
+	if c.Period <= 0 {
+		return c.Max
+	}
+	if c.Mult == 0 {
+		c.Mult = 1
+	}
+	cur := float64(t)
+	period := float64(c.Period)
+	if c.Mult == 1 {
+		cur = math.Mod(cur, period)
+	} else {
+		for cur >= period {
+			cur -= period
+			period *= c.Mult
+		}
+	}
+	return c.Min + 0.5*(c.Max-c.Min)*(1+math.Cos(math.Pi*cur/period))
+}
+
+// OneCycle anneals the learning rate up to a max and then back down to well below the initial
+// learning rate over a fixed number of steps.
+// Paper: https://arxiv.org/abs/1708.07120
+type OneCycle struct {
+	// Max learning rate.
+	// required
+	Max float64
+
+	// Total number of steps or epochs in the cycle.
+	// required
+	Total int
+
+	// PctStart is the percentage of the cycle spent increasing the learning rate.
+	// Defaults to 0.3
+	PctStart float64
+
+	// DivFactor determines the initial learning rate as Max/DivFactor.
+	// Defaults to 25
+	DivFactor float64
+
+	// FinalDivFactor determines the final learning rate as initial/FinalDivFactor.
+	// Defaults to 1e4
+	FinalDivFactor float64
+}
+
+// LearnRate returns the learning rate at t.
+func (o OneCycle) LearnRate(t int) float64 {
+	if o.PctStart == 0 {
+		o.PctStart = 0.3
+	}
+	if o.DivFactor == 0 {
+		o.DivFactor = 25
+	}
+	if o.FinalDivFactor == 0 {
+		o.FinalDivFactor = 1e4
+	}
+	initial := o.Max / o.DivFactor
+	final := initial / o.FinalDivFactor
+	if o.Total <= 0 || t >= o.Total {
+		return final
+	}
+	up := o.PctStart * float64(o.Total)
+	if float64(t) <= up {
+		return cosineAnneal(initial, o.Max, float64(t)/up)
+	}
+	return cosineAnneal(o.Max, final, (float64(t)-up)/(float64(o.Total)-up))
+}
+
+func cosineAnneal(start, end, pct float64) float64 {
+	return end + (start-end)/2*(math.Cos(math.Pi*pct)+1)
+}
+
+// LinearWarmup linearly increases the learning rate to that of the given schedule over a number of
+// steps or epochs.
+type LinearWarmup struct {
+	// Steps to warm up over.
+	// required
+	Steps int
+
+	// Schedule to use after warmup, the warmup targets the learning rate of this schedule.
+	// required
+	Schedule Schedule
+}
+
+// LearnRate returns the learning rate at t.
+func (l LinearWarmup) LearnRate(t int) float64 {
+	lr := l.Schedule.LearnRate(t)
+	if t >= l.Steps {
+		return lr
+	}
+	return lr * float64(t+1) / float64(l.Steps)
+}
+
+// ReduceOnPlateau reduces the learning rate when a monitored metric has stopped improving.
+type ReduceOnPlateau struct {
+	// Initial learning rate.
+	// required
+	Initial float64
+
+	// Monitor is the metric to monitor e.g. a tracked loss.
+	// required
+	Monitor Monitor
+
+	// Maximize indicates the monitored metric should increase rather than decrease.
+	Maximize bool
+
+	// Factor to reduce the learning rate by.
+	// Defaults to 0.1
+	Factor float64
+
+	// Patience is the number of steps or epochs with no improvement before reducing the learning rate.
+	// Defaults to 10
+	Patience int
+
+	// Threshold is the minimum change that counts as an improvement.
+	// Defaults to 1e-4
+	Threshold float64
+
+	// Cooldown is the number of steps or epochs to wait after a reduction before resuming monitoring.
+	Cooldown int
+
+	// Min learning rate.
+	Min float64
+
+	lr       float64
+	best     float64
+	bad      int
+	cooldown int
+	last     int
+	started  bool
+}
+
+// LearnRate returns the learning rate at t, the monitored metric is evaluated each time t advances.
+func (r *ReduceOnPlateau) LearnRate(t int) float64 {
+	if !r.started {
+		if r.Factor == 0 {
+			r.Factor = 0.1
+		}
+		if r.Patience == 0 {
+			r.Patience = 10
+		}
+		if r.Threshold == 0 {
+			r.Threshold = 1e-4
+		}
+		r.lr = r.Initial
+		r.best = math.Inf(1)
+		if r.Maximize {
+			r.best = math.Inf(-1)
+		}
+		r.last = t
+		r.started = true
+		return r.lr
+	}
+	if t == r.last || r.Monitor == nil {
+		return r.lr
+	}
+	r.last = t
+	current := r.Monitor.Scalar()
+	if r.improved(current) {
+		r.best = current
+		r.bad = 0
+	} else {
+		r.bad++
+	}
+	if r.cooldown > 0 {
+		r.cooldown--
+		r.bad = 0
+	}
+	if r.bad > r.Patience {
+		r.lr = math.Max(r.lr*r.Factor, r.Min)
+		r.cooldown = r.Cooldown
+		r.bad = 0
+	}
+	return r.lr
+}
+
+func (r *ReduceOnPlateau) improved(current float64) bool {
+	if r.Maximize {
+		return current > r.best+r.Threshold
+	}
+	return current < r.best-r.Threshold
+}
@@ -0,0 +1,98 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/aunum/goro/pkg/v1/layer"
+	. "github.com/aunum/goro/pkg/v1/model"
+
+	"github.com/stretchr/testify/require"
+	g "gorgonia.org/gorgonia"
+	"gorgonia.org/tensor"
+)
+
+type constMonitor struct{ val float64 }
+
+func (c *constMonitor) Scalar() float64 { return c.val }
+
+func TestSchedules(t *testing.T) {
+	step := StepDecay{Initial: 1, Factor: 0.5, Every: 2}
+	require.Equal(t, 1.0, step.LearnRate(1))
+	require.Equal(t, 0.5, step.LearnRate(2))
+	require.Equal(t, 0.25, step.LearnRate(5))
+
+	exp := ExponentialDecay{Initial: 1, Rate: 0.5, Steps: 10, Staircase: true}
+	require.Equal(t, 1.0, exp.LearnRate(9))
+	require.Equal(t, 0.5, exp.LearnRate(10))
+
+	cos := CosineAnnealing{Max: 1, Min: 0, Period: 10, Mult: 2}
+	require.Equal(t, 1.0, cos.LearnRate(0))
+	require.InDelta(t, 0.5, cos.LearnRate(5), 1e-9)
+	require.Equal(t, 1.0, cos.LearnRate(10))
+	require.InDelta(t, 0.5, cos.LearnRate(20), 1e-9)
+
+	one := OneCycle{Max: 1, Total: 100, DivFactor: 10, FinalDivFactor: 10}
+	require.InDelta(t, 0.1, one.LearnRate(0), 1e-9)
+	require.InDelta(t, 1, one.LearnRate(30), 1e-9)
+	require.InDelta(t, 0.01, one.LearnRate(100), 1e-9)
+
+	warm := LinearWarmup{Steps: 4, Schedule: StepDecay{Initial: 1, Every: 100}}
+	require.Equal(t, 0.25, warm.LearnRate(0))
+	require.Equal(t, 1.0, warm.LearnRate(4))
+
+	monitor := &constMonitor{val: 1}
+	plateau := &ReduceOnPlateau{Initial: 1, Monitor: monitor, Patience: 1}
+	require.Equal(t, 1.0, plateau.LearnRate(0))
+	require.Equal(t, 1.0, plateau.LearnRate(1))
+	require.Equal(t, 1.0, plateau.LearnRate(2))
+	require.InDelta(t, 0.1, plateau.LearnRate(3), 1e-9)
+	monitor.val = 0.5
+	require.InDelta(t, 0.1, plateau.LearnRate(4), 1e-9)
+}
+
+func TestScheduledOptimizer(t *testing.T) {
+	_, err := NewScheduledOptimizer(g.NewAdaGradSolver(), StepDecay{Initial: 1})
+	require.Error(t, err)
+
+	opt, err := NewScheduledOptimizer(g.NewVanillaSolver(), StepDecay{Initial: 1, Every: 1}, PerEpoch())
+	require.NoError(t, err)
+	require.Equal(t, 1.0, opt.LearnRate())
+
+	require.NoError(t, opt.Step(nil))
+	require.Equal(t, 1.0, opt.LearnRate())
+	opt.NextEpoch()
+	require.NoError(t, opt.Step(nil))
+	require.Equal(t, 0.5, opt.LearnRate())
+	require.Equal(t, 2, opt.Steps())
+}
+
+func TestSequentialNextEpoch(t *testing.T) {
+	opt, err := NewScheduledOptimizer(g.NewVanillaSolver(), StepDecay{Initial: 1, Every: 1}, PerEpoch())
+	require.NoError(t, err)
+
+	model, err := NewSequential("epochs")
+	require.NoError(t, err)
+	err = model.AddLayers(
+		layer.FC{Input: 3, Output: 2, Activation: layer.Linear, Name: "w0"},
+	)
+	require.NoError(t, err)
+	err = model.Compile(NewInput("x", []int{1, 3}), NewInput("y", []int{1, 2}),
+		WithOptimizer(opt),
+		WithBatchSize(4),
+		WithoutTracker(),
+	)
+	require.NoError(t, err)
+
+	x := tensor.New(tensor.WithShape(4, 3), tensor.WithBacking(tensor.Range(tensor.Float32, 0, 12)))
+	y := tensor.New(tensor.WithShape(4, 2), tensor.WithBacking(tensor.Range(tensor.Float32, 0, 8)))
+	for epoch, lr := range []float64{1, 0.5, 0.25} {
+		for i := 0; i < 2; i++ {
+			err = model.FitBatch(x, y)
+			require.NoError(t, err)
+		}
+		require.Equal(t, lr, opt.LearnRate())
+		require.Equal(t, epoch, opt.Epoch())
+		model.NextEpoch()
+	}
+	require.Equal(t, 6, opt.Steps())
+}
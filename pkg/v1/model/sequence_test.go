@@ -0,0 +1,106 @@
+package model_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/aunum/goro/pkg/v1/layer"
+	. "github.com/aunum/goro/pkg/v1/model"
+
+	"github.com/stretchr/testify/require"
+	"gorgonia.org/tensor"
+)
+
+func TestSequenceWrappers(t *testing.T) {
+	model, err := NewSequential("sequence")
+	require.NoError(t, err)
+	err = model.AddLayers(
+		layer.TimeDistributed{Layer: layer.FC{Name: "steps", Output: 4, Activation: layer.Tanh}},
+		layer.Bidirectional{Layer: layer.TimeDistributed{Layer: layer.FC{Name: "bi1", Output: 3, Activation: layer.Tanh}}, Sequences: true},
+		layer.Bidirectional{Layer: layer.TimeDistributed{Layer: layer.FC{Name: "bi2", Output: 2, Activation: layer.Tanh}}, Sequences: true, Merge: layer.MergeAverage},
+		layer.Flatten{},
+		layer.FC{Name: "out", Output: 2, Activation: layer.Linear},
+	)
+	require.NoError(t, err)
+	err = model.Compile(NewInput("x", []int{1, 3, 2}), NewInput("y", []int{1, 2}),
+		WithBatchSize(2),
+		WithoutTracker(),
+	)
+	require.NoError(t, err)
+	require.Len(t, model.Learnables(), 12)
+	require.Equal(t, "backward-bi1", model.Learnables()[4].Name())
+
+	summaries, err := model.Summary()
+	require.NoError(t, err)
+	require.Equal(t, tensor.Shape{1, 3, 6}, summaries[1].OutputShape)
+	require.Equal(t, tensor.Shape{1, 3, 2}, summaries[2].OutputShape)
+
+	x := tensor.New(tensor.WithShape(2, 3, 2), tensor.WithBacking(tensor.Range(tensor.Float32, 0, 12)))
+	y := tensor.New(tensor.WithShape(2, 2), tensor.WithBacking([]float32{1, 0, 0, 1}))
+	requireTrainedShared(t, model, x, y, true)
+
+	// the wrapped layers are stateless, so each timestep is computed independently with the learnables of
+	// the time distributed layers, and the backward outputs are reversed back to align with the forward.
+	values := [][]float32{}
+	for _, learnable := range model.Learnables() {
+		values = append(values, learnable.Value().Data().([]float32))
+	}
+	fc := func(x, w, b []float32, act func(float64) float64) []float32 {
+		out := make([]float32, len(b))
+		for j := range out {
+			v := float64(b[j])
+			for k := range x {
+				v += float64(x[k] * w[k*len(b)+j])
+			}
+			out[j] = float32(act(v))
+		}
+		return out
+	}
+	linear := func(v float64) float64 { return v }
+	expected := []float32{}
+	for i := 0; i < 2; i++ {
+		flat := []float32{}
+		for step := 0; step < 3; step++ {
+			h := fc(x.Data().([]float32)[i*6+step*2:i*6+step*2+2], values[0], values[1], math.Tanh)
+			h = append(fc(h, values[2], values[3], math.Tanh), fc(h, values[4], values[5], math.Tanh)...)
+			forward, backward := fc(h, values[6], values[7], math.Tanh), fc(h, values[8], values[9], math.Tanh)
+			for j := range forward {
+				flat = append(flat, (forward[j]+backward[j])/2)
+			}
+		}
+		expected = append(expected, fc(flat, values[10], values[11], linear)...)
+	}
+	prediction, err := model.PredictBatch(x)
+	require.NoError(t, err)
+	require.InDeltaSlice(t, expected, prediction.Data(), 1e-4)
+
+	_, err = layer.NewChain(layer.Bidirectional{Layer: layer.FC{Output: 2}, Merge: "max"})
+	require.Error(t, err)
+}
+
+func TestBidirectionalSimpleRNN(t *testing.T) {
+	model, err := NewSequential("rnn")
+	require.NoError(t, err)
+	err = model.AddLayers(
+		layer.Bidirectional{Layer: layer.SimpleRNN{Name: "rnn1", Output: 3, Sequences: true}, Sequences: true},
+		layer.Bidirectional{Layer: layer.SimpleRNN{Name: "rnn2", Output: 2}},
+		layer.FC{Name: "out", Output: 2, Activation: layer.Linear},
+	)
+	require.NoError(t, err)
+	err = model.Compile(NewInput("x", []int{1, 3, 2}), NewInput("y", []int{1, 2}),
+		WithBatchSize(2),
+		WithoutTracker(),
+	)
+	require.NoError(t, err)
+	require.Len(t, model.Learnables(), 14)
+	require.Equal(t, "backward-rnn1", model.Learnables()[3].Name())
+
+	summaries, err := model.Summary()
+	require.NoError(t, err)
+	require.Equal(t, tensor.Shape{1, 3, 6}, summaries[0].OutputShape)
+	require.Equal(t, tensor.Shape{1, 4}, summaries[1].OutputShape)
+
+	x := tensor.New(tensor.WithShape(2, 3, 2), tensor.WithBacking([]float32{1, 2, 3, 4, 5, 6, -1, 0, 0, 2, -3, 1}))
+	y := tensor.New(tensor.WithShape(2, 2), tensor.WithBacking([]float32{1, 0, 0, 1}))
+	requireTrainedShared(t, model, x, y, true)
+}
@@ -0,0 +1,256 @@
+package model
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	cgraph "github.com/aunum/goro/pkg/v1/common/graph"
+	"github.com/aunum/goro/pkg/v1/layer"
+
+	g "gorgonia.org/gorgonia"
+	t "gorgonia.org/tensor"
+)
+
+// LayerSummary describes a layer of a compiled model.
+type LayerSummary struct {
+	// Name of the layer.
+	Name string `json:"name"`
+
+	// Type of the layer e.g. fc.
+	Type string `json:"type"`
+
+	// OutputShape is the shape of the output of the layer in the train graph.
+	OutputShape t.Shape `json:"outputShape"`
+
+	// Params is the number of learnable parameters in the layer.
+	Params int `json:"params"`
+}
+
+// Summary describes the layers of the model.
+func (s *Sequential) Summary() ([]LayerSummary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.summary()
+}
+
+func (s *Sequential) summary() ([]LayerSummary, error) {
+	err := s.checkCompiled()
+	if err != nil {
+		return nil, err
+	}
+	learnables := map[*g.Node]bool{}
+	for _, learnable := range s.trainChain.Learnables() {
+		learnables[learnable] = true
+	}
+	outputs := s.trainChain.Outputs()
+	summaries := []LayerSummary{}
+	for i, nodes := range s.trainChain.LayerNodes() {
+		config := s.trainChain.Layers[i]
+		summary := LayerSummary{Type: fmt.Sprintf("%T", config)}
+		encoded, err := layer.Encode(config)
+		if err == nil {
+			summary.Type = encoded.Type
+			named := struct {
+				Name string `json:"name"`
+			}{}
+			err = json.Unmarshal(encoded.Config, &named)
+			if err != nil {
+				return nil, err
+			}
+			summary.Name = named.Name
+		}
+		if summary.Name == "" {
+			summary.Name = fmt.Sprintf("%s_%d", summary.Type, i)
+		}
+		if i < len(outputs) {
+			summary.OutputShape = outputs[i].Shape()
+		}
+		for _, n := range nodes {
+			if learnables[n] {
+				summary.Params += n.Shape().TotalSize()
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// Visualize the graph of the model by name with graphviz, grouping the nodes by layer.
+//
+// Note: this requires graphviz `dot` to be installed on the host os, use WriteSVG, WriteHTML or
+// WriteDot on headless servers.
+func (s *Sequential) Visualize(name string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	graph, groups, err := s.layerGroups(name)
+	if err != nil {
+		return err
+	}
+	return cgraph.Visualize(graph, groups...)
+}
+
+// WriteDot writes the graph of the model by name in graphviz DOT format, optionally grouping the
+// nodes by layer.
+func (s *Sequential) WriteDot(w io.Writer, name string, groupByLayer bool) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	graph, groups, err := s.layerGroups(name)
+	if err != nil {
+		return err
+	}
+	if !groupByLayer {
+		groups = nil
+	}
+	return cgraph.WriteDot(w, graph, groups...)
+}
+
+// LayerGraph returns the graph of the model by name along with its nodes grouped by layer.
+func (s *Sequential) LayerGraph(name string) (*g.ExprGraph, []cgraph.Group, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.layerGroups(name)
+}
+
+func (s *Sequential) layerGroups(name string) (*g.ExprGraph, []cgraph.Group, error) {
+	graphs := s.Graphs()
+	graph, ok := graphs[name]
+	if !ok || graph == nil {
+		names := []string{}
+		for n := range graphs {
+			names = append(names, n)
+		}
+		return nil, nil, fmt.Errorf("no graph %q, graphs are %v", name, names)
+	}
+	chains := s.sharedChains()
+	chains["train"] = s.trainChain
+	var chain *layer.Chain
+	for _, c := range chains {
+		if len(c.Outputs()) > 0 && c.Outputs()[0].Graph() == graph {
+			chain = c
+		}
+	}
+	if chain == nil {
+		return graph, nil, nil
+	}
+	summaries, err := s.summary()
+	if err != nil {
+		return nil, nil, err
+	}
+	groups := []cgraph.Group{}
+	for i, nodes := range chain.LayerNodes() {
+		groups = append(groups, cgraph.Group{Name: fmt.Sprintf("%s (%s)", summaries[i].Name, summaries[i].Type), Nodes: nodes})
+	}
+	return graph, groups, nil
+}
+
+const (
+	svgWidth     = 420
+	svgBoxHeight = 52
+	svgGap       = 28
+	svgMargin    = 20
+)
+
+// WriteSVG writes a diagram of the layers of the model as an SVG image.
+func (s *Sequential) WriteSVG(w io.Writer) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	summaries, err := s.summary()
+	if err != nil {
+		return err
+	}
+	type box struct {
+		title, detail string
+	}
+	boxes := []box{{
+		title:  fmt.Sprintf("input %s", s.fwd.Name()),
+		detail: fmt.Sprintf("shape %v  %v", s.fwd.Shape(), s.dtype),
+	}}
+	for _, summary := range summaries {
+		boxes = append(boxes, box{
+			title:  fmt.Sprintf("%s (%s)", summary.Name, summary.Type),
+			detail: fmt.Sprintf("output %v  params %d", summary.OutputShape, summary.Params),
+		})
+	}
+	height := 2*svgMargin + 30 + len(boxes)*svgBoxHeight + (len(boxes)-1)*svgGap
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Helvetica, Arial, sans-serif">`+"\n", svgWidth, height, svgWidth, height)
+	fmt.Fprintln(bw, `<defs><marker id="arrow" markerWidth="10" markerHeight="10" refX="5" refY="5" orient="auto"><path d="M0,0 L10,5 L0,10 z" fill="#555"/></marker></defs>`)
+	fmt.Fprintf(bw, `<text x="%d" y="%d" font-size="16" font-weight="bold" text-anchor="middle">%s</text>`+"\n", svgWidth/2, svgMargin+10, html.EscapeString(s.name))
+	boxWidth := svgWidth - 2*svgMargin
+	for i, b := range boxes {
+		y := svgMargin + 30 + i*(svgBoxHeight+svgGap)
+		fill := "#e8f0fe"
+		if i == 0 {
+			fill = "#eeeeee"
+		}
+		fmt.Fprintf(bw, `<rect x="%d" y="%d" width="%d" height="%d" rx="6" fill="%s" stroke="#555"/>`+"\n", svgMargin, y, boxWidth, svgBoxHeight, fill)
+		fmt.Fprintf(bw, `<text x="%d" y="%d" font-size="14" text-anchor="middle">%s</text>`+"\n", svgWidth/2, y+21, html.EscapeString(b.title))
+		fmt.Fprintf(bw, `<text x="%d" y="%d" font-size="12" fill="#444" text-anchor="middle">%s</text>`+"\n", svgWidth/2, y+40, html.EscapeString(b.detail))
+		if i > 0 {
+			fmt.Fprintf(bw, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#555" marker-end="url(#arrow)"/>`+"\n", svgWidth/2, y-svgGap, svgWidth/2, y-5)
+		}
+	}
+	fmt.Fprintln(bw, "</svg>")
+	return bw.Flush()
+}
+
+// WriteHTML writes a page with a diagram and a table of the layers of the model.
+func (s *Sequential) WriteHTML(w io.Writer) error {
+	summaries, err := s.Summary()
+	if err != nil {
+		return err
+	}
+	var svg strings.Builder
+	err = s.WriteSVG(&svg)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	name := html.EscapeString(s.name)
+	fmt.Fprintf(bw, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n", name)
+	fmt.Fprintln(bw, "<style>body{font-family:Helvetica,Arial,sans-serif;margin:2em}table{border-collapse:collapse;margin-top:1em}th,td{border:1px solid #ccc;padding:4px 10px;text-align:left}</style>")
+	fmt.Fprintf(bw, "</head>\n<body>\n<h1>%s</h1>\n", name)
+	fmt.Fprintln(bw, svg.String())
+	fmt.Fprintln(bw, "<table>\n<tr><th>Layer</th><th>Type</th><th>Output Shape</th><th>Params</th></tr>")
+	total := 0
+	for _, summary := range summaries {
+		fmt.Fprintf(bw, "<tr><td>%s</td><td>%s</td><td>%v</td><td>%d</td></tr>\n", html.EscapeString(summary.Name), html.EscapeString(summary.Type), summary.OutputShape, summary.Params)
+		total += summary.Params
+	}
+	fmt.Fprintf(bw, "<tr><th colspan=\"3\">Total</th><th>%d</th></tr>\n</table>\n</body>\n</html>\n", total)
+	return bw.Flush()
+}
+
+// VisualizeFile writes a visualization of the model to the path in the format of its extension, which
+// is .svg, .html or .dot for the train graph grouped by layer.
+func (s *Sequential) VisualizeFile(path string) error {
+	var write func(io.Writer) error
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".svg":
+		write = s.WriteSVG
+	case ".html", ".htm":
+		write = s.WriteHTML
+	case ".dot", ".gv":
+		write = func(w io.Writer) error {
+			return s.WriteDot(w, "train", true)
+		}
+	default:
+		return fmt.Errorf("unsupported visualization format %q, use .svg, .html or .dot", ext)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,50 @@
+package model_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aunum/goro/pkg/v1/layer"
+	. "github.com/aunum/goro/pkg/v1/model"
+
+	"github.com/stretchr/testify/require"
+	"gorgonia.org/tensor"
+)
+
+func TestVisualize(t *testing.T) {
+	model, err := NewSequential("viz")
+	require.NoError(t, err)
+	err = model.AddLayers(
+		layer.FC{Output: 4, Name: "hidden"},
+		layer.FC{Output: 2, Activation: layer.Linear},
+	)
+	require.NoError(t, err)
+	err = model.Compile(NewInput("x", []int{1, 3}), NewInput("y", []int{1, 2}), WithoutTracker())
+	require.NoError(t, err)
+
+	summaries, err := model.Summary()
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+	require.Equal(t, LayerSummary{Name: "hidden", Type: "fc", OutputShape: tensor.Shape{1, 4}, Params: 16}, summaries[0])
+	require.Equal(t, 10, summaries[1].Params)
+
+	var buf bytes.Buffer
+	require.NoError(t, model.WriteSVG(&buf))
+	require.True(t, strings.HasPrefix(buf.String(), "<svg"))
+	require.Contains(t, buf.String(), "hidden (fc)")
+
+	buf.Reset()
+	require.NoError(t, model.WriteHTML(&buf))
+	require.Contains(t, buf.String(), "<td>hidden</td>")
+
+	buf.Reset()
+	require.NoError(t, model.WriteDot(&buf, "trainBatch", true))
+	require.Contains(t, buf.String(), `label="hidden (fc)"`)
+	buf.Reset()
+	require.NoError(t, model.WriteDot(&buf, "online", false))
+	require.NotContains(t, buf.String(), "cluster_")
+
+	require.Error(t, model.WriteDot(&buf, "missing", true))
+	require.Error(t, model.VisualizeFile("model.png"))
+}
@@ -0,0 +1,224 @@
+package onnx
+
+import (
+	"fmt"
+
+	"github.com/aunum/goro/pkg/v1/layer"
+	"github.com/aunum/goro/pkg/v1/model"
+
+	g "gorgonia.org/gorgonia"
+)
+
+// OutputName is the name of the output of exported models.
+const OutputName = "output"
+
+// Export a compiled sequential model with its learnables as an ONNX model. The first dimension of the
+// input and output is the batch, which is dynamic. Dropout is exported for inference, where it does
+// nothing.
+func Export(m *model.Sequential) (*Model, error) {
+	learnables := m.Learnables()
+	if learnables == nil {
+		return nil, fmt.Errorf("model %q must be compiled before it is exported", m.Name())
+	}
+	elemType, err := FromDtype(m.DType())
+	if err != nil {
+		return nil, err
+	}
+	e := &exporter{graph: &Graph{Name: m.Name()}, learnables: learnables}
+	fwd := m.FwdInput()
+	e.value = fwd.Name()
+	e.graph.Inputs = []*ValueInfo{{Name: fwd.Name(), ElemType: elemType, Shape: batchShape(fwd.Shape())}}
+	for i, config := range m.Chain.Layers {
+		err = e.export(config.ApplyDefaults(), i)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(e.learnables) != 0 {
+		return nil, fmt.Errorf("%d learnables of the model do not belong to a layer", len(e.learnables))
+	}
+	if len(e.graph.Nodes) == 0 {
+		e.add(&Node{Name: "identity", OpType: "Identity"})
+	}
+	e.graph.Nodes[len(e.graph.Nodes)-1].Outputs = []string{OutputName}
+	e.graph.Outputs = []*ValueInfo{{Name: OutputName, ElemType: elemType, Shape: batchShape(m.OutputShape())}}
+	return &Model{
+		IRVersion:    IRVersion,
+		OpsetVersion: OpsetVersion,
+		ProducerName: "goro",
+		Graph:        e.graph,
+	}, nil
+}
+
+// ExportFile exports a compiled sequential model to the file at the path.
+func ExportFile(m *model.Sequential, path string) error {
+	onnxModel, err := Export(m)
+	if err != nil {
+		return err
+	}
+	return onnxModel.WriteFile(path)
+}
+
+// batchShape returns the shape with a dynamic batch dimension.
+func batchShape(shape []int) []int64 {
+	if len(shape) <= 1 || shape[0] != 1 {
+		shape = append([]int{1}, shape...)
+	}
+	ret := []int64{-1}
+	for _, d := range shape[1:] {
+		ret = append(ret, int64(d))
+	}
+	return ret
+}
+
+// exporter adds the nodes of layers to a graph.
+type exporter struct {
+	graph *Graph
+
+	// value is the name of the current output.
+	value string
+
+	// learnables not yet exported in layer order.
+	learnables g.Nodes
+}
+
+// add a node to the graph taking the current value as its first input.
+func (e *exporter) add(n *Node, inputs ...string) {
+	n.Inputs = append([]string{e.value}, inputs...)
+	e.value = n.Name
+	n.Outputs = []string{n.Name}
+	e.graph.Nodes = append(e.graph.Nodes, n)
+}
+
+// initializer adds the next learnable as an initializer, optionally with other dims of the same size.
+func (e *exporter) initializer(name string, dims ...int64) (string, error) {
+	if len(e.learnables) == 0 {
+		return "", fmt.Errorf("model has no learnable for %q", name)
+	}
+	learnable := e.learnables[0]
+	e.learnables = e.learnables[1:]
+	dataType, err := FromDtype(learnable.Dtype())
+	if err != nil {
+		return "", err
+	}
+	if len(dims) == 0 {
+		for _, d := range learnable.Shape() {
+			dims = append(dims, int64(d))
+		}
+	}
+	e.graph.Initializers = append(e.graph.Initializers, &Tensor{Name: name, Dims: dims, DataType: dataType, Data: learnable.Value().Data()})
+	return name, nil
+}
+
+func (e *exporter) export(config layer.Config, i int) error {
+	switch c := config.(type) {
+	case layer.FC:
+		name := layerName(c.Name, "fc", i)
+		inputs := []string{}
+		weights, err := e.initializer(name + "_weights")
+		if err != nil {
+			return err
+		}
+		inputs = append(inputs, weights)
+		if !c.NoBias {
+			bias, err := e.initializer(name + "_bias")
+			if err != nil {
+				return err
+			}
+			inputs = append(inputs, bias)
+		}
+		e.add(&Node{Name: name, OpType: "Gemm"}, inputs...)
+		return e.activation(name, c.Activation)
+	case layer.Conv2D:
+		name := layerName(c.Name, "conv2d", i)
+		filter, err := e.initializer(name + "_filter")
+		if err != nil {
+			return err
+		}
+		inputs := []string{filter}
+		if c.Bias {
+			bias, err := e.initializer(name+"_bias", int64(c.Output))
+			if err != nil {
+				return err
+			}
+			inputs = append(inputs, bias)
+		}
+		e.add(&Node{Name: name, OpType: "Conv", Attributes: []*Attribute{
+			ints("kernel_shape", c.Height, c.Width),
+			ints("pads", c.Pad[0], c.Pad[1], c.Pad[0], c.Pad[1]),
+			ints("strides", c.Stride...),
+			ints("dilations", c.Dilation...),
+		}}, inputs...)
+		return e.activation(name, c.Activation)
+	case layer.MaxPooling2D:
+		name := layerName(c.Name, "maxpooling2d", i)
+		e.add(&Node{Name: name, OpType: "MaxPool", Attributes: []*Attribute{
+			ints("kernel_shape", c.Kernel...),
+			ints("pads", c.Pad[0], c.Pad[1], c.Pad[0], c.Pad[1]),
+			ints("strides", c.Stride...),
+		}})
+	case layer.Flatten:
+		e.add(&Node{Name: layerName("", "flatten", i), OpType: "Flatten", Attributes: []*Attribute{
+			{Name: "axis", Type: AttributeInt, Int: 1},
+		}})
+	case layer.Reshape:
+		name := layerName("", "reshape", i)
+		shape := []int64{-1}
+		for _, d := range c.To {
+			shape = append(shape, int64(d))
+		}
+		e.graph.Initializers = append(e.graph.Initializers, &Tensor{Name: name + "_shape", Dims: []int64{int64(len(shape))}, DataType: Int64, Data: shape})
+		e.add(&Node{Name: name, OpType: "Reshape"}, name+"_shape")
+	case layer.Dropout:
+		name := layerName("", "dropout", i)
+		e.graph.Initializers = append(e.graph.Initializers, &Tensor{Name: name + "_ratio", DataType: Float, Data: []float32{float32(c.Probability)}})
+		e.add(&Node{Name: name, OpType: "Dropout"}, name+"_ratio")
+	default:
+		return fmt.Errorf("layer %T cannot be exported to onnx", config)
+	}
+	return nil
+}
+
+// activation adds the node of the activation function of a layer.
+func (e *exporter) activation(name string, fn layer.ActivationFn) error {
+	switch a := fn.(type) {
+	case nil, *layer.LinearActivation:
+	case *layer.SigmoidActivation:
+		e.add(&Node{Name: name + "_sigmoid", OpType: "Sigmoid"})
+	case *layer.TanhActivation:
+		e.add(&Node{Name: name + "_tanh", OpType: "Tanh"})
+	case *layer.ReLUActivation:
+		e.add(&Node{Name: name + "_relu", OpType: "Relu"})
+	case *layer.LeakyReLUActivation:
+		e.add(&Node{Name: name + "_leaky_relu", OpType: "LeakyRelu", Attributes: []*Attribute{
+			{Name: "alpha", Type: AttributeFloat, Float: float32(a.Alpha())},
+		}})
+	case *layer.SoftmaxActivation:
+		axis := int64(-1)
+		if len(a.Axis()) > 0 {
+			axis = int64(a.Axis()[0])
+		}
+		e.add(&Node{Name: name + "_softmax", OpType: "Softmax", Attributes: []*Attribute{
+			{Name: "axis", Type: AttributeInt, Int: axis},
+		}})
+	default:
+		return fmt.Errorf("activation %T cannot be exported to onnx", fn)
+	}
+	return nil
+}
+
+// layerName returns the name of the layer or one made from its type and index if it has none.
+func layerName(name, typ string, i int) string {
+	if name != "" {
+		return name
+	}
+	return fmt.Sprintf("%s_%d", typ, i)
+}
+
+func ints(name string, vs ...int) *Attribute {
+	a := &Attribute{Name: name, Type: AttributeInts}
+	for _, v := range vs {
+		a.Ints = append(a.Ints, int64(v))
+	}
+	return a
+}
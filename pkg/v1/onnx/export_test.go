@@ -0,0 +1,233 @@
+package onnx_test
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/aunum/goro/pkg/v1/layer"
+	"github.com/aunum/goro/pkg/v1/model"
+	. "github.com/aunum/goro/pkg/v1/onnx"
+
+	"github.com/stretchr/testify/require"
+	"gorgonia.org/tensor"
+)
+
+func TestExport(t *testing.T) {
+	m, err := model.NewSequential("onnx")
+	require.NoError(t, err)
+	err = m.AddLayers(
+		layer.Conv2D{Output: 2, Height: 3, Width: 3, Name: "conv"},
+		layer.MaxPooling2D{},
+		layer.Flatten{},
+		layer.FC{Output: 6, Activation: layer.NewLeakyReLU(0.1)},
+		layer.Reshape{To: []int{2, 3}},
+		layer.Flatten{},
+		layer.FC{Output: 4, Activation: layer.Tanh, NoBias: true},
+		layer.FC{Output: 3, Activation: layer.Softmax},
+	)
+	require.NoError(t, err)
+	err = m.Compile(model.NewInput("x", []int{1, 1, 6, 6}), model.NewInput("y", []int{1, 3}), model.WithoutTracker())
+	require.NoError(t, err)
+
+	exported, err := Export(m)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, exported.Write(&buf))
+	decoded, err := Read(&buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(IRVersion), decoded.IRVersion)
+	require.Equal(t, int64(OpsetVersion), decoded.OpsetVersion)
+	require.Equal(t, []int64{-1, 1, 6, 6}, decoded.Graph.Inputs[0].Shape)
+	require.Equal(t, Float, decoded.Graph.Inputs[0].ElemType)
+	require.Equal(t, []int64{-1, 3}, decoded.Graph.Outputs[0].Shape)
+	require.Equal(t, []int64{2, 1, 3, 3}, decoded.Graph.Initializer("conv_filter").Dims)
+	ops := []string{}
+	for _, n := range decoded.Graph.Nodes {
+		ops = append(ops, n.OpType)
+	}
+	require.Equal(t, []string{"Conv", "Relu", "MaxPool", "Flatten", "Gemm", "LeakyRelu", "Reshape", "Flatten", "Gemm", "Tanh", "Gemm", "Softmax"}, ops)
+
+	for i := 0; i < 3; i++ {
+		x := make([]float32, 36)
+		for j := range x {
+			x[j] = float32(math.Sin(float64(i*36 + j)))
+		}
+		prediction, err := m.Predict(tensor.New(tensor.WithShape(1, 1, 6, 6), tensor.WithBacking(x)))
+		require.NoError(t, err)
+
+		in := value{shape: []int{1, 1, 6, 6}}
+		for _, v := range x {
+			in.data = append(in.data, float64(v))
+		}
+		out, err := run(decoded.Graph, in)
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 3}, out.shape)
+		require.Len(t, prediction.Data(), 3)
+		for j, v := range prediction.Data().([]float32) {
+			require.InDelta(t, float64(v), out.data[j], 1e-5)
+		}
+	}
+
+	dropout, err := model.NewSequential("dropout")
+	require.NoError(t, err)
+	require.NoError(t, dropout.AddLayers(layer.Dropout{Probability: 0.2}, layer.FC{Output: 2}))
+	require.NoError(t, dropout.Compile(model.NewInput("x", []int{1, 3}), model.NewInput("y", []int{1, 2}), model.WithoutTracker()))
+	exported, err = Export(dropout)
+	require.NoError(t, err)
+	require.Equal(t, "Dropout", exported.Graph.Nodes[0].OpType)
+	require.Equal(t, []float32{0.2}, exported.Graph.Initializer("dropout_0_ratio").Data)
+
+	uncompiled, err := model.NewSequential("uncompiled")
+	require.NoError(t, err)
+	_, err = Export(uncompiled)
+	require.Error(t, err)
+}
+
+// value is a tensor of the reference evaluator.
+type value struct {
+	shape []int
+	data  []float64
+}
+
+// run evaluates the graph with a minimal reference implementation of the exported operators.
+func run(graph *Graph, x value) (value, error) {
+	values := map[string]value{graph.Inputs[0].Name: x}
+	for _, init := range graph.Initializers {
+		v := value{}
+		for _, d := range init.Dims {
+			v.shape = append(v.shape, int(d))
+		}
+		switch data := init.Data.(type) {
+		case []float32:
+			for _, d := range data {
+				v.data = append(v.data, float64(d))
+			}
+		case []int64:
+			for _, d := range data {
+				v.data = append(v.data, float64(d))
+			}
+		}
+		values[init.Name] = v
+	}
+	for _, n := range graph.Nodes {
+		in := values[n.Inputs[0]]
+		var out value
+		switch n.OpType {
+		case "Gemm":
+			w := values[n.Inputs[1]]
+			rows, k, cols := in.shape[0], in.shape[1], w.shape[1]
+			out = value{shape: []int{rows, cols}, data: make([]float64, rows*cols)}
+			for r := 0; r < rows; r++ {
+				for c := 0; c < cols; c++ {
+					sum := 0.0
+					for i := 0; i < k; i++ {
+						sum += in.data[r*k+i] * w.data[i*cols+c]
+					}
+					if len(n.Inputs) > 2 {
+						sum += values[n.Inputs[2]].data[c]
+					}
+					out.data[r*cols+c] = sum
+				}
+			}
+		case "Conv", "MaxPool":
+			out = window(n, in, values)
+		case "Flatten":
+			out = value{shape: []int{in.shape[0], len(in.data) / in.shape[0]}, data: in.data}
+		case "Reshape":
+			out = value{shape: []int{in.shape[0]}, data: in.data}
+			for _, d := range values[n.Inputs[1]].data[1:] {
+				out.shape = append(out.shape, int(d))
+			}
+		case "Dropout":
+			out = in
+		case "Relu", "LeakyRelu", "Tanh", "Sigmoid":
+			out = value{shape: in.shape, data: make([]float64, len(in.data))}
+			for i, v := range in.data {
+				switch {
+				case n.OpType == "Tanh":
+					v = math.Tanh(v)
+				case n.OpType == "Sigmoid":
+					v = 1 / (1 + math.Exp(-v))
+				case v < 0 && n.OpType == "Relu":
+					v = 0
+				case v < 0:
+					v *= float64(n.Attr("alpha").Float)
+				}
+				out.data[i] = v
+			}
+		case "Softmax":
+			out = value{shape: in.shape, data: make([]float64, len(in.data))}
+			cols := in.shape[len(in.shape)-1]
+			for r := 0; r < len(in.data)/cols; r++ {
+				sum := 0.0
+				for c := 0; c < cols; c++ {
+					out.data[r*cols+c] = math.Exp(in.data[r*cols+c])
+					sum += out.data[r*cols+c]
+				}
+				for c := 0; c < cols; c++ {
+					out.data[r*cols+c] /= sum
+				}
+			}
+		default:
+			return value{}, fmt.Errorf("unsupported op %q", n.OpType)
+		}
+		values[n.Outputs[0]] = out
+	}
+	return values[graph.Outputs[0].Name], nil
+}
+
+// window evaluates a convolution or max pool over NCHW input.
+func window(n *Node, in value, values map[string]value) value {
+	kernel, pads, strides := n.Attr("kernel_shape").Ints, n.Attr("pads").Ints, n.Attr("strides").Ints
+	dilations := []int64{1, 1}
+	if d := n.Attr("dilations"); d != nil {
+		dilations = d.Ints
+	}
+	batch, channels, height, width := in.shape[0], in.shape[1], in.shape[2], in.shape[3]
+	outChannels := channels
+	var filter value
+	if n.OpType == "Conv" {
+		filter = values[n.Inputs[1]]
+		outChannels = filter.shape[0]
+	}
+	kh, kw := int(kernel[0]), int(kernel[1])
+	oh := (height+int(pads[0]+pads[2])-int(dilations[0])*(kh-1)-1)/int(strides[0]) + 1
+	ow := (width+int(pads[1]+pads[3])-int(dilations[1])*(kw-1)-1)/int(strides[1]) + 1
+	out := value{shape: []int{batch, outChannels, oh, ow}, data: make([]float64, batch*outChannels*oh*ow)}
+	for b := 0; b < batch; b++ {
+		for o := 0; o < outChannels; o++ {
+			for y := 0; y < oh; y++ {
+				for x := 0; x < ow; x++ {
+					acc := 0.0
+					if n.OpType == "MaxPool" {
+						acc = math.Inf(-1)
+					}
+					for c := 0; c < channels; c++ {
+						if n.OpType == "MaxPool" && c != o {
+							continue
+						}
+						for i := 0; i < kh; i++ {
+							for j := 0; j < kw; j++ {
+								iy := y*int(strides[0]) + i*int(dilations[0]) - int(pads[0])
+								ix := x*int(strides[1]) + j*int(dilations[1]) - int(pads[1])
+								if iy < 0 || ix < 0 || iy >= height || ix >= width {
+									continue
+								}
+								v := in.data[((b*channels+c)*height+iy)*width+ix]
+								if n.OpType == "MaxPool" {
+									acc = math.Max(acc, v)
+								} else {
+									acc += v * filter.data[((o*channels+c)*kh+i)*kw+j]
+								}
+							}
+						}
+					}
+					out.data[((b*outChannels+o)*oh+y)*ow+x] = acc
+				}
+			}
+		}
+	}
+	return out
+}
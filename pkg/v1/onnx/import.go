@@ -0,0 +1,500 @@
+package onnx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aunum/goro/pkg/v1/layer"
+	"github.com/aunum/goro/pkg/v1/model"
+
+	g "gorgonia.org/gorgonia"
+	t "gorgonia.org/tensor"
+)
+
+// supportedOps are the operators which can be imported.
+var supportedOps = map[string]bool{
+	"Gemm":      true,
+	"MatMul":    true,
+	"Add":       true,
+	"Conv":      true,
+	"MaxPool":   true,
+	"Flatten":   true,
+	"Reshape":   true,
+	"Dropout":   true,
+	"Identity":  true,
+	"Relu":      true,
+	"LeakyRelu": true,
+	"Sigmoid":   true,
+	"Tanh":      true,
+	"Softmax":   true,
+}
+
+// Import a feed-forward or convolutional ONNX model as a compiled sequential model with the
+// initializers of the model as its learnables. The graph must be a chain of supported operators from
+// a single input, where activations follow a Gemm, MatMul or Conv. Any options are applied when the
+// model is compiled.
+func Import(m *Model, opts ...model.Opt) (*model.Sequential, error) {
+	if m.Graph == nil {
+		return nil, fmt.Errorf("model has no graph")
+	}
+	unsupported := map[string]bool{}
+	for _, n := range m.Graph.Nodes {
+		if !supportedOps[n.OpType] {
+			unsupported[n.OpType] = true
+		}
+	}
+	if len(unsupported) > 0 {
+		ops := []string{}
+		for op := range unsupported {
+			ops = append(ops, op)
+		}
+		sort.Strings(ops)
+		return nil, fmt.Errorf("unsupported onnx operators: %s", strings.Join(ops, ", "))
+	}
+
+	input, err := graphInput(m.Graph)
+	if err != nil {
+		return nil, err
+	}
+	dtype, err := input.ElemType.Dtype()
+	if err != nil {
+		return nil, err
+	}
+	shape := t.Shape{1}
+	for _, d := range input.Shape[1:] {
+		if d < 0 {
+			return nil, fmt.Errorf("input %q has dynamic dimensions %v, only the batch may be dynamic", input.Name, input.Shape)
+		}
+		shape = append(shape, int(d))
+	}
+
+	i := &importer{graph: m.Graph, value: input.Name, dtype: dtype}
+	for _, n := range m.Graph.Nodes {
+		err = i.add(n)
+		if err != nil {
+			return nil, fmt.Errorf("node %q %s: %w", n.Name, n.OpType, err)
+		}
+	}
+	chain, err := layer.NewChain(i.configs...)
+	if err != nil {
+		return nil, err
+	}
+	output, err := chain.InferShapes(shape)
+	if err != nil {
+		return nil, err
+	}
+
+	name := m.Graph.Name
+	if name == "" {
+		name = "onnx"
+	}
+	seq, err := model.NewSequential(name)
+	if err != nil {
+		return nil, err
+	}
+	err = seq.AddLayers(i.configs...)
+	if err != nil {
+		return nil, err
+	}
+	x := model.NewInput(input.Name, shape, model.AsType(dtype))
+	y := model.NewInput("y", output, model.AsType(dtype))
+	err = seq.Compile(x, y, append([]model.Opt{model.WithDType(dtype)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	scratch := g.NewGraph()
+	learnables := g.Nodes{}
+	for _, v := range i.learnables {
+		learnables = append(learnables, g.NodeFromAny(scratch, v))
+	}
+	err = seq.SetLearnables(learnables)
+	if err != nil {
+		return nil, err
+	}
+	return seq, nil
+}
+
+// ImportFile imports an ONNX model from the file at the path.
+func ImportFile(path string, opts ...model.Opt) (*model.Sequential, error) {
+	m, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Import(m, opts...)
+}
+
+// graphInput returns the single input of the graph which is not an initializer.
+func graphInput(graph *Graph) (*ValueInfo, error) {
+	inputs := []*ValueInfo{}
+	for _, input := range graph.Inputs {
+		if graph.Initializer(input.Name) == nil {
+			inputs = append(inputs, input)
+		}
+	}
+	if len(inputs) != 1 {
+		return nil, fmt.Errorf("graph must have a single input, found %d", len(inputs))
+	}
+	if len(inputs[0].Shape) == 0 {
+		return nil, fmt.Errorf("input %q has no shape", inputs[0].Name)
+	}
+	return inputs[0], nil
+}
+
+// importer converts the nodes of a graph into layers.
+type importer struct {
+	graph *Graph
+
+	// value is the name of the current output.
+	value string
+
+	dtype      t.Dtype
+	configs    []layer.Config
+	learnables []*t.Dense
+
+	// canActivate is whether an activation can be folded into the last layer.
+	canActivate bool
+}
+
+func (i *importer) add(n *Node) error {
+	if len(n.Inputs) == 0 || n.Inputs[0] != i.value {
+		return fmt.Errorf("graph is not sequential, expected input %q", i.value)
+	}
+	if len(n.Outputs) == 0 {
+		return fmt.Errorf("node has no output")
+	}
+	i.value = n.Outputs[0]
+	canActivate := false
+	defer func() { i.canActivate = canActivate }()
+
+	switch n.OpType {
+	case "Gemm":
+		if attrInt(n, "transA", 0) != 0 {
+			return fmt.Errorf("transA is not supported")
+		}
+		if attrFloat(n, "alpha", 1) != 1 || attrFloat(n, "beta", 1) != 1 {
+			return fmt.Errorf("alpha and beta other than 1 are not supported")
+		}
+		weights, err := i.initializer(n, 1)
+		if err != nil {
+			return err
+		}
+		if len(weights.Shape()) != 2 {
+			return fmt.Errorf("weights must be a matrix, got shape %v", weights.Shape())
+		}
+		if attrInt(n, "transB", 0) != 0 {
+			weights, err = transpose(weights)
+			if err != nil {
+				return err
+			}
+		}
+		i.fc(weights)
+		if len(n.Inputs) > 2 && n.Inputs[2] != "" {
+			err = i.bias(n, 2)
+			if err != nil {
+				return err
+			}
+		}
+		canActivate = true
+	case "MatMul":
+		weights, err := i.initializer(n, 1)
+		if err != nil {
+			return err
+		}
+		if len(weights.Shape()) != 2 {
+			return fmt.Errorf("weights must be a matrix, got shape %v", weights.Shape())
+		}
+		i.fc(weights)
+		canActivate = true
+	case "Add":
+		fc, ok := i.last().(layer.FC)
+		if !ok || !i.canActivate || !fc.NoBias || fc.Activation != layer.Linear {
+			return fmt.Errorf("add must follow a matmul as its bias")
+		}
+		err := i.bias(n, 1)
+		if err != nil {
+			return err
+		}
+		canActivate = true
+	case "Conv":
+		if attrInt(n, "group", 1) != 1 {
+			return fmt.Errorf("grouped convolutions are not supported")
+		}
+		filter, err := i.initializer(n, 1)
+		if err != nil {
+			return err
+		}
+		if len(filter.Shape()) != 4 {
+			return fmt.Errorf("only 2D convolutions are supported, got filter shape %v", filter.Shape())
+		}
+		pad, err := pads(n)
+		if err != nil {
+			return err
+		}
+		i.configs = append(i.configs, layer.Conv2D{
+			Input:      filter.Shape()[1],
+			Output:     filter.Shape()[0],
+			Height:     filter.Shape()[2],
+			Width:      filter.Shape()[3],
+			Name:       n.Name,
+			Activation: layer.Linear,
+			Pad:        pad,
+			Stride:     attrInts(n, "strides", 1, 1),
+			Dilation:   attrInts(n, "dilations", 1, 1),
+		})
+		i.learnables = append(i.learnables, filter)
+		if len(n.Inputs) > 2 && n.Inputs[2] != "" {
+			bias, err := i.initializer(n, 2)
+			if err != nil {
+				return err
+			}
+			err = bias.Reshape(1, filter.Shape()[0], 1, 1)
+			if err != nil {
+				return fmt.Errorf("bias of shape %v does not match the %d outputs", bias.Shape(), filter.Shape()[0])
+			}
+			conv := i.last().(layer.Conv2D)
+			conv.Bias = true
+			i.configs[len(i.configs)-1] = conv
+			i.learnables = append(i.learnables, bias)
+		}
+		canActivate = true
+	case "MaxPool":
+		if attrInt(n, "ceil_mode", 0) != 0 {
+			return fmt.Errorf("ceil mode is not supported")
+		}
+		if a := n.Attr("dilations"); a != nil && (a.Ints[0] != 1 || a.Ints[1] != 1) {
+			return fmt.Errorf("dilations are not supported")
+		}
+		kernel := n.Attr("kernel_shape")
+		if kernel == nil || len(kernel.Ints) != 2 {
+			return fmt.Errorf("only 2D pooling is supported")
+		}
+		pad, err := pads(n)
+		if err != nil {
+			return err
+		}
+		i.configs = append(i.configs, layer.MaxPooling2D{
+			Kernel: t.Shape{int(kernel.Ints[0]), int(kernel.Ints[1])},
+			Pad:    pad,
+			Stride: attrInts(n, "strides", 1, 1),
+			Name:   n.Name,
+		})
+	case "Flatten":
+		if attrInt(n, "axis", 1) != 1 {
+			return fmt.Errorf("only flattening from axis 1 is supported")
+		}
+		i.configs = append(i.configs, layer.Flatten{})
+	case "Reshape":
+		shape, err := i.initializer(n, 1)
+		if err != nil {
+			return err
+		}
+		dims, ok := shape.Data().([]int64)
+		if !ok || len(dims) < 2 || (dims[0] != -1 && dims[0] != 0) {
+			return fmt.Errorf("reshape must keep the batch as the first dimension, got shape %v", shape.Data())
+		}
+		to := t.Shape{}
+		for _, d := range dims[1:] {
+			if d <= 0 {
+				return fmt.Errorf("reshape dimensions after the batch must be set, got %v", dims)
+			}
+			to = append(to, int(d))
+		}
+		i.configs = append(i.configs, layer.Reshape{To: to})
+	case "Dropout":
+		ratio := float64(attrFloat(n, "ratio", 0.5))
+		if len(n.Inputs) > 1 && n.Inputs[1] != "" {
+			r, err := i.initializer(n, 1)
+			if err != nil {
+				return err
+			}
+			ratio = toFloat64(r.Data())
+		}
+		// a dropout with no ratio does nothing, it is skipped as a zero probability defaults to 0.6.
+		if ratio <= 0 {
+			canActivate = i.canActivate
+			break
+		}
+		i.configs = append(i.configs, layer.Dropout{Probability: ratio})
+	case "Identity":
+		canActivate = i.canActivate
+	default:
+		activation, err := activation(n)
+		if err != nil {
+			return err
+		}
+		if !i.canActivate {
+			return fmt.Errorf("activations must follow a gemm, matmul or conv")
+		}
+		switch c := i.last().(type) {
+		case layer.FC:
+			c.Activation = activation
+			i.configs[len(i.configs)-1] = c
+		case layer.Conv2D:
+			c.Activation = activation
+			i.configs[len(i.configs)-1] = c
+		}
+	}
+	return nil
+}
+
+// last returns the last layer, nil if there are none.
+func (i *importer) last() layer.Config {
+	if len(i.configs) == 0 {
+		return nil
+	}
+	return i.configs[len(i.configs)-1]
+}
+
+// fc adds a fully connected layer without a bias for the weights.
+func (i *importer) fc(weights *t.Dense) {
+	i.configs = append(i.configs, layer.FC{
+		Input:      weights.Shape()[0],
+		Output:     weights.Shape()[1],
+		Activation: layer.Linear,
+		NoBias:     true,
+	})
+	i.learnables = append(i.learnables, weights)
+}
+
+// bias adds the input of the node as the bias of the fully connected layer just added.
+func (i *importer) bias(n *Node, input int) error {
+	bias, err := i.initializer(n, input)
+	if err != nil {
+		return err
+	}
+	fc := i.last().(layer.FC)
+	if bias.Shape().TotalSize() != fc.Output {
+		return fmt.Errorf("bias of shape %v does not match the %d outputs", bias.Shape(), fc.Output)
+	}
+	err = bias.Reshape(1, fc.Output)
+	if err != nil {
+		return err
+	}
+	fc.NoBias = false
+	i.configs[len(i.configs)-1] = fc
+	i.learnables = append(i.learnables, bias)
+	return nil
+}
+
+// initializer returns the initializer of an input of the node as a tensor of the model type if it is
+// a float.
+func (i *importer) initializer(n *Node, input int) (*t.Dense, error) {
+	if len(n.Inputs) <= input {
+		return nil, fmt.Errorf("missing input %d", input)
+	}
+	init := i.graph.Initializer(n.Inputs[input])
+	if init == nil {
+		return nil, fmt.Errorf("input %q must be an initializer", n.Inputs[input])
+	}
+	shape := []int{}
+	for _, d := range init.Dims {
+		shape = append(shape, int(d))
+	}
+	data := init.Data
+	switch d := data.(type) {
+	case []float32:
+		if i.dtype == t.Float64 {
+			converted := make([]float64, len(d))
+			for j, v := range d {
+				converted[j] = float64(v)
+			}
+			data = converted
+		}
+	case []float64:
+		if i.dtype == t.Float32 {
+			converted := make([]float32, len(d))
+			for j, v := range d {
+				converted[j] = float32(v)
+			}
+			data = converted
+		}
+	}
+	if len(shape) == 0 {
+		shape = []int{1}
+	}
+	return t.New(t.WithShape(shape...), t.WithBacking(data)), nil
+}
+
+// activation returns the activation function of an activation node.
+func activation(n *Node) (layer.ActivationFn, error) {
+	switch n.OpType {
+	case "Relu":
+		return layer.NewReLU(), nil
+	case "LeakyRelu":
+		return layer.NewLeakyReLU(float64(attrFloat(n, "alpha", 0.01))), nil
+	case "Sigmoid":
+		return layer.NewSigmoid(), nil
+	case "Tanh":
+		return layer.NewTanh(), nil
+	case "Softmax":
+		axis := attrInt(n, "axis", -1)
+		if axis != -1 && axis != 1 {
+			return nil, fmt.Errorf("softmax on axis %d is not supported", axis)
+		}
+		return layer.NewSoftmax(), nil
+	}
+	return nil, fmt.Errorf("unsupported operator")
+}
+
+// pads returns the padding of a node, which must be the same at the start and end of each dimension.
+func pads(n *Node) ([]int, error) {
+	if a := n.Attr("auto_pad"); a != nil && a.String != "" && a.String != "NOTSET" && a.String != "VALID" {
+		return nil, fmt.Errorf("auto pad %q is not supported", a.String)
+	}
+	p := attrInts(n, "pads", 0, 0, 0, 0)
+	if len(p) != 4 || p[0] != p[2] || p[1] != p[3] {
+		return nil, fmt.Errorf("only symmetric 2D padding is supported, got %v", p)
+	}
+	return p[:2], nil
+}
+
+func attrInt(n *Node, name string, def int64) int64 {
+	if a := n.Attr(name); a != nil {
+		return a.Int
+	}
+	return def
+}
+
+func attrFloat(n *Node, name string, def float32) float32 {
+	if a := n.Attr(name); a != nil {
+		return a.Float
+	}
+	return def
+}
+
+func attrInts(n *Node, name string, def ...int) []int {
+	a := n.Attr(name)
+	if a == nil {
+		return def
+	}
+	ret := []int{}
+	for _, v := range a.Ints {
+		ret = append(ret, int(v))
+	}
+	return ret
+}
+
+func toFloat64(data interface{}) float64 {
+	switch d := data.(type) {
+	case []float32:
+		return float64(d[0])
+	case []float64:
+		return d[0]
+	}
+	return 0
+}
+
+// transpose returns a transposed copy of a matrix.
+func transpose(m *t.Dense) (*t.Dense, error) {
+	ret := m.Clone().(*t.Dense)
+	err := ret.T()
+	if err != nil {
+		return nil, err
+	}
+	err = ret.Transpose()
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -0,0 +1,119 @@
+package onnx_test
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/aunum/goro/pkg/v1/layer"
+	"github.com/aunum/goro/pkg/v1/model"
+	. "github.com/aunum/goro/pkg/v1/onnx"
+
+	"github.com/stretchr/testify/require"
+	g "gorgonia.org/gorgonia"
+	"gorgonia.org/tensor"
+)
+
+func TestImport(t *testing.T) {
+	m, err := model.NewSequential("onnx")
+	require.NoError(t, err)
+	err = m.AddLayers(
+		layer.Conv2D{Output: 2, Height: 3, Width: 3, Name: "conv", Bias: true, BiasInit: g.GlorotN(1)},
+		layer.MaxPooling2D{},
+		layer.Flatten{},
+		layer.FC{Output: 6, Activation: layer.Sigmoid},
+		layer.Reshape{To: []int{2, 3}},
+		layer.Flatten{},
+		layer.FC{Output: 3, Activation: layer.Softmax},
+	)
+	require.NoError(t, err)
+	err = m.Compile(model.NewInput("x", []int{1, 1, 6, 6}), model.NewInput("y", []int{1, 3}), model.WithoutTracker())
+	require.NoError(t, err)
+
+	exported, err := Export(m)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, exported.Write(&buf))
+	decoded, err := Read(&buf)
+	require.NoError(t, err)
+	imported, err := Import(decoded, model.WithoutTracker())
+	require.NoError(t, err)
+	require.Len(t, imported.Learnables(), len(m.Learnables()))
+
+	for i := 0; i < 3; i++ {
+		x := make([]float32, 36)
+		for j := range x {
+			x[j] = float32(math.Cos(float64(i*36 + j)))
+		}
+		expected, err := m.Predict(tensor.New(tensor.WithShape(1, 1, 6, 6), tensor.WithBacking(x)))
+		require.NoError(t, err)
+		actual, err := imported.Predict(tensor.New(tensor.WithShape(1, 1, 6, 6), tensor.WithBacking(x)))
+		require.NoError(t, err)
+		require.InDeltaSlice(t, expected.Data(), actual.Data(), 1e-6)
+	}
+
+	// a graph as written by other tools, with a matmul and add, and transposed gemm weights.
+	graph := &Graph{
+		Name:   "mlp",
+		Inputs: []*ValueInfo{{Name: "input", ElemType: Float, Shape: []int64{-1, 2}}},
+		Initializers: []*Tensor{
+			{Name: "w1", Dims: []int64{2, 2}, DataType: Float, Data: []float32{1, 2, 3, 4}},
+			{Name: "b1", Dims: []int64{2}, DataType: Float, Data: []float32{-10, 1}},
+			{Name: "w2", Dims: []int64{1, 2}, DataType: Float, Data: []float32{0.5, 2}},
+		},
+		Nodes: []*Node{
+			{Name: "matmul", OpType: "MatMul", Inputs: []string{"input", "w1"}, Outputs: []string{"h"}},
+			{Name: "add", OpType: "Add", Inputs: []string{"h", "b1"}, Outputs: []string{"hb"}},
+			{Name: "relu", OpType: "Relu", Inputs: []string{"hb"}, Outputs: []string{"a"}},
+			{Name: "gemm", OpType: "Gemm", Inputs: []string{"a", "w2"}, Outputs: []string{"out"}, Attributes: []*Attribute{
+				{Name: "transB", Type: AttributeInt, Int: 1},
+			}},
+		},
+		Outputs: []*ValueInfo{{Name: "out", ElemType: Float, Shape: []int64{-1, 1}}},
+	}
+	mlp, err := Import(&Model{Graph: graph}, model.WithoutTracker())
+	require.NoError(t, err)
+	prediction, err := mlp.Predict(tensor.New(tensor.WithShape(1, 2), tensor.WithBacking([]float32{1, 1})))
+	require.NoError(t, err)
+	// relu([1, 1] x [[1, 2], [3, 4]] + [-10, 1]) = [0, 7], [0, 7] x [0.5, 2] = 14
+	require.InDelta(t, 14, prediction.Data().([]float32)[0], 1e-6)
+
+	// imported models can be trained further.
+	err = mlp.Fit(tensor.New(tensor.WithShape(1, 2), tensor.WithBacking([]float32{1, 1})), tensor.New(tensor.WithShape(1, 1), tensor.WithBacking([]float32{10})))
+	require.NoError(t, err)
+
+	// a dropout with a zero ratio is skipped, so predictions are deterministic.
+	dropout := &Graph{
+		Name:   "dropout",
+		Inputs: []*ValueInfo{{Name: "input", ElemType: Float, Shape: []int64{-1, 2}}},
+		Initializers: []*Tensor{
+			{Name: "w", Dims: []int64{2, 2}, DataType: Float, Data: []float32{1, 2, 3, 4}},
+			{Name: "ratio", DataType: Float, Data: []float32{0}},
+		},
+		Nodes: []*Node{
+			{Name: "matmul", OpType: "MatMul", Inputs: []string{"input", "w"}, Outputs: []string{"h"}},
+			{Name: "dropout", OpType: "Dropout", Inputs: []string{"h", "ratio"}, Outputs: []string{"d"}},
+			{Name: "relu", OpType: "Relu", Inputs: []string{"d"}, Outputs: []string{"out"}},
+		},
+		Outputs: []*ValueInfo{{Name: "out", ElemType: Float, Shape: []int64{-1, 2}}},
+	}
+	deterministic, err := Import(&Model{Graph: dropout}, model.WithoutTracker())
+	require.NoError(t, err)
+	require.Len(t, deterministic.Chain.Layers, 1)
+	for i := 0; i < 5; i++ {
+		prediction, err = deterministic.Predict(tensor.New(tensor.WithShape(1, 2), tensor.WithBacking([]float32{1, -1})))
+		require.NoError(t, err)
+		// relu([1, -1] x [[1, 2], [3, 4]]) = [0, 0]
+		require.Equal(t, []float32{0, 0}, prediction.Data())
+		prediction, err = deterministic.Predict(tensor.New(tensor.WithShape(1, 2), tensor.WithBacking([]float32{1, 1})))
+		require.NoError(t, err)
+		require.Equal(t, []float32{4, 6}, prediction.Data())
+	}
+
+	graph.Nodes = append(graph.Nodes,
+		&Node{Name: "norm", OpType: "BatchNormalization", Inputs: []string{"out"}, Outputs: []string{"norm"}},
+		&Node{Name: "lstm", OpType: "LSTM", Inputs: []string{"norm"}, Outputs: []string{"lstm"}},
+	)
+	_, err = Import(&Model{Graph: graph})
+	require.EqualError(t, err, "unsupported onnx operators: BatchNormalization, LSTM")
+}
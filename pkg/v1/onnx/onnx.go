@@ -0,0 +1,637 @@
+// Package onnx converts sequential models to and from the ONNX format.
+// See https://github.com/onnx/onnx/blob/master/docs/IR.md
+package onnx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+
+	"github.com/aunum/goro/pkg/v1/common/proto"
+
+	t "gorgonia.org/tensor"
+)
+
+const (
+	// IRVersion is the version of the ONNX IR models are written with.
+	IRVersion = 7
+
+	// OpsetVersion is the version of the default operator set models are written with.
+	OpsetVersion = 13
+)
+
+// DataType is the type of the elements of a tensor.
+type DataType int32
+
+// Data types of tensor elements.
+const (
+	Undefined DataType = 0
+	Float     DataType = 1
+	Int32     DataType = 6
+	Int64     DataType = 7
+	Double    DataType = 11
+)
+
+// AttributeType is the type of an attribute.
+type AttributeType int32
+
+// Types of attributes.
+const (
+	AttributeFloat   AttributeType = 1
+	AttributeInt     AttributeType = 2
+	AttributeString  AttributeType = 3
+	AttributeFloats  AttributeType = 6
+	AttributeInts    AttributeType = 7
+	AttributeStrings AttributeType = 8
+)
+
+// Model is an ONNX model.
+type Model struct {
+	// IRVersion of the model.
+	IRVersion int64
+
+	// OpsetVersion of the default operator set.
+	OpsetVersion int64
+
+	// ProducerName is the name of the tool that produced the model.
+	ProducerName string
+
+	// Graph of the model.
+	Graph *Graph
+}
+
+// Graph is a graph of nodes.
+type Graph struct {
+	// Name of the graph.
+	Name string
+
+	// Nodes of the graph in topological order.
+	Nodes []*Node
+
+	// Initializers are the constant tensors of the graph e.g. weights.
+	Initializers []*Tensor
+
+	// Inputs to the graph.
+	Inputs []*ValueInfo
+
+	// Outputs of the graph.
+	Outputs []*ValueInfo
+}
+
+// Node is an operation in a graph.
+type Node struct {
+	// Name of the node.
+	Name string
+
+	// OpType is the operator e.g. Gemm.
+	OpType string
+
+	// Inputs are the names of the values input to the node.
+	Inputs []string
+
+	// Outputs are the names of the values output by the node.
+	Outputs []string
+
+	// Attributes of the operator.
+	Attributes []*Attribute
+}
+
+// Attribute is a named attribute of a node.
+type Attribute struct {
+	Name    string
+	Type    AttributeType
+	Float   float32
+	Int     int64
+	String  string
+	Floats  []float32
+	Ints    []int64
+	Strings []string
+}
+
+// Tensor is a constant tensor.
+type Tensor struct {
+	// Name of the tensor.
+	Name string
+
+	// Dims are the shape of the tensor.
+	Dims []int64
+
+	// DataType of the elements.
+	DataType DataType
+
+	// Data are the elements, which is a []float32, []float64, []int32 or []int64 for the data type.
+	Data interface{}
+}
+
+// ValueInfo describes a graph input or output.
+type ValueInfo struct {
+	// Name of the value.
+	Name string
+
+	// ElemType is the data type of the elements.
+	ElemType DataType
+
+	// Shape of the value, a dimension of -1 is a dynamic dimension e.g. the batch.
+	Shape []int64
+}
+
+// Attr returns the attribute by name, nil if the node does not have it.
+func (n *Node) Attr(name string) *Attribute {
+	for _, a := range n.Attributes {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// Initializer returns the initializer by name, nil if the graph does not have it.
+func (g *Graph) Initializer(name string) *Tensor {
+	for _, i := range g.Initializers {
+		if i.Name == name {
+			return i
+		}
+	}
+	return nil
+}
+
+// FromDtype returns the data type for a tensor dtype.
+func FromDtype(dtype t.Dtype) (DataType, error) {
+	switch dtype {
+	case t.Float32:
+		return Float, nil
+	case t.Float64:
+		return Double, nil
+	case t.Int32:
+		return Int32, nil
+	case t.Int64:
+		return Int64, nil
+	}
+	return Undefined, fmt.Errorf("unsupported dtype %v", dtype)
+}
+
+// Dtype returns the tensor dtype for the data type.
+func (d DataType) Dtype() (t.Dtype, error) {
+	switch d {
+	case Float:
+		return t.Float32, nil
+	case Double:
+		return t.Float64, nil
+	case Int32:
+		return t.Int32, nil
+	case Int64:
+		return t.Int64, nil
+	}
+	return t.Dtype{}, fmt.Errorf("unsupported onnx data type %d", d)
+}
+
+// Marshal the model as protobuf.
+func (m *Model) Marshal() ([]byte, error) {
+	if m.Graph == nil {
+		return nil, fmt.Errorf("model has no graph")
+	}
+	graph, err := m.Graph.marshal()
+	if err != nil {
+		return nil, err
+	}
+	opset := proto.Message{}.String(1, "").Int64(2, m.OpsetVersion)
+	return proto.Message{}.
+		Int64(1, m.IRVersion).
+		String(2, m.ProducerName).
+		Bytes(7, graph).
+		Bytes(8, opset), nil
+}
+
+// Write the model as protobuf to the writer.
+func (m *Model) Write(w io.Writer) error {
+	b, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// WriteFile writes the model to the file at the path, conventionally with a .onnx extension.
+func (m *Model) WriteFile(path string) error {
+	b, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+func (g *Graph) marshal() (proto.Message, error) {
+	msg := proto.Message{}
+	for _, n := range g.Nodes {
+		msg = msg.Bytes(1, n.marshal())
+	}
+	msg = msg.String(2, g.Name)
+	for _, i := range g.Initializers {
+		b, err := i.marshal()
+		if err != nil {
+			return nil, err
+		}
+		msg = msg.Bytes(5, b)
+	}
+	for _, v := range g.Inputs {
+		msg = msg.Bytes(11, v.marshal())
+	}
+	for _, v := range g.Outputs {
+		msg = msg.Bytes(12, v.marshal())
+	}
+	return msg, nil
+}
+
+func (n *Node) marshal() proto.Message {
+	msg := proto.Message{}
+	for _, i := range n.Inputs {
+		msg = msg.String(1, i)
+	}
+	for _, o := range n.Outputs {
+		msg = msg.String(2, o)
+	}
+	msg = msg.String(3, n.Name).String(4, n.OpType)
+	for _, a := range n.Attributes {
+		msg = msg.Bytes(5, a.marshal())
+	}
+	return msg
+}
+
+func (a *Attribute) marshal() proto.Message {
+	msg := proto.Message{}.String(1, a.Name)
+	switch a.Type {
+	case AttributeFloat:
+		msg = msg.Float(2, a.Float)
+	case AttributeInt:
+		msg = msg.Int64(3, a.Int)
+	case AttributeString:
+		msg = msg.String(4, a.String)
+	case AttributeFloats:
+		msg = msg.PackedFloats(7, a.Floats)
+	case AttributeInts:
+		msg = msg.PackedInt64s(8, a.Ints)
+	case AttributeStrings:
+		for _, s := range a.Strings {
+			msg = msg.String(9, s)
+		}
+	}
+	return msg.Varint(20, uint64(a.Type))
+}
+
+func (t *Tensor) marshal() (proto.Message, error) {
+	msg := proto.Message{}.PackedInt64s(1, t.Dims).Varint(2, uint64(t.DataType)).String(8, t.Name)
+	var raw bytes.Buffer
+	switch t.Data.(type) {
+	case []float32, []float64, []int32, []int64:
+		err := binary.Write(&raw, binary.LittleEndian, t.Data)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported data %T for tensor %q", t.Data, t.Name)
+	}
+	return msg.Bytes(9, raw.Bytes()), nil
+}
+
+func (v *ValueInfo) marshal() proto.Message {
+	shape := proto.Message{}
+	for _, d := range v.Shape {
+		dim := proto.Message{}
+		if d < 0 {
+			dim = dim.String(2, "batch")
+		} else {
+			dim = dim.Int64(1, d)
+		}
+		shape = shape.Bytes(1, dim)
+	}
+	tensorType := proto.Message{}.Varint(1, uint64(v.ElemType)).Bytes(2, shape)
+	return proto.Message{}.String(1, v.Name).Bytes(2, proto.Message{}.Bytes(1, tensorType))
+}
+
+// Unmarshal a model from protobuf.
+func Unmarshal(b []byte) (*Model, error) {
+	fs, err := proto.Fields(b)
+	if err != nil {
+		return nil, err
+	}
+	m := &Model{}
+	for _, f := range fs {
+		switch f.Number {
+		case 1:
+			m.IRVersion = f.Int64()
+		case 2:
+			m.ProducerName = f.String()
+		case 7:
+			m.Graph, err = unmarshalGraph(f.Bytes)
+			if err != nil {
+				return nil, err
+			}
+		case 8:
+			ofs, err := proto.Fields(f.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			domain, version := "", int64(0)
+			for _, of := range ofs {
+				switch of.Number {
+				case 1:
+					domain = of.String()
+				case 2:
+					version = of.Int64()
+				}
+			}
+			if domain == "" || domain == "ai.onnx" {
+				m.OpsetVersion = version
+			}
+		}
+	}
+	if m.Graph == nil {
+		return nil, fmt.Errorf("model has no graph")
+	}
+	return m, nil
+}
+
+// Read a model from protobuf.
+func Read(r io.Reader) (*Model, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Unmarshal(b)
+}
+
+// ReadFile reads a model from the file at the path.
+func ReadFile(path string) (*Model, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+func unmarshalGraph(b []byte) (*Graph, error) {
+	fs, err := proto.Fields(b)
+	if err != nil {
+		return nil, err
+	}
+	g := &Graph{}
+	for _, f := range fs {
+		switch f.Number {
+		case 1:
+			n, err := unmarshalNode(f.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			g.Nodes = append(g.Nodes, n)
+		case 2:
+			g.Name = f.String()
+		case 5:
+			t, err := unmarshalTensor(f.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			g.Initializers = append(g.Initializers, t)
+		case 11, 12:
+			v, err := unmarshalValueInfo(f.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			if f.Number == 11 {
+				g.Inputs = append(g.Inputs, v)
+			} else {
+				g.Outputs = append(g.Outputs, v)
+			}
+		}
+	}
+	return g, nil
+}
+
+func unmarshalNode(b []byte) (*Node, error) {
+	fs, err := proto.Fields(b)
+	if err != nil {
+		return nil, err
+	}
+	n := &Node{}
+	for _, f := range fs {
+		switch f.Number {
+		case 1:
+			n.Inputs = append(n.Inputs, f.String())
+		case 2:
+			n.Outputs = append(n.Outputs, f.String())
+		case 3:
+			n.Name = f.String()
+		case 4:
+			n.OpType = f.String()
+		case 5:
+			a, err := unmarshalAttribute(f.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			n.Attributes = append(n.Attributes, a)
+		}
+	}
+	return n, nil
+}
+
+func unmarshalAttribute(b []byte) (*Attribute, error) {
+	fs, err := proto.Fields(b)
+	if err != nil {
+		return nil, err
+	}
+	a := &Attribute{}
+	for _, f := range fs {
+		switch f.Number {
+		case 1:
+			a.Name = f.String()
+		case 2:
+			a.Float = f.Float()
+		case 3:
+			a.Int = f.Int64()
+		case 4:
+			a.String = f.String()
+		case 7:
+			a.Floats = append(a.Floats, f.Floats()...)
+		case 8:
+			ints, err := f.Int64s()
+			if err != nil {
+				return nil, err
+			}
+			a.Ints = append(a.Ints, ints...)
+		case 9:
+			a.Strings = append(a.Strings, f.String())
+		case 20:
+			a.Type = AttributeType(f.Value)
+		}
+	}
+	return a, nil
+}
+
+func unmarshalTensor(b []byte) (*Tensor, error) {
+	fs, err := proto.Fields(b)
+	if err != nil {
+		return nil, err
+	}
+	t := &Tensor{}
+	var raw []byte
+	var floats []float32
+	var doubles []float64
+	var ints []int64
+	for _, f := range fs {
+		switch f.Number {
+		case 1:
+			dims, err := f.Int64s()
+			if err != nil {
+				return nil, err
+			}
+			t.Dims = append(t.Dims, dims...)
+		case 2:
+			t.DataType = DataType(f.Value)
+		case 4:
+			floats = append(floats, f.Floats()...)
+		case 5, 7:
+			vs, err := f.Int64s()
+			if err != nil {
+				return nil, err
+			}
+			ints = append(ints, vs...)
+		case 8:
+			t.Name = f.String()
+		case 9:
+			raw = f.Bytes
+		case 10:
+			doubles = append(doubles, f.Doubles()...)
+		}
+	}
+	size := int64(1)
+	for _, d := range t.Dims {
+		size *= d
+	}
+	switch t.DataType {
+	case Float:
+		if raw != nil {
+			floats = make([]float32, len(raw)/4)
+			for i := range floats {
+				floats[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:]))
+			}
+		}
+		t.Data = floats
+	case Double:
+		if raw != nil {
+			doubles = make([]float64, len(raw)/8)
+			for i := range doubles {
+				doubles[i] = math.Float64frombits(binary.LittleEndian.Uint64(raw[8*i:]))
+			}
+		}
+		t.Data = doubles
+	case Int32:
+		vs := make([]int32, len(ints))
+		if raw != nil {
+			vs = make([]int32, len(raw)/4)
+			for i := range vs {
+				vs[i] = int32(binary.LittleEndian.Uint32(raw[4*i:]))
+			}
+		} else {
+			for i, v := range ints {
+				vs[i] = int32(v)
+			}
+		}
+		t.Data = vs
+	case Int64:
+		if raw != nil {
+			ints = make([]int64, len(raw)/8)
+			for i := range ints {
+				ints[i] = int64(binary.LittleEndian.Uint64(raw[8*i:]))
+			}
+		}
+		t.Data = ints
+	default:
+		return nil, fmt.Errorf("unsupported data type %d for tensor %q", t.DataType, t.Name)
+	}
+	if int64(dataLen(t.Data)) != size {
+		return nil, fmt.Errorf("tensor %q has %d elements for dims %v", t.Name, dataLen(t.Data), t.Dims)
+	}
+	return t, nil
+}
+
+func dataLen(data interface{}) int {
+	switch d := data.(type) {
+	case []float32:
+		return len(d)
+	case []float64:
+		return len(d)
+	case []int32:
+		return len(d)
+	case []int64:
+		return len(d)
+	}
+	return 0
+}
+
+func unmarshalValueInfo(b []byte) (*ValueInfo, error) {
+	fs, err := proto.Fields(b)
+	if err != nil {
+		return nil, err
+	}
+	v := &ValueInfo{}
+	for _, f := range fs {
+		switch f.Number {
+		case 1:
+			v.Name = f.String()
+		case 2:
+			// TypeProto.tensor_type
+			tfs, err := proto.Fields(f.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			for _, tf := range tfs {
+				if tf.Number != 1 {
+					continue
+				}
+				err = v.unmarshalTensorType(tf.Bytes)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return v, nil
+}
+
+func (v *ValueInfo) unmarshalTensorType(b []byte) error {
+	fs, err := proto.Fields(b)
+	if err != nil {
+		return err
+	}
+	for _, f := range fs {
+		switch f.Number {
+		case 1:
+			v.ElemType = DataType(f.Value)
+		case 2:
+			dims, err := proto.Fields(f.Bytes)
+			if err != nil {
+				return err
+			}
+			v.Shape = []int64{}
+			for _, dim := range dims {
+				dfs, err := proto.Fields(dim.Bytes)
+				if err != nil {
+					return err
+				}
+				d := int64(-1)
+				for _, df := range dfs {
+					if df.Number == 1 {
+						d = df.Int64()
+					}
+				}
+				v.Shape = append(v.Shape, d)
+			}
+		}
+	}
+	return nil
+}
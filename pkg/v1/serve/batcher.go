@@ -0,0 +1,180 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aunum/goro/pkg/v1/model"
+
+	t "gorgonia.org/tensor"
+)
+
+// ErrClosed is returned when a prediction is requested from a closed server.
+var ErrClosed = errors.New("server is closed")
+
+// request is a single instance to predict.
+type request struct {
+	x    []float64
+	resp chan response
+}
+
+// response is the prediction for a single instance, the prediction is a []float32 or []float64
+// depending on the data type of the model.
+type response struct {
+	prediction interface{}
+	err        error
+}
+
+// batcher coalesces concurrent requests into batch predictions. A batch is predicted once it
+// reaches the max batch size or the oldest request in it has waited for the max latency, with at
+// most max concurrency batches predicted at once.
+type batcher struct {
+	model      *model.Sequential
+	maxBatch   int
+	maxLatency time.Duration
+
+	requests chan *request
+	slots    chan struct{}
+	done     chan struct{}
+	stopped  chan struct{}
+	inflight sync.WaitGroup
+	once     sync.Once
+}
+
+func newBatcher(m *model.Sequential, maxBatch int, maxLatency time.Duration, maxConcurrency int) *batcher {
+	b := &batcher{
+		model:      m,
+		maxBatch:   maxBatch,
+		maxLatency: maxLatency,
+		requests:   make(chan *request),
+		slots:      make(chan struct{}, maxConcurrency),
+		done:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// predict an instance, blocking until it has been predicted as part of a batch.
+func (b *batcher) predict(ctx context.Context, x []float64) (interface{}, error) {
+	r := &request{x: x, resp: make(chan response, 1)}
+	select {
+	case b.requests <- r:
+	case <-b.done:
+		return nil, ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case resp := <-r.resp:
+		return resp.prediction, resp.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (b *batcher) run() {
+	defer close(b.stopped)
+	for {
+		var batch []*request
+		select {
+		case <-b.done:
+			return
+		case r := <-b.requests:
+			batch = append(batch, r)
+		}
+		timer := time.NewTimer(b.maxLatency)
+	collect:
+		for len(batch) < b.maxBatch {
+			select {
+			case r := <-b.requests:
+				batch = append(batch, r)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+		// wait for a slot so batches queue here rather than as goroutines.
+		b.slots <- struct{}{}
+		b.inflight.Add(1)
+		go b.predictBatch(batch)
+	}
+}
+
+// predictBatch predicts the batch and responds to each request with its prediction.
+func (b *batcher) predictBatch(batch []*request) {
+	defer b.inflight.Done()
+	defer func() { <-b.slots }()
+	predictions, err := b.predictValues(batch)
+	for i, r := range batch {
+		if err != nil {
+			r.resp <- response{err: err}
+			continue
+		}
+		r.resp <- response{prediction: predictions[i]}
+	}
+}
+
+func (b *batcher) predictValues(batch []*request) ([]interface{}, error) {
+	n := len(batch)
+	shape := append(t.Shape{n}, instanceShape(b.model.FwdInput().Shape())...)
+	var backing interface{}
+	switch b.model.DType() {
+	case t.Float64:
+		data := make([]float64, 0, shape.TotalSize())
+		for _, r := range batch {
+			data = append(data, r.x...)
+		}
+		backing = data
+	default:
+		data := make([]float32, 0, shape.TotalSize())
+		for _, r := range batch {
+			for _, v := range r.x {
+				data = append(data, float32(v))
+			}
+		}
+		backing = data
+	}
+	prediction, err := b.model.PredictBatch(t.New(t.WithShape(shape...), t.WithBacking(backing)))
+	if err != nil {
+		return nil, err
+	}
+
+	// a batch of one may have had its batch dimension removed, so rows are split by size.
+	predictions := make([]interface{}, n)
+	switch data := prediction.Data().(type) {
+	case []float32:
+		size := len(data) / n
+		for i := range predictions {
+			predictions[i] = data[i*size : (i+1)*size]
+		}
+	case []float64:
+		size := len(data) / n
+		for i := range predictions {
+			predictions[i] = data[i*size : (i+1)*size]
+		}
+	default:
+		return nil, fmt.Errorf("unsupported prediction data type %T", data)
+	}
+	return predictions, nil
+}
+
+// close the batcher, waiting for any in flight batches to complete.
+func (b *batcher) close() {
+	b.once.Do(func() {
+		close(b.done)
+		<-b.stopped
+		b.inflight.Wait()
+	})
+}
+
+// instanceShape is the shape of a single instance of an input or output shape.
+func instanceShape(shape t.Shape) t.Shape {
+	if len(shape) > 1 && shape[0] == 1 {
+		return shape[1:].Clone()
+	}
+	return shape.Clone()
+}
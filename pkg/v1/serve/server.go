@@ -0,0 +1,503 @@
+// Package serve provides an HTTP inference server for models.
+package serve
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aunum/goro/pkg/v1/model"
+	"github.com/aunum/log"
+
+	t "gorgonia.org/tensor"
+)
+
+// Server serves predictions from a model over HTTP.
+//
+// Endpoints:
+//
+//	GET  /healthz           liveness check
+//	GET  /readyz            readiness check, fails once the server is closing
+//	GET  /v1/metadata       model inputs and output shape
+//	POST /v1/predict        JSON predictions {"instances": [...]}
+//	POST /v1/predict/raw    little endian binary tensor predictions
+//
+// Instances from concurrent requests are coalesced into batch predictions.
+type Server struct {
+	model          *model.Sequential
+	maxBatch       int
+	maxLatency     time.Duration
+	maxConcurrency int
+	maxBodyBytes   int64
+
+	batcher *batcher
+	mux     *http.ServeMux
+	httpSrv *http.Server
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// Opt is a server option.
+type Opt func(*Server)
+
+// WithMaxBatchSize sets the largest batch that instances are coalesced into.
+// Defaults to the max batch size of the model.
+func WithMaxBatchSize(size int) func(*Server) {
+	return func(s *Server) {
+		s.maxBatch = size
+	}
+}
+
+// WithMaxLatency sets the longest an instance will wait for a batch to fill before it is predicted.
+// Defaults to 5ms.
+func WithMaxLatency(latency time.Duration) func(*Server) {
+	return func(s *Server) {
+		s.maxLatency = latency
+	}
+}
+
+// WithMaxConcurrency sets the most batches that are predicted at once, further batches wait for
+// one to complete.
+// Defaults to GOMAXPROCS.
+func WithMaxConcurrency(max int) func(*Server) {
+	return func(s *Server) {
+		s.maxConcurrency = max
+	}
+}
+
+// WithMaxBodyBytes sets the largest request body accepted, larger requests fail with status 413.
+// Defaults to 32MB.
+func WithMaxBodyBytes(max int64) func(*Server) {
+	return func(s *Server) {
+		s.maxBodyBytes = max
+	}
+}
+
+// NewServer returns a new server for the compiled model. Only the forward input is served so models
+// with a mask input are not supported.
+func NewServer(m *model.Sequential, opts ...Opt) (*Server, error) {
+	if m == nil || m.OutputShape() == nil {
+		return nil, &model.ConfigError{Name: "server", Reason: "model must be compiled"}
+	}
+	if mask := m.MaskInput(); mask != nil {
+		return nil, &model.ConfigError{Name: "server", Reason: fmt.Sprintf("models with a mask input are not supported, found mask input %q", mask.Name())}
+	}
+	s := &Server{
+		model:          m,
+		maxBatch:       m.MaxBatchSize(),
+		maxLatency:     5 * time.Millisecond,
+		maxConcurrency: runtime.GOMAXPROCS(0),
+		maxBodyBytes:   32 << 20,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.maxBatch < 1 || s.maxBatch > m.MaxBatchSize() {
+		return nil, &model.ConfigError{Name: "server max batch size", Reason: fmt.Sprintf("must be between 1 and the model max batch size %d, got %d", m.MaxBatchSize(), s.maxBatch)}
+	}
+	if s.maxLatency < 0 {
+		return nil, &model.ConfigError{Name: "server max latency", Reason: fmt.Sprintf("must not be negative, got %v", s.maxLatency)}
+	}
+	if s.maxConcurrency < 1 {
+		return nil, &model.ConfigError{Name: "server max concurrency", Reason: fmt.Sprintf("must be at least 1, got %d", s.maxConcurrency)}
+	}
+	if s.maxBodyBytes < 1 {
+		return nil, &model.ConfigError{Name: "server max body bytes", Reason: fmt.Sprintf("must be at least 1, got %d", s.maxBodyBytes)}
+	}
+	s.batcher = newBatcher(m, s.maxBatch, s.maxLatency, s.maxConcurrency)
+
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("/healthz", s.handleHealth)
+	s.mux.HandleFunc("/readyz", s.handleReady)
+	s.mux.HandleFunc("/v1/metadata", s.handleMetadata)
+	s.mux.HandleFunc("/v1/predict", s.handlePredict)
+	s.mux.HandleFunc("/v1/predict/raw", s.handlePredictRaw)
+	return s, nil
+}
+
+// Handler returns the HTTP handler for the server.
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// ListenAndServe listens on the address and serves requests until the server is closed.
+func (s *Server) ListenAndServe(addr string) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	s.httpSrv = &http.Server{Addr: addr, Handler: s.mux}
+	srv := s.httpSrv
+	s.mu.Unlock()
+	log.Infof("serving model %q on %s", s.model.Name(), addr)
+	err := srv.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// Close the server, waiting for in flight requests to complete or the context to be done.
+func (s *Server) Close(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	srv := s.httpSrv
+	s.mu.Unlock()
+	var err error
+	if srv != nil {
+		err = srv.Shutdown(ctx)
+	}
+	s.batcher.close()
+	return err
+}
+
+// InputMetadata describes an input of the model.
+type InputMetadata struct {
+	// Name of the input.
+	Name string `json:"name"`
+
+	// Shape of the input.
+	Shape []int `json:"shape"`
+
+	// DType is the data type of the input.
+	DType string `json:"dtype"`
+
+	// Fwd indicates the input is sent through the layers and is the input to predict.
+	Fwd bool `json:"fwd"`
+}
+
+// Metadata describes the model being served.
+type Metadata struct {
+	// Name of the model.
+	Name string `json:"name"`
+
+	// DType is the data type of the model.
+	DType string `json:"dtype"`
+
+	// Inputs of the model.
+	Inputs []InputMetadata `json:"inputs"`
+
+	// InstanceShape is the shape of a single instance to predict.
+	InstanceShape []int `json:"instanceShape"`
+
+	// OutputShape is the shape of a single prediction.
+	OutputShape []int `json:"outputShape"`
+
+	// MaxBatchSize is the largest batch instances are coalesced into.
+	MaxBatchSize int `json:"maxBatchSize"`
+}
+
+// Metadata returns the metadata of the model being served.
+func (s *Server) Metadata() Metadata {
+	md := Metadata{
+		Name:          s.model.Name(),
+		DType:         s.model.DType().String(),
+		InstanceShape: instanceShape(s.model.FwdInput().Shape()),
+		OutputShape:   instanceShape(s.model.OutputShape()),
+		MaxBatchSize:  s.maxBatch,
+	}
+	for _, input := range s.model.X().Inputs() {
+		md.Inputs = append(md.Inputs, InputMetadata{
+			Name:  input.Name(),
+			Shape: input.Shape(),
+			DType: input.DType().String(),
+			Fwd:   input.Name() == s.model.FwdInput().Name(),
+		})
+	}
+	return md
+}
+
+// PredictRequest is a JSON prediction request.
+type PredictRequest struct {
+	// Instances to predict, each may be a nested or flat array of the instance shape.
+	Instances []interface{} `json:"instances"`
+}
+
+// PredictResponse is a JSON prediction response.
+type PredictResponse struct {
+	// Predictions for each instance, flattened.
+	Predictions [][]float64 `json:"predictions"`
+
+	// Shape of each prediction.
+	Shape []int `json:"shape"`
+}
+
+// errorResponse is returned on any failed request.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "closing"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+func (s *Server) handleMetadata(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.Metadata())
+}
+
+func (s *Server) handlePredict(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	req := PredictRequest{}
+	err := json.NewDecoder(s.limitBody(r)).Decode(&req)
+	if err != nil {
+		writeError(w, bodyStatusOf(err), fmt.Errorf("could not decode request: %w", err))
+		return
+	}
+	if len(req.Instances) == 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("no instances provided"))
+		return
+	}
+	size := instanceShape(s.model.FwdInput().Shape()).TotalSize()
+	instances := make([][]float64, len(req.Instances))
+	for i, instance := range req.Instances {
+		instances[i], err = flatten(instance, nil)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("instance %d: %w", i, err))
+			return
+		}
+		if len(instances[i]) != size {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("instance %d has %d values but the instance shape %v has %d", i, len(instances[i]), instanceShape(s.model.FwdInput().Shape()), size))
+			return
+		}
+	}
+	predictions, err := s.predictAll(r.Context(), instances)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	resp := PredictResponse{Shape: instanceShape(s.model.OutputShape())}
+	for _, prediction := range predictions {
+		resp.Predictions = append(resp.Predictions, toFloat64s(prediction))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handlePredictRaw predicts a body of one or more instances encoded as little endian values of the
+// model data type. The predictions are returned in the same encoding with their shape, including
+// the batch, in the X-Goro-Shape header.
+func (s *Server) handlePredictRaw(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	body, err := ioutil.ReadAll(s.limitBody(r))
+	if err != nil {
+		writeError(w, bodyStatusOf(err), err)
+		return
+	}
+	dtype := s.model.DType()
+	size := instanceShape(s.model.FwdInput().Shape()).TotalSize()
+	instanceBytes := size * int(dtype.Size())
+	if len(body) == 0 || len(body)%instanceBytes != 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("body of %d bytes is not a whole number of %d byte %v instances", len(body), instanceBytes, dtype))
+		return
+	}
+	n := len(body) / instanceBytes
+	instances := make([][]float64, n)
+	for i := range instances {
+		chunk := bytes.NewReader(body[i*instanceBytes : (i+1)*instanceBytes])
+		instances[i] = make([]float64, size)
+		if dtype == t.Float64 {
+			err = binary.Read(chunk, binary.LittleEndian, instances[i])
+		} else {
+			f32 := make([]float32, size)
+			err = binary.Read(chunk, binary.LittleEndian, f32)
+			for j, v := range f32 {
+				instances[i][j] = float64(v)
+			}
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	predictions, err := s.predictAll(r.Context(), instances)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	buf := &bytes.Buffer{}
+	for _, prediction := range predictions {
+		err = binary.Write(buf, binary.LittleEndian, prediction)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	shape := append([]int{n}, instanceShape(s.model.OutputShape())...)
+	dims := []string{}
+	for _, d := range shape {
+		dims = append(dims, strconv.Itoa(d))
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("X-Goro-Shape", strings.Join(dims, ","))
+	w.Header().Set("X-Goro-DType", dtype.String())
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// predictAll submits the instances to the batcher and waits for all predictions. No more instances
+// are submitted at once than fill every batch the batcher predicts concurrently.
+func (s *Server) predictAll(ctx context.Context, instances [][]float64) ([]interface{}, error) {
+	predictions := make([]interface{}, len(instances))
+	errs := make([]error, len(instances))
+	workers := s.maxBatch * s.maxConcurrency
+	if workers > len(instances) {
+		workers = len(instances)
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				predictions[i], errs[i] = s.batcher.predict(ctx, instances[i])
+			}
+		}()
+	}
+	for i := range instances {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return predictions, nil
+}
+
+// flatten a nested array of numbers decoded from JSON.
+func flatten(v interface{}, into []float64) ([]float64, error) {
+	switch val := v.(type) {
+	case float64:
+		return append(into, val), nil
+	case []interface{}:
+		var err error
+		for _, e := range val {
+			into, err = flatten(e, into)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return into, nil
+	default:
+		return nil, fmt.Errorf("unsupported value %v of type %T, expected numbers or arrays", v, v)
+	}
+}
+
+func toFloat64s(v interface{}) []float64 {
+	switch data := v.(type) {
+	case []float64:
+		return data
+	case []float32:
+		ret := make([]float64, len(data))
+		for i, d := range data {
+			ret[i] = float64(d)
+		}
+		return ret
+	}
+	return nil
+}
+
+// statusOf returns the HTTP status for a prediction error.
+func statusOf(err error) int {
+	var shapeErr *model.ShapeError
+	var dtypeErr *model.DTypeError
+	switch {
+	case errors.Is(err, ErrClosed):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusRequestTimeout
+	case errors.As(err, &shapeErr), errors.As(err, &dtypeErr):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// errBodyTooLarge is returned when reading a request body larger than the max body bytes.
+var errBodyTooLarge = errors.New("request body too large")
+
+// limitedBody is a request body which returns errBodyTooLarge once more than a number of bytes are read.
+type limitedBody struct {
+	body      io.Reader
+	remaining int64
+}
+
+// limitBody limits the body of the request to the max body bytes.
+func (s *Server) limitBody(r *http.Request) io.Reader {
+	return &limitedBody{body: r.Body, remaining: s.maxBodyBytes}
+}
+
+// Read from the body.
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// a body of exactly the limit is told apart from a larger one by reading past it.
+		n, err := l.body.Read(make([]byte, 1))
+		if n > 0 {
+			return 0, errBodyTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.body.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// bodyStatusOf returns the HTTP status for an error reading a request body.
+func bodyStatusOf(err error) int {
+	if errors.Is(err, errBodyTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Errorf("could not write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
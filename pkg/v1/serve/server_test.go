@@ -0,0 +1,198 @@
+package serve_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aunum/goro/pkg/v1/layer"
+	m "github.com/aunum/goro/pkg/v1/model"
+	. "github.com/aunum/goro/pkg/v1/serve"
+
+	"github.com/stretchr/testify/require"
+	"gorgonia.org/tensor"
+)
+
+func TestServer(t *testing.T) {
+	model, err := m.NewSequential("serve")
+	require.NoError(t, err)
+	err = model.AddLayers(
+		layer.FC{Output: 4, Activation: layer.Sigmoid},
+		layer.FC{Output: 2, Activation: layer.Linear},
+	)
+	require.NoError(t, err)
+	err = model.Compile(m.NewInput("x", []int{1, 3}), m.NewInput("y", []int{1, 2}),
+		m.WithBatchSize(8),
+		m.WithoutTracker(),
+	)
+	require.NoError(t, err)
+
+	// save and load the model as it would be served.
+	buf := &bytes.Buffer{}
+	err = model.Save(buf)
+	require.NoError(t, err)
+	model, err = m.Load(buf, m.WithoutTracker())
+	require.NoError(t, err)
+
+	_, err = NewServer(model, WithMaxConcurrency(0))
+	require.Error(t, err)
+	server, err := NewServer(model, WithMaxLatency(20*time.Millisecond), WithMaxConcurrency(2), WithMaxBodyBytes(1024))
+	require.NoError(t, err)
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/healthz")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	resp, err = http.Get(ts.URL + "/v1/metadata")
+	require.NoError(t, err)
+	md := Metadata{}
+	err = json.NewDecoder(resp.Body).Decode(&md)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, "serve", md.Name)
+	require.Equal(t, []int{3}, md.InstanceShape)
+	require.Equal(t, []int{2}, md.OutputShape)
+	require.Len(t, md.Inputs, 1)
+	require.True(t, md.Inputs[0].Fwd)
+
+	// concurrent single instance requests are coalesced into batches.
+	numRequests := 16
+	var wg sync.WaitGroup
+	errs := make(chan error, numRequests)
+	for i := 0; i < numRequests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			x := []float32{float32(i), float32(i + 1), float32(i + 2)}
+			body := fmt.Sprintf(`{"instances": [[%v, %v, %v]]}`, x[0], x[1], x[2])
+			resp, err := http.Post(ts.URL+"/v1/predict", "application/json", bytes.NewBufferString(body))
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				errs <- fmt.Errorf("unexpected status %d", resp.StatusCode)
+				return
+			}
+			pr := PredictResponse{}
+			err = json.NewDecoder(resp.Body).Decode(&pr)
+			if err != nil {
+				errs <- err
+				return
+			}
+			expected, err := model.Predict(tensor.New(tensor.WithShape(1, 3), tensor.WithBacking(x)))
+			if err != nil {
+				errs <- err
+				return
+			}
+			for j, v := range expected.Data().([]float32) {
+				if float32(pr.Predictions[0][j]) != v {
+					errs <- fmt.Errorf("instance %d expected %v got %v", i, expected.Data(), pr.Predictions[0])
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// raw tensors.
+	x := []float32{0, 1, 2, 3, 4, 5}
+	raw := &bytes.Buffer{}
+	err = binary.Write(raw, binary.LittleEndian, x)
+	require.NoError(t, err)
+	resp, err = http.Post(ts.URL+"/v1/predict/raw", "application/octet-stream", raw)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "2,2", resp.Header.Get("X-Goro-Shape"))
+	b, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	predictions := make([]float32, 4)
+	err = binary.Read(bytes.NewReader(b), binary.LittleEndian, predictions)
+	require.NoError(t, err)
+	expected, err := model.PredictBatch(tensor.New(tensor.WithShape(2, 3), tensor.WithBacking(x)))
+	require.NoError(t, err)
+	require.Equal(t, expected.Data(), predictions)
+
+	// invalid instances.
+	resp, err = http.Post(ts.URL+"/v1/predict", "application/json", bytes.NewBufferString(`{"instances": [[1, 2]]}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+
+	// a body at the limit holds more instances than are predicted at once.
+	x = make([]float32, 255)
+	for i := range x {
+		x[i] = float32(i) / 255
+	}
+	raw = &bytes.Buffer{}
+	err = binary.Write(raw, binary.LittleEndian, x)
+	require.NoError(t, err)
+	resp, err = http.Post(ts.URL+"/v1/predict/raw", "application/octet-stream", raw)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "85,2", resp.Header.Get("X-Goro-Shape"))
+	b, err = ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	predictions = make([]float32, 170)
+	err = binary.Read(bytes.NewReader(b), binary.LittleEndian, predictions)
+	require.NoError(t, err)
+	for i := 0; i < 85; i += 17 {
+		expected, err := model.Predict(tensor.New(tensor.WithShape(1, 3), tensor.WithBacking(x[i*3:i*3+3])))
+		require.NoError(t, err)
+		require.Equal(t, expected.Data(), predictions[i*2:i*2+2])
+	}
+
+	// bodies over the limit.
+	large := bytes.Repeat([]byte{0}, 1032)
+	resp, err = http.Post(ts.URL+"/v1/predict/raw", "application/octet-stream", bytes.NewReader(large))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	resp.Body.Close()
+	resp, err = http.Post(ts.URL+"/v1/predict", "application/json", bytes.NewBufferString(`{"instances": [`+strings.Repeat("[1, 2, 3], ", 100)+`[1, 2, 3]]}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	resp.Body.Close()
+
+	err = server.Close(context.Background())
+	require.NoError(t, err)
+	resp, err = http.Get(ts.URL + "/readyz")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestServerMaskInput(t *testing.T) {
+	model, err := m.NewSequential("masked")
+	require.NoError(t, err)
+	err = model.AddLayers(
+		layer.TimeDistributed{Layer: layer.FC{Output: 2, Activation: layer.Linear}},
+	)
+	require.NoError(t, err)
+	mask := m.NewInput("mask", []int{1, 3})
+	model.Mask(mask)
+	err = model.Compile(m.Inputs{m.NewInput("x", []int{1, 3, 2}), mask}, m.NewInput("y", []int{1, 3, 2}), m.WithoutTracker())
+	require.NoError(t, err)
+	_, err = NewServer(model)
+	var configErr *m.ConfigError
+	require.True(t, errors.As(err, &configErr))
+}
@@ -0,0 +1,217 @@
+package tensorboard
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aunum/goro/pkg/v1/common/proto"
+)
+
+// Event is a decoded event from an event file.
+type Event struct {
+	// WallTime the event was written in seconds since the epoch.
+	WallTime float64
+
+	// Step of the event.
+	Step int64
+
+	// FileVersion is set on the first event of a file.
+	FileVersion string
+
+	// Graph is set on graph events.
+	Graph []GraphNode
+
+	// Values are set on summary events.
+	Values []Value
+}
+
+// Value is a summary value of an event.
+type Value struct {
+	// Tag of the value.
+	Tag string
+
+	// Scalar is the value of a scalar.
+	Scalar float32
+
+	// Histogram is set on histogram values.
+	Histogram *Histogram
+}
+
+// Histogram is a histogram summary value.
+type Histogram struct {
+	Min, Max, Num, Sum, SumSquares float64
+
+	// BucketLimits are the upper edges of the buckets.
+	BucketLimits []float64
+
+	// Buckets are the counts of the buckets.
+	Buckets []float64
+}
+
+// GraphNode is a node of a graph event.
+type GraphNode struct {
+	Name   string
+	Op     string
+	Inputs []string
+}
+
+// ReadEvents reads all the events from an event file, checking the CRC of each record.
+func ReadEvents(r io.Reader) ([]Event, error) {
+	events := []Event{}
+	for {
+		var header [12]byte
+		_, err := io.ReadFull(r, header[:])
+		if err == io.EOF {
+			return events, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if maskedCRC(header[:8]) != binary.LittleEndian.Uint32(header[8:]) {
+			return nil, fmt.Errorf("corrupt length of record %d", len(events))
+		}
+		data := make([]byte, binary.LittleEndian.Uint64(header[:8])+4)
+		_, err = io.ReadFull(r, data)
+		if err != nil {
+			return nil, err
+		}
+		footer := data[len(data)-4:]
+		data = data[:len(data)-4]
+		if maskedCRC(data) != binary.LittleEndian.Uint32(footer) {
+			return nil, fmt.Errorf("corrupt data of record %d", len(events))
+		}
+		e, err := decodeEvent(data)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", len(events), err)
+		}
+		events = append(events, e)
+	}
+}
+
+// ReadEventsFile reads all the events from an event file at the path.
+func ReadEventsFile(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadEvents(f)
+}
+
+func decodeEvent(b []byte) (Event, error) {
+	e := Event{}
+	fs, err := proto.Fields(b)
+	if err != nil {
+		return e, err
+	}
+	for _, f := range fs {
+		switch f.Number {
+		case 1:
+			e.WallTime = f.Double()
+		case 2:
+			e.Step = f.Int64()
+		case 3:
+			e.FileVersion = f.String()
+		case 4:
+			e.Graph, err = decodeGraph(f.Bytes)
+		case 5:
+			e.Values, err = decodeSummary(f.Bytes)
+		}
+		if err != nil {
+			return e, err
+		}
+	}
+	return e, nil
+}
+
+func decodeSummary(b []byte) ([]Value, error) {
+	fs, err := proto.Fields(b)
+	if err != nil {
+		return nil, err
+	}
+	values := []Value{}
+	for _, f := range fs {
+		if f.Number != 1 {
+			continue
+		}
+		vfs, err := proto.Fields(f.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		v := Value{}
+		for _, vf := range vfs {
+			switch vf.Number {
+			case 1:
+				v.Tag = vf.String()
+			case 2:
+				v.Scalar = vf.Float()
+			case 5:
+				v.Histogram, err = decodeHistogram(vf.Bytes)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func decodeHistogram(b []byte) (*Histogram, error) {
+	fs, err := proto.Fields(b)
+	if err != nil {
+		return nil, err
+	}
+	h := &Histogram{}
+	for _, f := range fs {
+		switch f.Number {
+		case 1:
+			h.Min = f.Double()
+		case 2:
+			h.Max = f.Double()
+		case 3:
+			h.Num = f.Double()
+		case 4:
+			h.Sum = f.Double()
+		case 5:
+			h.SumSquares = f.Double()
+		case 6:
+			h.BucketLimits = append(h.BucketLimits, f.Doubles()...)
+		case 7:
+			h.Buckets = append(h.Buckets, f.Doubles()...)
+		}
+	}
+	return h, nil
+}
+
+func decodeGraph(b []byte) ([]GraphNode, error) {
+	fs, err := proto.Fields(b)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []GraphNode{}
+	for _, f := range fs {
+		if f.Number != 1 {
+			continue
+		}
+		nfs, err := proto.Fields(f.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		n := GraphNode{}
+		for _, nf := range nfs {
+			switch nf.Number {
+			case 1:
+				n.Name = nf.String()
+			case 2:
+				n.Op = nf.String()
+			case 3:
+				n.Inputs = append(n.Inputs, nf.String())
+			}
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
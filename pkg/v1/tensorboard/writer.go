@@ -0,0 +1,322 @@
+// Package tensorboard writes TensorBoard event files of scalars, histograms and graphs.
+package tensorboard
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aunum/gold/pkg/v1/track"
+	cgraph "github.com/aunum/goro/pkg/v1/common/graph"
+	"github.com/aunum/goro/pkg/v1/common/proto"
+	"github.com/aunum/goro/pkg/v1/model"
+
+	g "gorgonia.org/gorgonia"
+)
+
+// fileVersion is the version of the event file format written in the first event.
+const fileVersion = "brain.Event:2"
+
+// histogramBuckets is the number of buckets in a histogram.
+const histogramBuckets = 30
+
+var writers uint32
+
+// Writer writes events to a TensorBoard event file, it is safe for concurrent use.
+type Writer struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+	w    *bufio.Writer
+}
+
+// NewWriter creates a new event file in the log directory, creating the directory if it does not
+// exist. Point TensorBoard at the directory, or a parent of it to compare runs, with
+// `tensorboard --logdir <dir>`.
+func NewWriter(dir string) (*Writer, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	name := fmt.Sprintf("events.out.tfevents.%d.%s.%d.%d.v2", time.Now().Unix(), hostname, os.Getpid(), atomic.AddUint32(&writers, 1))
+	path := filepath.Join(dir, name)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+	w := &Writer{path: path, f: f, w: bufio.NewWriter(f)}
+	err = w.write(event(0).String(3, fileVersion))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, w.Flush()
+}
+
+// Path of the event file.
+func (w *Writer) Path() string {
+	return w.path
+}
+
+// AddScalar adds a scalar value at the step.
+func (w *Writer) AddScalar(tag string, value float64, step int64) error {
+	v := proto.Message{}.String(1, tag).Float(2, float32(value))
+	return w.write(event(step).Bytes(5, proto.Message{}.Bytes(1, v)))
+}
+
+// AddHistogram adds a histogram of the values at the step.
+func (w *Writer) AddHistogram(tag string, values []float64, step int64) error {
+	if len(values) == 0 {
+		return fmt.Errorf("cannot add an empty histogram %q", tag)
+	}
+	v := proto.Message{}.String(1, tag).Bytes(5, histogram(values))
+	return w.write(event(step).Bytes(5, proto.Message{}.Bytes(1, v)))
+}
+
+// AddGraph adds the graph, nodes in a group are nested under its name.
+func (w *Writer) AddGraph(graph *g.ExprGraph, groups ...cgraph.Group) error {
+	if graph == nil {
+		return fmt.Errorf("graph is nil")
+	}
+	return w.write(event(0).Bytes(4, graphDef(graph, groups)))
+}
+
+// AddTracker adds the current scalar of each value in the tracker at the step.
+func (w *Writer) AddTracker(tracker *track.Tracker, step int64) error {
+	for _, name := range tracker.ValueNames() {
+		v, err := tracker.GetValue(name)
+		if err != nil {
+			return err
+		}
+		err = w.AddScalar(name, v.Scalar(), step)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddLearnables adds a histogram of the value of each learnable at the step.
+func (w *Writer) AddLearnables(learnables g.Nodes, step int64) error {
+	for _, learnable := range learnables {
+		values, err := floats(learnable.Value())
+		if err != nil {
+			return fmt.Errorf("learnable %q: %w", learnable.Name(), err)
+		}
+		err = w.AddHistogram(learnable.Name(), values, step)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddModel adds the tracked values and learnables of a compiled model at the step, call it after each
+// epoch or every few batches to follow training.
+func (w *Writer) AddModel(m *model.Sequential, step int64) error {
+	if m.Tracker != nil {
+		err := w.AddTracker(m.Tracker, step)
+		if err != nil {
+			return err
+		}
+	}
+	return w.AddLearnables(m.Learnables(), step)
+}
+
+// AddModelGraph adds the train graph of a compiled model with its nodes grouped by layer.
+func (w *Writer) AddModelGraph(m *model.Sequential) error {
+	graph, groups, err := m.LayerGraph("train")
+	if err != nil {
+		return err
+	}
+	return w.AddGraph(graph, groups...)
+}
+
+// Flush buffered events to the file.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Flush()
+}
+
+// Close flushes and closes the file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.w.Flush()
+	if err != nil {
+		w.f.Close()
+		return err
+	}
+	return w.f.Close()
+}
+
+// write an event as a record, which is the length of the data, the masked CRC of the length, the
+// data, and the masked CRC of the data.
+func (w *Writer) write(data proto.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var header [12]byte
+	binary.LittleEndian.PutUint64(header[:8], uint64(len(data)))
+	binary.LittleEndian.PutUint32(header[8:], maskedCRC(header[:8]))
+	var footer [4]byte
+	binary.LittleEndian.PutUint32(footer[:], maskedCRC(data))
+	for _, b := range [][]byte{header[:], data, footer[:]} {
+		_, err := w.w.Write(b)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+func maskedCRC(b []byte) uint32 {
+	crc := crc32.Checksum(b, crcTable)
+	return ((crc >> 15) | (crc << 17)) + 0xa282ead8
+}
+
+// event starts an event message at the step.
+func event(step int64) proto.Message {
+	wallTime := float64(time.Now().UnixNano()) / 1e9
+	return proto.Message{}.Double(1, wallTime).Int64(2, step)
+}
+
+// histogram encodes the values as a histogram with evenly spaced buckets.
+func histogram(values []float64) proto.Message {
+	min, max := math.Inf(1), math.Inf(-1)
+	var sum, sumSquares float64
+	for _, v := range values {
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+		sum += v
+		sumSquares += v * v
+	}
+	n := histogramBuckets
+	if min == max {
+		n = 1
+	}
+	width := (max - min) / float64(n)
+	limits := make([]float64, n)
+	for i := range limits {
+		limits[i] = min + float64(i+1)*width
+	}
+	limits[n-1] = max
+	counts := make([]float64, n)
+	for _, v := range values {
+		i := n - 1
+		if width > 0 {
+			i = int((v - min) / width)
+			if i >= n {
+				i = n - 1
+			}
+		}
+		counts[i]++
+	}
+	return proto.Message{}.
+		Double(1, min).
+		Double(2, max).
+		Double(3, float64(len(values))).
+		Double(4, sum).
+		Double(5, sumSquares).
+		PackedDoubles(6, limits).
+		PackedDoubles(7, counts)
+}
+
+// graphDef encodes the graph as a GraphDef of nodes with their operation and inputs.
+func graphDef(graph *g.ExprGraph, groups []cgraph.Group) proto.Message {
+	scopes := map[int64]string{}
+	for _, group := range groups {
+		for _, n := range group.Nodes {
+			if _, ok := scopes[n.ID()]; !ok {
+				scopes[n.ID()] = sanitize(group.Name)
+			}
+		}
+	}
+	nodes := graph.AllNodes()
+	names := map[int64]string{}
+	used := map[string]bool{}
+	for _, n := range nodes {
+		name := sanitize(n.Name())
+		if scope, ok := scopes[n.ID()]; ok {
+			name = scope + "/" + name
+		}
+		if used[name] {
+			name = fmt.Sprintf("%s_%d", name, n.ID())
+		}
+		used[name] = true
+		names[n.ID()] = name
+	}
+	def := proto.Message{}
+	for _, n := range nodes {
+		op := "Variable"
+		if !n.IsVar() && n.Op() != nil {
+			op = n.Op().String()
+		}
+		node := proto.Message{}.String(1, names[n.ID()]).String(2, op)
+		children := graph.From(n.ID())
+		for children.Next() {
+			node = node.String(3, names[children.Node().ID()])
+		}
+		def = def.Bytes(1, node)
+	}
+	return def
+}
+
+// sanitize replaces the characters TensorBoard does not allow in node names.
+func sanitize(name string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range name {
+		valid := r == '_' || r == '.' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+		if !valid || r == '_' {
+			if !underscore {
+				b.WriteRune('_')
+			}
+			underscore = true
+			continue
+		}
+		underscore = false
+		b.WriteRune(r)
+	}
+	ret := strings.Trim(b.String(), "_")
+	if ret == "" {
+		return "node"
+	}
+	return ret
+}
+
+// floats returns the data of a value as float64s.
+func floats(v g.Value) ([]float64, error) {
+	if v == nil {
+		return nil, fmt.Errorf("value is nil")
+	}
+	switch data := v.Data().(type) {
+	case []float64:
+		return data, nil
+	case []float32:
+		ret := make([]float64, len(data))
+		for i, d := range data {
+			ret[i] = float64(d)
+		}
+		return ret, nil
+	case float64:
+		return []float64{data}, nil
+	case float32:
+		return []float64{float64(data)}, nil
+	}
+	return nil, fmt.Errorf("unsupported value type %T", v.Data())
+}
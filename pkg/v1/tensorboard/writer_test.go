@@ -0,0 +1,97 @@
+package tensorboard_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aunum/goro/pkg/v1/layer"
+	"github.com/aunum/goro/pkg/v1/model"
+	. "github.com/aunum/goro/pkg/v1/tensorboard"
+
+	"github.com/stretchr/testify/require"
+	"gorgonia.org/tensor"
+)
+
+func TestWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tensorboard")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	m, err := model.NewSequential("tb")
+	require.NoError(t, err)
+	err = m.AddLayers(
+		layer.FC{Output: 4, Name: "hidden"},
+		layer.FC{Output: 2, Activation: layer.Linear},
+	)
+	require.NoError(t, err)
+	err = m.Compile(model.NewInput("x", []int{1, 3}), model.NewInput("y", []int{1, 2}))
+	require.NoError(t, err)
+	x := tensor.New(tensor.WithShape(1, 3), tensor.WithBacking([]float32{1, 2, 3}))
+	y := tensor.New(tensor.WithShape(1, 2), tensor.WithBacking([]float32{0, 1}))
+	require.NoError(t, m.Fit(x, y))
+
+	w, err := NewWriter(dir)
+	require.NoError(t, err)
+	require.True(t, strings.Contains(w.Path(), "tfevents"))
+	require.NoError(t, w.AddScalar("loss", 0.5, 1))
+	require.NoError(t, w.AddHistogram("values", []float64{1, 2, 2, 3}, 1))
+	require.Error(t, w.AddHistogram("empty", nil, 1))
+	require.NoError(t, w.AddModel(m, 2))
+	require.NoError(t, w.AddModelGraph(m))
+	require.NoError(t, w.Close())
+
+	events, err := ReadEventsFile(w.Path())
+	require.NoError(t, err)
+	require.Equal(t, "brain.Event:2", events[0].FileVersion)
+	require.Equal(t, "loss", events[1].Values[0].Tag)
+	require.Equal(t, float32(0.5), events[1].Values[0].Scalar)
+	require.Equal(t, int64(1), events[1].Step)
+
+	h := events[2].Values[0].Histogram
+	require.NotNil(t, h)
+	require.Equal(t, 1.0, h.Min)
+	require.Equal(t, 3.0, h.Max)
+	require.Equal(t, 4.0, h.Num)
+	require.Equal(t, 8.0, h.Sum)
+	require.Len(t, h.Buckets, len(h.BucketLimits))
+	total := 0.0
+	for _, b := range h.Buckets {
+		total += b
+	}
+	require.Equal(t, 4.0, total)
+
+	tags := map[string]bool{}
+	var graph []GraphNode
+	for _, e := range events[3:] {
+		for _, v := range e.Values {
+			tags[v.Tag] = true
+		}
+		if e.Graph != nil {
+			graph = e.Graph
+		}
+	}
+	require.True(t, tags["tb_train_loss"])
+	require.Len(t, tags, len(m.Learnables())+len(m.Tracker.ValueNames()))
+	require.NotEmpty(t, graph)
+	names := map[string]bool{}
+	hidden := false
+	for _, n := range graph {
+		names[n.Name] = true
+		hidden = hidden || strings.HasPrefix(n.Name, "hidden_fc/")
+	}
+	require.True(t, hidden)
+	for _, n := range graph {
+		for _, input := range n.Inputs {
+			require.True(t, names[input], "missing input %q", input)
+		}
+	}
+
+	data, err := ioutil.ReadFile(w.Path())
+	require.NoError(t, err)
+	data[len(data)-1]++
+	_, err = ReadEvents(bytes.NewReader(data))
+	require.Error(t, err)
+}